@@ -10,7 +10,9 @@ import (
 
 	persistentProto "github.com/EventStore/EventStore-Client-Go/protos/persistent"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 
 	api "github.com/EventStore/EventStore-Client-Go/protos/streams"
 )
@@ -21,17 +23,38 @@ type Client struct {
 	Config     *Configuration
 }
 
-// NewClient ...
+// NewClient creates a client. It connects on the first operation, unless
+// Configuration.EagerConnectTimeout is positive.
 func NewClient(configuration *Configuration) (*Client, error) {
 	grpcClient := NewGrpcClient(*configuration)
-	return &Client{
+	client := &Client{
 		grpcClient: grpcClient,
 		Config:     configuration,
-	}, nil
+	}
+
+	if configuration.EagerConnectTimeout > 0 {
+		ctx, cancel := context.WithTimeout(context.Background(), configuration.EagerConnectTimeout)
+		defer cancel()
+
+		if _, err := client.Ping(ctx); err != nil {
+			client.Close()
+			return nil, fmt.Errorf("failed to connect within %v: %w", configuration.EagerConnectTimeout, err)
+		}
+	}
+
+	return client, nil
+}
+
+// ConnectionName returns the name identifying the client, see Configuration.ConnectionName.
+func (client *Client) ConnectionName() string {
+	return client.grpcClient.config.ConnectionName
 }
 
-// Close ...
+// Close closes the client right away. Open subscriptions are dropped with
+// SubscriptionDropReason_ClientClosed and operations in flight are cancelled, see Shutdown to let
+// them complete.
 func (client *Client) Close() error {
+	client.grpcClient.operations.shutdown()
 	client.grpcClient.close()
 	return nil
 }
@@ -42,21 +65,36 @@ func (client *Client) AppendToStream(
 	streamID string,
 	opts AppendToStreamOptions,
 	events ...EventData,
-) (*WriteResult, error) {
+) (_ *WriteResult, err error) {
+	context, span := client.grpcClient.startOperation(context, "AppendToStream", SpanOptions{
+		Kind: SpanKind_Producer,
+		Attributes: []SpanAttribute{
+			{Key: SpanAttribute_Stream, Value: streamID},
+			{Key: SpanAttribute_EventCount, Value: int64(len(events))},
+		},
+	})
+	defer func() { span.end(err) }()
+
+	end, err := client.grpcClient.operations.begin()
+	if err != nil {
+		return nil, err
+	}
+	defer end()
+
+	context, cancel := client.grpcClient.withDeadline(context, "AppendToStream", opts.Deadline)
+	defer cancel()
+	defer func() { err = deadlineError(context, err) }()
+
 	opts.setDefaults()
-	handle, err := client.grpcClient.getConnectionHandle()
+	handle, err := client.grpcClient.getLeaderConnectionHandle(context, opts.Authenticated)
 	if err != nil {
 		return nil, fmt.Errorf("can't get a connection handle: %w", err)
 	}
+	context = routeContext(context, handle)
 	streamsClient := api.NewStreamsClient(handle.Connection())
 	var headers, trailers metadata.MD
 	callOptions := []grpc.CallOption{grpc.Header(&headers), grpc.Trailer(&trailers)}
-	if opts.Authenticated != nil {
-		callOptions = append(callOptions, grpc.PerRPCCredentials(basicAuth{
-			username: opts.Authenticated.Login,
-			password: opts.Authenticated.Password,
-		}))
-	}
+	context = withCallCredentials(context, opts.Authenticated)
 
 	appendOperation, err := streamsClient.Append(context, callOptions...)
 	if err != nil {
@@ -71,7 +109,11 @@ func (client *Client) AppendToStream(
 		return nil, fmt.Errorf("could not send append request header. Reason: %w", err)
 	}
 
+	traceParent := TraceParentFromContext(context)
+	appendedBytes := 0
 	for _, event := range events {
+		event = withTraceParent(event, traceParent)
+		appendedBytes += len(event.Data) + len(event.Metadata)
 		appendRequest := &api.AppendReq{
 			Content: &api.AppendReq_ProposedMessage_{
 				ProposedMessage: toProposedMessage(event),
@@ -93,6 +135,7 @@ func (client *Client) AppendToStream(
 	switch result.(type) {
 	case *api.AppendResp_Success_:
 		{
+			metricsOf(&client.grpcClient.config).BytesAppended(appendedBytes)
 			success := result.(*api.AppendResp_Success_)
 			var streamRevision uint64
 			if _, ok := success.Success.GetCurrentRevisionOption().(*api.AppendResp_Success_NoStream); ok {
@@ -110,6 +153,7 @@ func (client *Client) AppendToStream(
 				streamRevision = success.Success.GetCurrentRevision()
 			}
 
+			span.setAttributes(SpanAttribute{Key: SpanAttribute_Revision, Value: streamRevision})
 			return &WriteResult{
 				CommitPosition:      commitPosition,
 				PreparePosition:     preparePosition,
@@ -211,21 +255,30 @@ func (client *Client) DeleteStream(
 	context context.Context,
 	streamID string,
 	opts DeleteStreamOptions,
-) (*DeleteResult, error) {
+) (_ *DeleteResult, err error) {
+	context, span := client.grpcClient.startSpan(context, "DeleteStream", streamID)
+	defer func() { span.end(err) }()
+
+	end, err := client.grpcClient.operations.begin()
+	if err != nil {
+		return nil, err
+	}
+	defer end()
+
+	context, cancel := client.grpcClient.withDeadline(context, "DeleteStream", opts.Deadline)
+	defer cancel()
+	defer func() { err = deadlineError(context, err) }()
+
 	opts.setDefaults()
-	handle, err := client.grpcClient.getConnectionHandle()
+	handle, err := client.grpcClient.getLeaderConnectionHandle(context, opts.Authenticated)
 	if err != nil {
 		return nil, fmt.Errorf("can't get a connection handle: %w", err)
 	}
+	context = routeContext(context, handle)
 	streamsClient := api.NewStreamsClient(handle.Connection())
 	var headers, trailers metadata.MD
 	callOptions := []grpc.CallOption{grpc.Header(&headers), grpc.Trailer(&trailers)}
-	if opts.Authenticated != nil {
-		callOptions = append(callOptions, grpc.PerRPCCredentials(basicAuth{
-			username: opts.Authenticated.Login,
-			password: opts.Authenticated.Password,
-		}))
-	}
+	context = withCallCredentials(context, opts.Authenticated)
 	deleteRequest := toDeleteRequest(streamID, opts.ExpectedRevision)
 	deleteResponse, err := streamsClient.Delete(context, deleteRequest, callOptions...)
 	if err != nil {
@@ -241,21 +294,30 @@ func (client *Client) TombstoneStream(
 	context context.Context,
 	streamID string,
 	opts TombstoneStreamOptions,
-) (*DeleteResult, error) {
+) (_ *DeleteResult, err error) {
+	context, span := client.grpcClient.startSpan(context, "TombstoneStream", streamID)
+	defer func() { span.end(err) }()
+
+	end, err := client.grpcClient.operations.begin()
+	if err != nil {
+		return nil, err
+	}
+	defer end()
+
+	context, cancel := client.grpcClient.withDeadline(context, "TombstoneStream", opts.Deadline)
+	defer cancel()
+	defer func() { err = deadlineError(context, err) }()
+
 	opts.setDefaults()
-	handle, err := client.grpcClient.getConnectionHandle()
+	handle, err := client.grpcClient.getLeaderConnectionHandle(context, opts.Authenticated)
 	if err != nil {
 		return nil, fmt.Errorf("can't get a connection handle: %w", err)
 	}
+	context = routeContext(context, handle)
 	streamsClient := api.NewStreamsClient(handle.Connection())
 	var headers, trailers metadata.MD
 	callOptions := []grpc.CallOption{grpc.Header(&headers), grpc.Trailer(&trailers)}
-	if opts.Authenticated != nil {
-		callOptions = append(callOptions, grpc.PerRPCCredentials(basicAuth{
-			username: opts.Authenticated.Login,
-			password: opts.Authenticated.Password,
-		}))
-	}
+	context = withCallCredentials(context, opts.Authenticated)
 	tombstoneRequest := toTombstoneRequest(streamID, opts.ExpectedRevision)
 	tombstoneResponse, err := streamsClient.Tombstone(context, tombstoneRequest, callOptions...)
 
@@ -273,16 +335,27 @@ func (client *Client) ReadStream(
 	streamID string,
 	opts ReadStreamOptions,
 	count uint64,
-) (*ReadStream, error) {
+) (_ *ReadStream, err error) {
+	context, span := client.grpcClient.startSpan(context, "ReadStream", streamID, SpanAttribute{Key: SpanAttribute_EventCount, Value: count})
+	defer func() { span.end(err) }()
+
+	end, err := client.grpcClient.operations.begin()
+	if err != nil {
+		return nil, err
+	}
+	defer end()
+
 	opts.setDefaults()
 	readRequest := toReadStreamRequest(streamID, opts.Direction, opts.From, count, opts.ResolveLinkTos)
-	handle, err := client.grpcClient.getConnectionHandle()
+	handshake := client.grpcClient.startHandshake(context, "ReadStream", opts.Deadline)
+	handle, err := handshake.getConnectionHandle(context, client.grpcClient, opts.NodePreference, opts.Authenticated)
 	if err != nil {
-		return nil, fmt.Errorf("can't get a connection handle: %w", err)
+		return nil, handshake.done(fmt.Errorf("can't get a connection handle: %w", err))
 	}
+	context = routeContext(context, handle)
 	streamsClient := api.NewStreamsClient(handle.Connection())
 
-	return readInternal(context, client.grpcClient, handle, streamsClient, readRequest, opts.Authenticated)
+	return readInternal(context, client.grpcClient, handle, streamsClient, readRequest, opts.Authenticated, handshake)
 }
 
 // ReadAll ...
@@ -290,15 +363,26 @@ func (client *Client) ReadAll(
 	context context.Context,
 	opts ReadAllOptions,
 	count uint64,
-) (*ReadStream, error) {
+) (_ *ReadStream, err error) {
+	context, span := client.grpcClient.startSpan(context, "ReadAll", "$all", SpanAttribute{Key: SpanAttribute_EventCount, Value: count})
+	defer func() { span.end(err) }()
+
+	end, err := client.grpcClient.operations.begin()
+	if err != nil {
+		return nil, err
+	}
+	defer end()
+
 	opts.setDefaults()
-	handle, err := client.grpcClient.getConnectionHandle()
+	handshake := client.grpcClient.startHandshake(context, "ReadAll", opts.Deadline)
+	handle, err := handshake.getConnectionHandle(context, client.grpcClient, opts.NodePreference, opts.Authenticated)
 	if err != nil {
-		return nil, fmt.Errorf("can't get a connection handle: %w", err)
+		return nil, handshake.done(fmt.Errorf("can't get a connection handle: %w", err))
 	}
+	context = routeContext(context, handle)
 	streamsClient := api.NewStreamsClient(handle.Connection())
 	readRequest := toReadAllRequest(opts.Direction, opts.From, count, opts.ResolveLinkTos)
-	return readInternal(context, client.grpcClient, handle, streamsClient, readRequest, opts.Authenticated)
+	return readInternal(context, client.grpcClient, handle, streamsClient, readRequest, opts.Authenticated, handshake)
 }
 
 // SubscribeToStream ...
@@ -306,43 +390,58 @@ func (client *Client) SubscribeToStream(
 	ctx context.Context,
 	streamID string,
 	opts SubscribeToStreamOptions,
-) (*Subscription, error) {
+) (_ *Subscription, err error) {
+	ctx, span := client.grpcClient.startSpan(ctx, "SubscribeToStream", streamID)
+	defer func() { span.end(err) }()
+
+	end, err := client.grpcClient.operations.begin()
+	if err != nil {
+		return nil, err
+	}
+	defer end()
+
 	opts.setDefaults()
-	handle, err := client.grpcClient.getConnectionHandle()
+	handshake := client.grpcClient.startHandshake(ctx, "SubscribeToStream", opts.Deadline)
+	handle, err := handshake.getConnectionHandle(ctx, client.grpcClient, opts.NodePreference, opts.Authenticated)
 	if err != nil {
-		return nil, fmt.Errorf("can't get a connection handle: %w", err)
+		return nil, handshake.done(fmt.Errorf("can't get a connection handle: %w", err))
 	}
+	ctx = routeContext(ctx, handle)
 	var headers, trailers metadata.MD
 	callOptions := []grpc.CallOption{grpc.Header(&headers), grpc.Trailer(&trailers)}
-	if opts.Authenticated != nil {
-		callOptions = append(callOptions, grpc.PerRPCCredentials(basicAuth{
-			username: opts.Authenticated.Login,
-			password: opts.Authenticated.Password,
-		}))
-	}
+	ctx = withCallCredentials(ctx, opts.Authenticated)
 	streamsClient := api.NewStreamsClient(handle.Connection())
 	subscriptionRequest, err := toStreamSubscriptionRequest(streamID, opts.From, opts.ResolveLinkTos, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to construct subscription. Reason: %w", err)
 	}
 	ctx, cancel := context.WithCancel(ctx)
+	handshake.watch(cancel)
 	readClient, err := streamsClient.Read(ctx, subscriptionRequest, callOptions...)
 	if err != nil {
 		defer cancel()
 		err = client.grpcClient.handleError(handle, headers, trailers, err)
-		return nil, fmt.Errorf("failed to construct subscription. Reason: %w", err)
+		return nil, handshake.done(fmt.Errorf("failed to construct subscription. Reason: %w", err))
 	}
 	readResult, err := readClient.Recv()
 	if err != nil {
 		defer cancel()
 		err = client.grpcClient.handleError(handle, headers, trailers, err)
-		return nil, fmt.Errorf("failed to perform read. Reason: %w", err)
+		return nil, handshake.done(fmt.Errorf("failed to perform read. Reason: %w", err))
 	}
+	handshake.done(nil)
 	switch readResult.Content.(type) {
 	case *api.ReadResp_Confirmation:
 		{
 			confirmation := readResult.GetConfirmation()
-			return NewSubscription(client, cancel, readClient, confirmation.SubscriptionId), nil
+			var idle *idleWatchdog
+			if opts.IdleTimeout > 0 {
+				idle = newIdleWatchdog(&client.grpcClient.config, opts.IdleTimeout, client.idleProbe(handle, streamID, opts.Authenticated), cancel)
+			}
+
+			subscription := newSubscription(client, cancel, readClient, confirmation.SubscriptionId, idle, opts.EventBufferSize)
+			client.startStatsSampling(subscription.tracker, subscription.Id(), opts.Stats, opts.Authenticated)
+			return subscription, nil
 		}
 	}
 	defer cancel()
@@ -353,21 +452,30 @@ func (client *Client) SubscribeToStream(
 func (client *Client) SubscribeToAll(
 	ctx context.Context,
 	opts SubscribeToAllOptions,
-) (*Subscription, error) {
+) (_ *Subscription, err error) {
+	ctx, span := client.grpcClient.startSpan(ctx, "SubscribeToAll", "$all")
+	defer func() { span.end(err) }()
+
+	end, err := client.grpcClient.operations.begin()
+	if err != nil {
+		return nil, err
+	}
+	defer end()
+
 	opts.setDefaults()
-	handle, err := client.grpcClient.getConnectionHandle()
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	handshake := client.grpcClient.startHandshake(ctx, "SubscribeToAll", opts.Deadline)
+	handle, err := handshake.getConnectionHandle(ctx, client.grpcClient, opts.NodePreference, opts.Authenticated)
 	if err != nil {
-		return nil, fmt.Errorf("can't get a connection handle: %w", err)
+		return nil, handshake.done(fmt.Errorf("can't get a connection handle: %w", err))
 	}
+	ctx = routeContext(ctx, handle)
 	streamsClient := api.NewStreamsClient(handle.Connection())
 	var headers, trailers metadata.MD
 	callOptions := []grpc.CallOption{grpc.Header(&headers), grpc.Trailer(&trailers)}
-	if opts.Authenticated != nil {
-		callOptions = append(callOptions, grpc.PerRPCCredentials(basicAuth{
-			username: opts.Authenticated.Login,
-			password: opts.Authenticated.Password,
-		}))
-	}
+	ctx = withCallCredentials(ctx, opts.Authenticated)
 
 	var filterOptions *SubscriptionFilterOptions = nil
 	if opts.Filter != nil {
@@ -383,23 +491,38 @@ func (client *Client) SubscribeToAll(
 		return nil, fmt.Errorf("failed to construct subscription. Reason: %w", err)
 	}
 	ctx, cancel := context.WithCancel(ctx)
+	handshake.watch(cancel)
 	readClient, err := streamsClient.Read(ctx, subscriptionRequest, callOptions...)
 	if err != nil {
 		defer cancel()
 		err = client.grpcClient.handleError(handle, headers, trailers, err)
-		return nil, fmt.Errorf("failed to construct subscription. Reason: %w", err)
+		return nil, handshake.done(fmt.Errorf("failed to construct subscription. Reason: %w", err))
 	}
 	readResult, err := readClient.Recv()
 	if err != nil {
 		defer cancel()
 		err = client.grpcClient.handleError(handle, headers, trailers, err)
-		return nil, fmt.Errorf("failed to perform read. Reason: %w", err)
+		return nil, handshake.done(fmt.Errorf("failed to perform read. Reason: %w", err))
 	}
+	handshake.done(nil)
 	switch readResult.Content.(type) {
 	case *api.ReadResp_Confirmation:
 		{
 			confirmation := readResult.GetConfirmation()
-			return NewSubscription(client, cancel, readClient, confirmation.SubscriptionId), nil
+			var idle *idleWatchdog
+			if opts.IdleTimeout > 0 {
+				// Filtered subscriptions receive regular checkpoints, silence means the connection is gone.
+				var probe func(ctx context.Context) error
+				if opts.Filter == nil {
+					probe = client.idleProbe(handle, "", opts.Authenticated)
+				}
+
+				idle = newIdleWatchdog(&client.grpcClient.config, opts.IdleTimeout, probe, cancel)
+			}
+
+			subscription := newSubscription(client, cancel, readClient, confirmation.SubscriptionId, idle, opts.EventBufferSize)
+			client.startStatsSampling(subscription.tracker, subscription.Id(), opts.Stats, opts.Authenticated)
+			return subscription, nil
 		}
 	}
 	defer cancel()
@@ -412,22 +535,43 @@ func (client *Client) ConnectToPersistentSubscription(
 	streamName string,
 	groupName string,
 	options ConnectToPersistentSubscriptionOptions,
-) (*PersistentSubscription, error) {
+) (_ *PersistentSubscription, err error) {
+	ctx, span := client.grpcClient.startSpan(ctx, "ConnectToPersistentSubscription", streamName)
+	defer func() { span.end(err) }()
+
+	end, err := client.grpcClient.operations.begin()
+	if err != nil {
+		return nil, err
+	}
+	defer end()
+
 	options.setDefaults()
-	handle, err := client.grpcClient.getConnectionHandle()
+	handshake := client.grpcClient.startHandshake(ctx, "ConnectToPersistentSubscription", options.Deadline)
+	handle, err := handshake.getConnectionHandle(ctx, client.grpcClient, NodePreference_Leader, options.Authenticated)
 	if err != nil {
-		return nil, fmt.Errorf("can't get a connection handle: %w", err)
+		return nil, handshake.done(fmt.Errorf("can't get a connection handle: %w", err))
 	}
+	ctx = routeContext(ctx, handle)
 	persistentSubscriptionClient := newPersistentClient(client.grpcClient, persistentProto.NewPersistentSubscriptionsClient(handle.Connection()))
 
-	return persistentSubscriptionClient.ConnectToPersistentSubscription(
+	subscription, err := persistentSubscriptionClient.ConnectToPersistentSubscription(
 		ctx,
 		handle,
+		handshake,
 		int32(options.BatchSize),
 		streamName,
 		groupName,
 		options.Authenticated,
+		options.IdleTimeout,
+		client.idleProbe(handle, streamName, options.Authenticated),
+		options.EventBufferSize,
 	)
+	if err != nil {
+		return nil, err
+	}
+
+	client.startStatsSampling(subscription.tracker, subscription.subscriptionId, options.Stats, options.Authenticated)
+	return subscription, nil
 }
 
 func (client *Client) CreatePersistentSubscription(
@@ -435,12 +579,29 @@ func (client *Client) CreatePersistentSubscription(
 	streamName string,
 	groupName string,
 	options PersistentStreamSubscriptionOptions,
-) error {
+) (err error) {
+	ctx, span := client.grpcClient.startSpan(ctx, "CreatePersistentSubscription", streamName)
+	defer func() { span.end(err) }()
+
+	end, err := client.grpcClient.operations.begin()
+	if err != nil {
+		return err
+	}
+	defer end()
+
+	ctx, cancel := client.grpcClient.withDeadline(ctx, "CreatePersistentSubscription", options.Deadline)
+	defer cancel()
+	defer func() { err = deadlineError(ctx, err) }()
+
 	options.setDefaults()
-	handle, err := client.grpcClient.getConnectionHandle()
+	if err := options.Validate(); err != nil {
+		return err
+	}
+	handle, err := client.grpcClient.getLeaderConnectionHandle(ctx, options.Authenticated)
 	if err != nil {
 		return fmt.Errorf("can't get a connection handle: %w", err)
 	}
+	ctx = routeContext(ctx, handle)
 	persistentSubscriptionClient := newPersistentClient(client.grpcClient, persistentProto.NewPersistentSubscriptionsClient(handle.Connection()))
 
 	if options.Settings == nil {
@@ -455,12 +616,29 @@ func (client *Client) CreatePersistentSubscriptionAll(
 	ctx context.Context,
 	groupName string,
 	options PersistentAllSubscriptionOptions,
-) error {
+) (err error) {
+	ctx, span := client.grpcClient.startSpan(ctx, "CreatePersistentSubscriptionAll", "$all")
+	defer func() { span.end(err) }()
+
+	end, err := client.grpcClient.operations.begin()
+	if err != nil {
+		return err
+	}
+	defer end()
+
+	ctx, cancel := client.grpcClient.withDeadline(ctx, "CreatePersistentSubscriptionAll", options.Deadline)
+	defer cancel()
+	defer func() { err = deadlineError(ctx, err) }()
+
 	options.setDefaults()
-	handle, err := client.grpcClient.getConnectionHandle()
+	if err := options.Validate(); err != nil {
+		return err
+	}
+	handle, err := client.grpcClient.getLeaderConnectionHandle(ctx, options.Authenticated)
 	if err != nil {
 		return fmt.Errorf("can't get a connection handle: %w", err)
 	}
+	ctx = routeContext(ctx, handle)
 
 	var filterOptions *SubscriptionFilterOptions = nil
 	if options.Filter != nil {
@@ -493,12 +671,29 @@ func (client *Client) UpdatePersistentStreamSubscription(
 	streamName string,
 	groupName string,
 	options PersistentStreamSubscriptionOptions,
-) error {
+) (err error) {
+	ctx, span := client.grpcClient.startSpan(ctx, "UpdatePersistentStreamSubscription", streamName)
+	defer func() { span.end(err) }()
+
+	end, err := client.grpcClient.operations.begin()
+	if err != nil {
+		return err
+	}
+	defer end()
+
+	ctx, cancel := client.grpcClient.withDeadline(ctx, "UpdatePersistentStreamSubscription", options.Deadline)
+	defer cancel()
+	defer func() { err = deadlineError(ctx, err) }()
+
 	options.setDefaults()
-	handle, err := client.grpcClient.getConnectionHandle()
+	if err := options.Validate(); err != nil {
+		return err
+	}
+	handle, err := client.grpcClient.getLeaderConnectionHandle(ctx, options.Authenticated)
 	if err != nil {
 		return fmt.Errorf("can't get a connection handle: %w", err)
 	}
+	ctx = routeContext(ctx, handle)
 	persistentSubscriptionClient := newPersistentClient(client.grpcClient, persistentProto.NewPersistentSubscriptionsClient(handle.Connection()))
 
 	if options.Settings == nil {
@@ -513,27 +708,137 @@ func (client *Client) UpdatePersistentSubscriptionAll(
 	ctx context.Context,
 	groupName string,
 	options PersistentAllSubscriptionOptions,
-) error {
+) (err error) {
+	ctx, span := client.grpcClient.startSpan(ctx, "UpdatePersistentSubscriptionAll", "$all")
+	defer func() { span.end(err) }()
+
+	end, err := client.grpcClient.operations.begin()
+	if err != nil {
+		return err
+	}
+	defer end()
+
+	ctx, cancel := client.grpcClient.withDeadline(ctx, "UpdatePersistentSubscriptionAll", options.Deadline)
+	defer cancel()
+	defer func() { err = deadlineError(ctx, err) }()
+
 	options.setDefaults()
-	handle, err := client.grpcClient.getConnectionHandle()
+	if err := options.Validate(); err != nil {
+		return err
+	}
+	handle, err := client.grpcClient.getLeaderConnectionHandle(ctx, options.Authenticated)
 	if err != nil {
 		return fmt.Errorf("can't get a connection handle: %w", err)
 	}
+	ctx = routeContext(ctx, handle)
 	persistentSubscriptionClient := newPersistentClient(client.grpcClient, persistentProto.NewPersistentSubscriptionsClient(handle.Connection()))
 
+	if options.Settings == nil {
+		setts := SubscriptionSettingsDefault()
+		options.Settings = &setts
+	}
+
 	return persistentSubscriptionClient.UpdateAllSubscription(ctx, handle, groupName, options.From, *options.Settings, options.Authenticated)
 }
 
+// EnsurePersistentSubscription creates a persistent subscription group on a stream, or updates it
+// with the given settings if the group already exists with other settings. The settings of the
+// group are read with the HTTP API of the server, as its gRPC API doesn't expose them. Only the
+// settings are compared, an existing group with the same settings is left as is even if options
+// starts from another position.
+func (client *Client) EnsurePersistentSubscription(
+	ctx context.Context,
+	streamName string,
+	groupName string,
+	options PersistentStreamSubscriptionOptions,
+) (*EnsurePersistentSubscriptionResult, error) {
+	if err := options.Validate(); err != nil {
+		return nil, err
+	}
+
+	action, err := client.persistentSubscriptionAction(ctx, streamName, groupName, options.Settings, options.Authenticated)
+	if err != nil {
+		return nil, err
+	}
+
+	return ensurePersistentSubscription(action, func() error {
+		return client.CreatePersistentSubscription(ctx, streamName, groupName, options)
+	}, func() error {
+		return client.UpdatePersistentStreamSubscription(ctx, streamName, groupName, options)
+	})
+}
+
+// EnsurePersistentSubscriptionAll creates a persistent subscription group on $all, or updates it
+// with the given settings if the group already exists with other settings, see
+// EnsurePersistentSubscription. The filter of an existing group can't be changed by an update.
+func (client *Client) EnsurePersistentSubscriptionAll(
+	ctx context.Context,
+	groupName string,
+	options PersistentAllSubscriptionOptions,
+) (*EnsurePersistentSubscriptionResult, error) {
+	if err := options.Validate(); err != nil {
+		return nil, err
+	}
+
+	action, err := client.persistentSubscriptionAction(ctx, "$all", groupName, options.Settings, options.Authenticated)
+	if err != nil {
+		return nil, err
+	}
+
+	return ensurePersistentSubscription(action, func() error {
+		return client.CreatePersistentSubscriptionAll(ctx, groupName, options)
+	}, func() error {
+		return client.UpdatePersistentSubscriptionAll(ctx, groupName, options)
+	})
+}
+
+func ensurePersistentSubscription(action ManifestAction, create func() error, update func() error) (*EnsurePersistentSubscriptionResult, error) {
+	switch action {
+	case ManifestAction_Unchanged:
+		return &EnsurePersistentSubscriptionResult{}, nil
+	case ManifestAction_Create:
+		err := create()
+		if err == nil {
+			return &EnsurePersistentSubscriptionResult{Created: true}, nil
+		}
+
+		// The group was created by someone else since its settings were read.
+		if !errors.Is(err, ErrAlreadyExists) {
+			return nil, err
+		}
+	}
+
+	if err := update(); err != nil {
+		return nil, err
+	}
+
+	return &EnsurePersistentSubscriptionResult{Updated: true}, nil
+}
+
 func (client *Client) DeletePersistentSubscription(
 	ctx context.Context,
 	streamName string,
 	groupName string,
 	options DeletePersistentSubscriptionOptions,
-) error {
-	handle, err := client.grpcClient.getConnectionHandle()
+) (err error) {
+	ctx, span := client.grpcClient.startSpan(ctx, "DeletePersistentSubscription", streamName)
+	defer func() { span.end(err) }()
+
+	end, err := client.grpcClient.operations.begin()
+	if err != nil {
+		return err
+	}
+	defer end()
+
+	ctx, cancel := client.grpcClient.withDeadline(ctx, "DeletePersistentSubscription", options.Deadline)
+	defer cancel()
+	defer func() { err = deadlineError(ctx, err) }()
+
+	handle, err := client.grpcClient.getLeaderConnectionHandle(ctx, options.Authenticated)
 	if err != nil {
 		return fmt.Errorf("can't get a connection handle: %w", err)
 	}
+	ctx = routeContext(ctx, handle)
 	persistentSubscriptionClient := newPersistentClient(client.grpcClient, persistentProto.NewPersistentSubscriptionsClient(handle.Connection()))
 
 	return persistentSubscriptionClient.DeleteStreamSubscription(ctx, handle, streamName, groupName, options.Authenticated)
@@ -543,16 +848,96 @@ func (client *Client) DeletePersistentSubscriptionAll(
 	ctx context.Context,
 	groupName string,
 	options DeletePersistentSubscriptionOptions,
-) error {
-	handle, err := client.grpcClient.getConnectionHandle()
+) (err error) {
+	ctx, span := client.grpcClient.startSpan(ctx, "DeletePersistentSubscriptionAll", "$all")
+	defer func() { span.end(err) }()
+
+	end, err := client.grpcClient.operations.begin()
+	if err != nil {
+		return err
+	}
+	defer end()
+
+	ctx, cancel := client.grpcClient.withDeadline(ctx, "DeletePersistentSubscriptionAll", options.Deadline)
+	defer cancel()
+	defer func() { err = deadlineError(ctx, err) }()
+
+	handle, err := client.grpcClient.getLeaderConnectionHandle(ctx, options.Authenticated)
 	if err != nil {
 		return fmt.Errorf("can't get a connection handle: %w", err)
 	}
+	ctx = routeContext(ctx, handle)
 	persistentSubscriptionClient := newPersistentClient(client.grpcClient, persistentProto.NewPersistentSubscriptionsClient(handle.Connection()))
 
 	return persistentSubscriptionClient.DeleteAllSubscription(ctx, handle, groupName, options.Authenticated)
 }
 
+// idleProbe checks the connection of a subscription is alive by reading the last event of a
+// stream, or of $all when streamID is empty. The read goes through the connection of the
+// subscription itself, as another connection of the client answering tells nothing of a
+// subscription stalled on a half-open one.
+func (client *Client) idleProbe(handle connectionHandle, streamID string, auth *Credentials) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		var request *api.ReadReq
+		if streamID == "" {
+			request = toReadAllRequest(Backwards, End{}, 1, false)
+		} else {
+			request = toReadStreamRequest(streamID, Backwards, End{}, 1, false)
+		}
+
+		ctx, cancel := context.WithCancel(withCallCredentials(routeContext(ctx, handle), auth))
+		defer cancel()
+
+		stream, err := api.NewStreamsClient(handle.Connection()).Read(ctx, request)
+		if err == nil {
+			_, err = stream.Recv()
+		}
+
+		// Any answer of the server, even an error, means the connection is alive.
+		if err != nil && err != io.EOF && !answeredByServer(err) {
+			return err
+		}
+
+		return nil
+	}
+}
+
+// answeredByServer tells if an operation failed because of the answer of the server, like a
+// stream not found or an access denied, rather than because the server couldn't be reached or
+// didn't answer in time.
+func answeredByServer(err error) bool {
+	var streamDeletedError *StreamDeletedError
+	if errors.Is(err, ErrStreamNotFound) || errors.As(err, &streamDeletedError) ||
+		errors.Is(err, ErrPermissionDenied) || errors.Is(err, ErrUnauthenticated) {
+		return true
+	}
+
+	var grpcError interface{ GRPCStatus() *status.Status }
+	if !errors.As(err, &grpcError) {
+		return false
+	}
+
+	switch grpcError.GRPCStatus().Code() {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.Canceled:
+		return false
+	default:
+		return true
+	}
+}
+
+func (client *Client) startStatsSampling(tracker *subscriptionTracker, subscriptionId string, options *SubscriptionStatsOptions, auth *Credentials) {
+	if options == nil {
+		return
+	}
+
+	samplingOptions := *options
+	if samplingOptions.Authenticated == nil {
+		samplingOptions.Authenticated = auth
+	}
+
+	tracker.startSampling(client, subscriptionId, samplingOptions)
+}
+
 func readInternal(
 	ctx context.Context,
 	client *grpcClient,
@@ -560,26 +945,23 @@ func readInternal(
 	streamsClient api.StreamsClient,
 	readRequest *api.ReadReq,
 	auth *Credentials,
+	handshake *handshakeDeadline,
 ) (*ReadStream, error) {
 	var headers, trailers metadata.MD
 	callOptions := []grpc.CallOption{grpc.Header(&headers), grpc.Trailer(&trailers)}
-	if auth != nil {
-		callOptions = append(callOptions, grpc.PerRPCCredentials(basicAuth{
-			username: auth.Login,
-			password: auth.Password,
-		}))
-	}
+	ctx = withCallCredentials(ctx, auth)
 	ctx, cancel := context.WithCancel(ctx)
+	handshake.watch(cancel)
 	result, err := streamsClient.Read(ctx, readRequest, callOptions...)
 	if err != nil {
 		defer cancel()
 
 		err = client.handleError(handle, headers, trailers, err)
-		return nil, fmt.Errorf("failed to construct read stream. Reason: %w", err)
+		return nil, handshake.done(fmt.Errorf("failed to construct read stream. Reason: %w", err))
 	}
 
 	msg, err := result.Recv()
-	if err != nil {
+	if err = handshake.done(err); err != nil {
 		defer cancel()
 		values := trailers.Get("exception")
 
@@ -1,6 +1,7 @@
 package esdb
 
 import (
+	"crypto/tls"
 	"crypto/x509"
 	"fmt"
 	"io/ioutil"
@@ -8,6 +9,8 @@ import (
 	"strconv"
 	"strings"
 	"time"
+
+	"google.golang.org/grpc"
 )
 
 const (
@@ -25,6 +28,11 @@ const (
 
 // Configuration describes how to connect to an instance of EventStoreDB.
 type Configuration struct {
+	// Identifies the client in the server and network logs. It is sent with every call, as the
+	// connection-name metadata and in the user agent, and prefixes the logs and labels the metrics
+	// of the client.
+	ConnectionName string // Defaults to a generated name.
+
 	// The URI of the EventStoreDB. Use this when connecting to a single node.
 	// Example: localhost:2113
 	Address string
@@ -38,32 +46,111 @@ type Configuration struct {
 	// The NodePreference to use when connecting.
 	NodePreference NodePreference
 
+	// Picks the node to connect to during discovery, overriding NodePreference when set.
+	NodeSelector NodeSelector // Defaults to nil.
+
 	// The username to use for authenticating against the EventStoreDB instance.
 	Username string
 
 	// The password to use for authenticating against the EventStoreDB instance.
 	Password string
 
+	// Authenticates the calls made without credentials of their own, taking precedence over
+	// Username and Password. It is consulted on every call, see BasicCredentialsProvider to rotate
+	// credentials at runtime or BearerTokenProvider to use bearer tokens.
+	CredentialsProvider CredentialsProvider // Defaults to nil.
+
+	// Refuses to send credentials over connections without TLS. Anonymous calls are still made.
+	CredentialsRequireTLS bool // Defaults to false.
+
 	// RootCAs defines the set of root certificate authorities
 	// that clients use when verifying server certificates.
 	// If RootCAs is nil, TLS uses the host's root CA set.
 	RootCAs *x509.CertPool // Defaults to nil.
 
+	// The file RootCAs was loaded from by the tlsCAFile setting, kept to write the setting back in
+	// ConnectionString.
+	TLSCAFile string // Defaults to "".
+
 	// Allows to skip certificate validation.
 	SkipCertificateVerification bool // Defaults to false.
 
+	// PEM encoded X.509 certificate and private key the client authenticates with over TLS.
+	ClientCertificate []byte // Defaults to nil.
+	ClientKey         []byte // Defaults to nil.
+
+	// Files holding the PEM encoded client certificate and private key, taking precedence over
+	// ClientCertificate and ClientKey. They are read again for every new connection, so rotated
+	// certificates are picked up without restarting the client.
+	ClientCertificateFile string // Defaults to "".
+	ClientKeyFile         string // Defaults to "".
+
 	// The maximum number of times to attempt end point discovery.
 	MaxDiscoverAttempts int // Defaults to 10.
 
-	// The polling interval (in milliseconds) used to discover the end point.
+	// The polling interval (in milliseconds) used to discover the end point. It doubles after every
+	// failed attempt, up to MaxDiscoveryInterval.
 	DiscoveryInterval int // Defaults to 100 milliseconds.
 
+	// The maximum interval (in milliseconds) between two discovery attempts.
+	MaxDiscoveryInterval int // Defaults to 5000 milliseconds.
+
 	// The amount of time (in seconds) after which an attempt to discover gossip will fail.
 	GossipTimeout int // Defaults to 5 seconds.
 
 	// Specifies if DNS discovery should be used.
 	DnsDiscover bool // Defaults to false.
 
+	// Resolves the _esdb._tcp SRV records and the A and AAAA records of the host of DnsDiscover,
+	// again on every discovery.
+	Resolver Resolver // Defaults to net.DefaultResolver.
+
+	// When connected to a cluster, the interval at which gossip is read to check the nodes the client
+	// is connected to, one per node preference in use. A new discovery starts as soon as a node is no
+	// longer alive, leaves the states a client can connect to, or stops being the leader when its
+	// node preference is Leader. Use 0 to disable.
+	GossipWatchInterval time.Duration // Defaults to 0.
+
+	// Translates the addresses advertised by the cluster, in gossip and in not-leader redirects,
+	// into addresses the client can reach, e.g. behind NAT or port forwarding. Takes precedence
+	// over AddressMap when set.
+	MapAddress func(advertised EndPoint) EndPoint // Defaults to nil.
+
+	// Static translation of the addresses advertised by the cluster. Addresses missing from the
+	// map are used as advertised.
+	AddressMap map[EndPoint]EndPoint // Defaults to nil.
+
+	// How long an operation may take when neither it nor its context has a deadline of its own.
+	// Streaming operations, like reads and subscriptions, are only bounded until they receive their
+	// first response. The Deadline of the options of an operation overrides it, 0 keeping this one
+	// and -1 disabling it. An operation running out of its deadline fails with a
+	// *DeadlineExceededError. Use 0 or -1 to disable.
+	DefaultDeadline time.Duration // Defaults to 10 seconds.
+
+	// When positive, NewClient connects to a node and pings it, see Client.Ping, failing when it
+	// can't within that time. Otherwise the client connects on its first operation.
+	EagerConnectTimeout time.Duration // Defaults to 0.
+
+	// Opens the network connections to the nodes, of both gossip and operations. See
+	// ProxyDialerFromEnvironment and HTTPProxyDialer to go through an HTTP proxy.
+	Dialer Dialer // Defaults to nil.
+
+	// Additional options of the gRPC connections, applied after the ones derived from this
+	// configuration, e.g. message size limits, compression or a user agent.
+	DialOptions []grpc.DialOption // Defaults to nil.
+
+	// Interceptors of the gRPC calls, of both gossip and operations, run in order.
+	UnaryInterceptors  []grpc.UnaryClientInterceptor  // Defaults to nil.
+	StreamInterceptors []grpc.StreamClientInterceptor // Defaults to nil.
+
+	// Starts a span around every operation and every event delivered by a subscription. The trace
+	// context of appends is stored in the JSON metadata of the events, see TraceParentMetadataKey.
+	Tracer Tracer // Defaults to nil.
+
+	// Receives the latency and errors of the operations, and measurements of discoveries,
+	// reconnections and subscriptions. See PrometheusMetrics.
+	Metrics Metrics // Defaults to nil.
+
 	// The amount of time (in milliseconds) to wait after which a keepalive ping is sent on the transport.
 	// If set below 10s, a minimum value of 10s will be used instead. Use -1 to disable. Use -1 to disable.
 	KeepAliveInterval time.Duration // Defaults to 10 seconds.
@@ -72,15 +159,22 @@ type Configuration struct {
 	KeepAliveTimeout time.Duration // Defaults to 10 seconds.
 }
 
+// defaultConfiguration returns the configuration connection strings start from.
+func defaultConfiguration() *Configuration {
+	return &Configuration{
+		DiscoveryInterval:    100,
+		MaxDiscoveryInterval: 5000,
+		GossipTimeout:        5,
+		MaxDiscoverAttempts:  10,
+		KeepAliveInterval:    10 * time.Second,
+		KeepAliveTimeout:     10 * time.Second,
+		DefaultDeadline:      10 * time.Second,
+	}
+}
+
 // ParseConnectionString creates a Configuration based on an EventStoreDb connection string.
 func ParseConnectionString(connectionString string) (*Configuration, error) {
-	config := &Configuration{
-		DiscoveryInterval:   100,
-		GossipTimeout:       5,
-		MaxDiscoverAttempts: 10,
-		KeepAliveInterval:   10 * time.Second,
-		KeepAliveTimeout:    10 * time.Second,
-	}
+	config := defaultConfiguration()
 
 	schemeIndex := strings.Index(connectionString, SchemeSeparator)
 	if schemeIndex == -1 {
@@ -196,6 +290,25 @@ func parseSettings(settings string, config *Configuration) error {
 		}
 	}
 
+	return checkClientCertificateFiles(config)
+}
+
+// checkClientCertificateFiles checks the userCertFile and userKeyFile settings, loading the files.
+func checkClientCertificateFiles(config *Configuration) error {
+	if (config.ClientCertificateFile == "") != (config.ClientKeyFile == "") {
+		return fmt.Errorf("The userCertFile and userKeyFile settings must be specified together")
+	}
+
+	if config.ClientCertificateFile != "" {
+		if config.DisableTLS {
+			return fmt.Errorf("The userCertFile and userKeyFile settings require TLS")
+		}
+
+		if _, err := config.clientCertificate(); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -216,6 +329,11 @@ func parseSetting(k, v string, config *Configuration) error {
 		if err != nil {
 			return err
 		}
+	case "maxdiscoveryinterval":
+		err := parseIntSetting(k, v, &config.MaxDiscoveryInterval)
+		if err != nil {
+			return err
+		}
 	case "gossiptimeout":
 		err := parseIntSetting(k, v, &config.GossipTimeout)
 		if err != nil {
@@ -226,11 +344,28 @@ func parseSetting(k, v string, config *Configuration) error {
 		if err != nil {
 			return err
 		}
+	case "connectionname":
+		config.ConnectionName = v
 	case "nodepreference":
 		err := parseNodePreference(v, config)
 		if err != nil {
 			return err
 		}
+	case "defaultdeadline":
+		err := parseKeepAliveSetting(k, v, &config.DefaultDeadline)
+		if err != nil {
+			return err
+		}
+	case "eagerconnecttimeout":
+		err := parseKeepAliveSetting(k, v, &config.EagerConnectTimeout)
+		if err != nil {
+			return err
+		}
+	case "addressmap":
+		err := parseAddressMap(v, config)
+		if err != nil {
+			return err
+		}
 	case "keepaliveinterval":
 		err := parseKeepAliveSetting(k, v, &config.KeepAliveInterval)
 		if err != nil {
@@ -238,7 +373,7 @@ func parseSetting(k, v string, config *Configuration) error {
 		}
 
 		if config.KeepAliveInterval >= 0 && config.KeepAliveInterval < 10*time.Second {
-			log.Printf("Specified KeepAliveInterval of %d is less than recommended 10_000 ms", config.KeepAliveInterval)
+			config.logf("Specified KeepAliveInterval of %d is less than recommended 10_000 ms", config.KeepAliveInterval)
 		}
 	case "keepalivetimeout":
 		err := parseKeepAliveSetting(k, v, &config.KeepAliveTimeout)
@@ -255,6 +390,10 @@ func parseSetting(k, v string, config *Configuration) error {
 		if err != nil {
 			return err
 		}
+	case "usercertfile":
+		config.ClientCertificateFile = v
+	case "userkeyfile":
+		config.ClientKeyFile = v
 	case "tlsverifycert":
 		err := parseBoolSetting(k, v, &config.SkipCertificateVerification, true)
 		if err != nil {
@@ -279,10 +418,164 @@ func parseCertificateFile(certFile string, config *Configuration) error {
 	}
 
 	config.RootCAs = cp
+	config.TLSCAFile = certFile
 
 	return nil
 }
 
+// parseAddressMap parses a comma-separated list of {advertised}->{reachable} address pairs.
+func parseAddressMap(v string, config *Configuration) error {
+	addressMap := make(map[EndPoint]EndPoint)
+
+	for _, pair := range strings.Split(v, ",") {
+		tokens := strings.Split(pair, "->")
+		if len(tokens) != 2 {
+			return fmt.Errorf("Invalid addressMap entry '%s', expecting {advertised host}:{port}->{host}:{port}", pair)
+		}
+
+		advertised, err := ParseEndPoint(tokens[0])
+		if err != nil {
+			return fmt.Errorf("Invalid advertised address in addressMap entry '%s': %w", pair, err)
+		}
+
+		reachable, err := ParseEndPoint(tokens[1])
+		if err != nil {
+			return fmt.Errorf("Invalid address in addressMap entry '%s': %w", pair, err)
+		}
+
+		addressMap[*advertised] = *reachable
+	}
+
+	config.AddressMap = addressMap
+
+	return nil
+}
+
+// clientCertificate loads the client certificate, if any. Files are read on every call so the
+// next connection uses rotated certificates.
+func (conf *Configuration) clientCertificate() (*tls.Certificate, error) {
+	if conf.ClientCertificateFile != "" || conf.ClientKeyFile != "" {
+		certificate, err := tls.LoadX509KeyPair(conf.ClientCertificateFile, conf.ClientKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load the client certificate: %w", err)
+		}
+
+		return &certificate, nil
+	}
+
+	if conf.ClientCertificate != nil || conf.ClientKey != nil {
+		certificate, err := tls.X509KeyPair(conf.ClientCertificate, conf.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse the client certificate: %w", err)
+		}
+
+		return &certificate, nil
+	}
+
+	return nil, nil
+}
+
+// Validate checks the configuration for invalid and conflicting settings, like several hosts with
+// DnsDiscover. It returns a *ValidationError listing every invalid field.
+func (conf *Configuration) Validate() error {
+	err := &ValidationError{}
+
+	switch {
+	case conf.DnsDiscover && len(conf.GossipSeeds) > 0:
+		err.add("GossipSeeds", "can't be set together with DnsDiscover, esdb+discover takes the single DNS name of the cluster")
+	case conf.Address != "" && len(conf.GossipSeeds) > 0:
+		err.add("Address", "can't be set together with GossipSeeds")
+	case conf.Address == "" && len(conf.GossipSeeds) == 0:
+		err.add("Address", "or GossipSeeds must be set")
+	}
+
+	for i, seed := range conf.GossipSeeds {
+		if seed == nil {
+			err.add(fmt.Sprintf("GossipSeeds[%d]", i), "must not be nil")
+		}
+	}
+
+	switch conf.NodePreference {
+	case "", NodePreference_Leader, NodePreference_Follower, NodePreference_ReadOnlyReplica, NodePreference_Random:
+	default:
+		err.add("NodePreference", "is unknown, got %q", string(conf.NodePreference))
+	}
+
+	if (conf.Username == "") != (conf.Password == "") {
+		err.add("Username", "and Password must be set together")
+	}
+	if conf.CredentialsRequireTLS && conf.DisableTLS && conf.credentialsProvider() != nil {
+		err.add("CredentialsRequireTLS", "can't be satisfied with DisableTLS, the credentials would never be sent")
+	}
+
+	if (conf.ClientCertificateFile == "") != (conf.ClientKeyFile == "") {
+		err.add("ClientCertificateFile", "and ClientKeyFile must be set together")
+	}
+	if (conf.ClientCertificate == nil) != (conf.ClientKey == nil) {
+		err.add("ClientCertificate", "and ClientKey must be set together")
+	}
+	if conf.DisableTLS && (conf.ClientCertificateFile != "" || conf.ClientCertificate != nil) {
+		err.add("DisableTLS", "can't be set together with a client certificate, which requires TLS")
+	}
+
+	if conf.MaxDiscoverAttempts <= 0 {
+		err.add("MaxDiscoverAttempts", "must be positive, got %d", conf.MaxDiscoverAttempts)
+	}
+	if conf.DiscoveryInterval < 0 {
+		err.add("DiscoveryInterval", "must not be negative, got %d", conf.DiscoveryInterval)
+	}
+	if conf.MaxDiscoveryInterval < conf.DiscoveryInterval {
+		err.add("MaxDiscoveryInterval", "must not be smaller than DiscoveryInterval (%d), got %d", conf.DiscoveryInterval, conf.MaxDiscoveryInterval)
+	}
+	if conf.GossipTimeout <= 0 {
+		err.add("GossipTimeout", "must be positive, got %d", conf.GossipTimeout)
+	}
+	if conf.GossipWatchInterval < 0 {
+		err.add("GossipWatchInterval", "must not be negative, got %v", conf.GossipWatchInterval)
+	}
+
+	if conf.DefaultDeadline < -1 {
+		err.add("DefaultDeadline", "must not be negative, except -1 to disable, got %v", conf.DefaultDeadline)
+	}
+	if conf.EagerConnectTimeout < -1 {
+		err.add("EagerConnectTimeout", "must not be negative, except -1 to disable, got %v", conf.EagerConnectTimeout)
+	}
+	if conf.KeepAliveInterval < -1 {
+		err.add("KeepAliveInterval", "must not be negative, except -1 to disable, got %v", conf.KeepAliveInterval)
+	}
+	if conf.KeepAliveTimeout < -1 {
+		err.add("KeepAliveTimeout", "must not be negative, except -1 to disable, got %v", conf.KeepAliveTimeout)
+	}
+
+	return err.orNil()
+}
+
+// credentialsProvider returns the provider authenticating the calls made without credentials.
+func (conf *Configuration) credentialsProvider() CredentialsProvider {
+	if conf.CredentialsProvider != nil {
+		return conf.CredentialsProvider
+	}
+
+	if conf.Username == "" && conf.Password == "" {
+		return nil
+	}
+
+	return NewBasicCredentialsProvider(conf.Username, conf.Password)
+}
+
+// translateAddress returns the address to dial for an address advertised by the cluster.
+func (conf *Configuration) translateAddress(advertised EndPoint) EndPoint {
+	if conf.MapAddress != nil {
+		return conf.MapAddress(advertised)
+	}
+
+	if reachable, ok := conf.AddressMap[advertised]; ok {
+		return reachable
+	}
+
+	return advertised
+}
+
 func parseBoolSetting(k, v string, b *bool, inverse bool) error {
 	var err error
 	*b, err = strconv.ParseBool(strings.ToLower(v))
@@ -371,3 +664,13 @@ const (
 func (nodePreference NodePreference) String() string {
 	return string(nodePreference)
 }
+
+// logf logs a message prefixed with the connection name. conf may be nil.
+func (conf *Configuration) logf(format string, args ...interface{}) {
+	if conf == nil || conf.ConnectionName == "" {
+		log.Printf(format, args...)
+		return
+	}
+
+	log.Printf("[%s] "+format, append([]interface{}{conf.ConnectionName}, args...)...)
+}
@@ -0,0 +1,109 @@
+package esdb
+
+import (
+	"context"
+	"sync"
+
+	"google.golang.org/grpc"
+)
+
+// connectionCalls counts the calls in progress on each connection of a client, so that a
+// connection replaced by another one, after a leader change or a failure, is only closed once the
+// calls and streams still using it are over.
+type connectionCalls struct {
+	lock sync.Mutex
+	// Only holds the connections with calls in progress.
+	active  map[*grpc.ClientConn]int
+	retired map[*grpc.ClientConn]bool
+	closed  bool
+}
+
+func newConnectionCalls() *connectionCalls {
+	return &connectionCalls{
+		active:  make(map[*grpc.ClientConn]int),
+		retired: make(map[*grpc.ClientConn]bool),
+	}
+}
+
+// withInterceptors returns the configuration with the interceptors counting the calls of its
+// connections.
+func (calls *connectionCalls) withInterceptors(conf Configuration) Configuration {
+	conf.UnaryInterceptors = append([]grpc.UnaryClientInterceptor{calls.unaryInterceptor}, conf.UnaryInterceptors...)
+	conf.StreamInterceptors = append([]grpc.StreamClientInterceptor{calls.streamInterceptor}, conf.StreamInterceptors...)
+	return conf
+}
+
+func (calls *connectionCalls) unaryInterceptor(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	calls.begin(cc)
+	defer calls.end(cc)
+
+	return invoker(ctx, method, req, reply, cc, opts...)
+}
+
+func (calls *connectionCalls) streamInterceptor(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	calls.begin(cc)
+
+	stream, err := streamer(ctx, desc, cc, method, opts...)
+	if err != nil {
+		calls.end(cc)
+		return nil, err
+	}
+
+	// The context of the stream is done once the stream is over, whether it completed, failed or
+	// was cancelled.
+	go func() {
+		<-stream.Context().Done()
+		calls.end(cc)
+	}()
+
+	return stream, nil
+}
+
+func (calls *connectionCalls) begin(cc *grpc.ClientConn) {
+	calls.lock.Lock()
+	defer calls.lock.Unlock()
+
+	calls.active[cc]++
+}
+
+func (calls *connectionCalls) end(cc *grpc.ClientConn) {
+	calls.lock.Lock()
+	defer calls.lock.Unlock()
+
+	calls.active[cc]--
+	if calls.active[cc] > 0 {
+		return
+	}
+
+	delete(calls.active, cc)
+	if calls.retired[cc] {
+		delete(calls.retired, cc)
+		go cc.Close()
+	}
+}
+
+// retire closes a connection that was replaced, right away if it has no call in progress or once
+// its last call is over otherwise.
+func (calls *connectionCalls) retire(cc *grpc.ClientConn) {
+	calls.lock.Lock()
+	defer calls.lock.Unlock()
+
+	if calls.active[cc] == 0 || calls.closed {
+		go cc.Close()
+		return
+	}
+
+	calls.retired[cc] = true
+}
+
+// close closes the retired connections without waiting for their calls, as the client is closed.
+func (calls *connectionCalls) close() {
+	calls.lock.Lock()
+	defer calls.lock.Unlock()
+
+	calls.closed = true
+	for cc := range calls.retired {
+		go cc.Close()
+	}
+	calls.retired = make(map[*grpc.ClientConn]bool)
+}
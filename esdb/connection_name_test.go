@@ -0,0 +1,97 @@
+package esdb_test
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/EventStore/EventStore-Client-Go/esdb"
+	api "github.com/EventStore/EventStore-Client-Go/protos/streams"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/metadata"
+)
+
+// syncBuffer is a buffer clients of other tests can still log to while it's read.
+type syncBuffer struct {
+	lock   sync.Mutex
+	buffer bytes.Buffer
+}
+
+func (buffer *syncBuffer) Write(p []byte) (int, error) {
+	buffer.lock.Lock()
+	defer buffer.lock.Unlock()
+	return buffer.buffer.Write(p)
+}
+
+func (buffer *syncBuffer) String() string {
+	buffer.lock.Lock()
+	defer buffer.lock.Unlock()
+	return buffer.buffer.String()
+}
+
+func captureLogs(t *testing.T) *syncBuffer {
+	logs := &syncBuffer{}
+	log.SetOutput(logs)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	return logs
+}
+
+func TestConnectionNameIsSentOnEveryCallAndPrefixesLogs(t *testing.T) {
+	var names, userAgents []string
+	var lock sync.Mutex
+	address := startFakeLeader(t, &fakeStreamsServer{
+		read: func(req *api.ReadReq, server api.Streams_ReadServer) error {
+			md, _ := metadata.FromIncomingContext(server.Context())
+			lock.Lock()
+			names = append(names, md.Get("connection-name")...)
+			userAgents = append(userAgents, md.Get("user-agent")...)
+			lock.Unlock()
+			return server.Send(fakeReadEvent("orders", 0))
+		},
+	})
+
+	logs := captureLogs(t)
+	config, err := esdb.ParseConnectionString("esdb+discover://" + address + "?tls=false&connectionName=orders-service")
+	require.NoError(t, err)
+	assert.Equal(t, "orders-service", config.ConnectionName)
+
+	client, err := esdb.NewClient(config)
+	require.NoError(t, err)
+	defer client.Close()
+	assert.Equal(t, "orders-service", client.ConnectionName())
+
+	_, err = readFirstEvent(client)
+	require.NoError(t, err)
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	assert.Equal(t, []string{"orders-service"}, names)
+	require.Len(t, userAgents, 1)
+	assert.True(t, strings.HasPrefix(userAgents[0], "EventStore-Client-Go (orders-service)"), userAgents[0])
+	assert.Contains(t, logs.String(), "[orders-service] [info] ")
+}
+
+func TestConnectionNameDefaultsToGeneratedName(t *testing.T) {
+	config, err := esdb.ParseConnectionString("esdb://localhost:2113?tls=false")
+	require.NoError(t, err)
+	assert.Empty(t, config.ConnectionName)
+
+	first, err := esdb.NewClient(config)
+	require.NoError(t, err)
+	defer first.Close()
+
+	second, err := esdb.NewClient(config)
+	require.NoError(t, err)
+	defer second.Close()
+
+	assert.True(t, strings.HasPrefix(first.ConnectionName(), "esdb-"), first.ConnectionName())
+	assert.NotEqual(t, first.ConnectionName(), second.ConnectionName())
+	// The configuration of the client isn't modified.
+	assert.Empty(t, config.ConnectionName)
+}
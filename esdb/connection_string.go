@@ -0,0 +1,228 @@
+package esdb
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// redactedPassword replaces the password in redacted connection strings.
+const redactedPassword = "***"
+
+// ConnectionString writes the configuration back as an esdb:// or esdb+discover:// connection
+// string, with the settings that differ from their defaults. When redact is true the password is
+// replaced by "***", so the result can be logged. Settings without connection string form, like
+// Dialer, Tracer or RootCAs not loaded from TLSCAFile, are left out.
+func (conf *Configuration) ConnectionString(redact bool) string {
+	var builder strings.Builder
+
+	if conf.DnsDiscover {
+		builder.WriteString(SchemeNameWithDiscover)
+	} else {
+		builder.WriteString(SchemeName)
+	}
+	builder.WriteString(SchemeSeparator)
+
+	if conf.Username != "" || conf.Password != "" {
+		password := conf.Password
+		if redact {
+			password = redactedPassword
+		}
+
+		builder.WriteString(conf.Username + ":" + password + SchemeUserInfoSeparator)
+	}
+
+	if len(conf.GossipSeeds) > 0 {
+		seeds := make([]string, 0, len(conf.GossipSeeds))
+		for _, seed := range conf.GossipSeeds {
+			if seed != nil {
+				seeds = append(seeds, seed.String())
+			}
+		}
+
+		builder.WriteString(strings.Join(seeds, SchemaHostsSeparator))
+	} else {
+		builder.WriteString(conf.Address)
+	}
+
+	settings := conf.settings()
+	if len(settings) > 0 {
+		builder.WriteString(SchemeQuerySeparator + strings.Join(settings, SchemeSettingSeparator))
+	}
+
+	return builder.String()
+}
+
+// String returns the redacted connection string of the configuration.
+func (conf *Configuration) String() string {
+	return conf.ConnectionString(true)
+}
+
+// settings returns the key=value settings of the configuration that differ from the defaults.
+func (conf *Configuration) settings() []string {
+	defaults := defaultConfiguration()
+	var settings []string
+
+	add := func(key string, value string) {
+		settings = append(settings, key+"="+value)
+	}
+
+	if conf.ConnectionName != "" {
+		add("connectionName", conf.ConnectionName)
+	}
+	if conf.DisableTLS {
+		add("tls", "false")
+	}
+	if conf.SkipCertificateVerification {
+		add("tlsVerifyCert", "false")
+	}
+	if conf.TLSCAFile != "" {
+		add("tlsCAFile", conf.TLSCAFile)
+	}
+	if conf.ClientCertificateFile != "" {
+		add("userCertFile", conf.ClientCertificateFile)
+	}
+	if conf.ClientKeyFile != "" {
+		add("userKeyFile", conf.ClientKeyFile)
+	}
+	if conf.NodePreference != "" {
+		add("nodePreference", conf.NodePreference.String())
+	}
+	if conf.MaxDiscoverAttempts != defaults.MaxDiscoverAttempts {
+		add("maxDiscoverAttempts", strconv.Itoa(conf.MaxDiscoverAttempts))
+	}
+	if conf.DiscoveryInterval != defaults.DiscoveryInterval {
+		add("discoveryInterval", strconv.Itoa(conf.DiscoveryInterval))
+	}
+	if conf.MaxDiscoveryInterval != defaults.MaxDiscoveryInterval {
+		add("maxDiscoveryInterval", strconv.Itoa(conf.MaxDiscoveryInterval))
+	}
+	if conf.GossipTimeout != defaults.GossipTimeout {
+		add("gossipTimeout", strconv.Itoa(conf.GossipTimeout))
+	}
+	if len(conf.AddressMap) > 0 {
+		add("addressMap", formatAddressMap(conf.AddressMap))
+	}
+	if conf.KeepAliveInterval != defaults.KeepAliveInterval {
+		add("keepAliveInterval", formatMilliseconds(conf.KeepAliveInterval))
+	}
+	if conf.KeepAliveTimeout != defaults.KeepAliveTimeout {
+		add("keepAliveTimeout", formatMilliseconds(conf.KeepAliveTimeout))
+	}
+	if conf.DefaultDeadline != defaults.DefaultDeadline {
+		add("defaultDeadline", formatMilliseconds(conf.DefaultDeadline))
+	}
+	if conf.EagerConnectTimeout != defaults.EagerConnectTimeout {
+		add("eagerConnectTimeout", formatMilliseconds(conf.EagerConnectTimeout))
+	}
+
+	return settings
+}
+
+// formatMilliseconds formats a duration setting, negative durations being written as -1.
+func formatMilliseconds(duration time.Duration) string {
+	if duration < 0 {
+		return "-1"
+	}
+
+	return strconv.FormatInt(int64(duration/time.Millisecond), 10)
+}
+
+// formatAddressMap formats an address map the way parseAddressMap reads it, sorted by address.
+func formatAddressMap(addressMap map[EndPoint]EndPoint) string {
+	entries := make([]string, 0, len(addressMap))
+	for advertised, reachable := range addressMap {
+		entries = append(entries, advertised.String()+"->"+reachable.String())
+	}
+	sort.Strings(entries)
+
+	return strings.Join(entries, ",")
+}
+
+// environmentSettings maps the ESDB_* environment variables read by FromEnv to the connection
+// string settings they override.
+var environmentSettings = []struct {
+	variable string
+	setting  string
+}{
+	{"ESDB_CONNECTION_NAME", "connectionName"},
+	{"ESDB_TLS", "tls"},
+	{"ESDB_TLS_VERIFY_CERT", "tlsVerifyCert"},
+	{"ESDB_TLS_CA_FILE", "tlsCAFile"},
+	{"ESDB_USER_CERT_FILE", "userCertFile"},
+	{"ESDB_USER_KEY_FILE", "userKeyFile"},
+	{"ESDB_NODE_PREFERENCE", "nodePreference"},
+	{"ESDB_MAX_DISCOVER_ATTEMPTS", "maxDiscoverAttempts"},
+	{"ESDB_DISCOVERY_INTERVAL", "discoveryInterval"},
+	{"ESDB_MAX_DISCOVERY_INTERVAL", "maxDiscoveryInterval"},
+	{"ESDB_GOSSIP_TIMEOUT", "gossipTimeout"},
+	{"ESDB_ADDRESS_MAP", "addressMap"},
+	{"ESDB_KEEP_ALIVE_INTERVAL", "keepAliveInterval"},
+	{"ESDB_KEEP_ALIVE_TIMEOUT", "keepAliveTimeout"},
+	{"ESDB_DEFAULT_DEADLINE", "defaultDeadline"},
+	{"ESDB_EAGER_CONNECT_TIMEOUT", "eagerConnectTimeout"},
+}
+
+// FromEnv creates a Configuration from a connection string, overridden by the ESDB_* environment
+// variables:
+//   - ESDB_CONNECTION_STRING replaces connectionString.
+//   - ESDB_HOSTS replaces its hosts, a comma-separated list of host:port.
+//   - ESDB_USERNAME and ESDB_PASSWORD replace its credentials.
+//   - Every setting has a variable named after it, like ESDB_NODE_PREFERENCE for nodePreference
+//     or ESDB_TLS_CA_FILE for tlsCAFile, taking the same values.
+//
+// Empty variables are ignored. The resulting configuration is validated, see Validate.
+func FromEnv(connectionString string) (*Configuration, error) {
+	if value := os.Getenv("ESDB_CONNECTION_STRING"); value != "" {
+		connectionString = value
+	}
+
+	if connectionString == "" {
+		return nil, fmt.Errorf("No connection string given, nor set in ESDB_CONNECTION_STRING")
+	}
+
+	config, err := ParseConnectionString(connectionString)
+	if err != nil {
+		return nil, err
+	}
+
+	if hosts := os.Getenv("ESDB_HOSTS"); hosts != "" {
+		config.Address = ""
+		config.GossipSeeds = nil
+
+		if err := parseHost(hosts, config); err != nil {
+			return nil, fmt.Errorf("Invalid ESDB_HOSTS: %w", err)
+		}
+	}
+
+	if username := os.Getenv("ESDB_USERNAME"); username != "" {
+		config.Username = username
+	}
+	if password := os.Getenv("ESDB_PASSWORD"); password != "" {
+		config.Password = password
+	}
+
+	for _, setting := range environmentSettings {
+		value := os.Getenv(setting.variable)
+		if value == "" {
+			continue
+		}
+
+		if err := parseSetting(setting.setting, value, config); err != nil {
+			return nil, fmt.Errorf("Invalid %s: %w", setting.variable, err)
+		}
+	}
+
+	if err := checkClientCertificateFiles(config); err != nil {
+		return nil, err
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
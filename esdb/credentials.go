@@ -1,6 +1,174 @@
 package esdb
 
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"sync"
+	"time"
+
+	grpcCredentials "google.golang.org/grpc/credentials"
+)
+
 type Credentials struct {
 	Login    string
 	Password string
+	// Client certificate authenticating the operation instead of Login and Password. The operation
+	// runs on a connection to the same node dedicated to that certificate.
+	Certificate *tls.Certificate
+}
+
+// CredentialsProvider authenticates the calls made without credentials of their own. It is
+// consulted on every call, so the credentials it returns can change over time.
+type CredentialsProvider interface {
+	// GetRequestMetadata returns the headers authenticating a call, ctx being the context of the
+	// call. A nil map sends the call anonymously.
+	GetRequestMetadata(ctx context.Context) (map[string]string, error)
+}
+
+type credentialsContextKey struct{}
+
+// ContextWithCredentials returns a context authenticating the calls made with it, e.g. to
+// impersonate the user a request is served for. It takes precedence over the CredentialsProvider
+// of the configuration, the Authenticated option of a call taking precedence over both.
+func ContextWithCredentials(ctx context.Context, credentials Credentials) context.Context {
+	return context.WithValue(ctx, credentialsContextKey{}, credentials)
+}
+
+// CredentialsFromContext returns the credentials set by ContextWithCredentials, if any.
+func CredentialsFromContext(ctx context.Context) (Credentials, bool) {
+	credentials, ok := ctx.Value(credentialsContextKey{}).(Credentials)
+	return credentials, ok
+}
+
+// withCallCredentials authenticates a call with the credentials of its options, if any.
+func withCallCredentials(ctx context.Context, credentials *Credentials) context.Context {
+	if credentials == nil {
+		return ctx
+	}
+
+	return ContextWithCredentials(ctx, *credentials)
+}
+
+// BasicCredentialsProvider authenticates calls with a login and a password that can be rotated
+// at runtime.
+type BasicCredentialsProvider struct {
+	lock        sync.RWMutex
+	credentials basicAuth
+}
+
+func NewBasicCredentialsProvider(login string, password string) *BasicCredentialsProvider {
+	return &BasicCredentialsProvider{
+		credentials: basicAuth{username: login, password: password},
+	}
+}
+
+// Update replaces the credentials used by the next calls.
+func (provider *BasicCredentialsProvider) Update(login string, password string) {
+	provider.lock.Lock()
+	defer provider.lock.Unlock()
+	provider.credentials = basicAuth{username: login, password: password}
+}
+
+func (provider *BasicCredentialsProvider) GetRequestMetadata(ctx context.Context) (map[string]string, error) {
+	provider.lock.RLock()
+	defer provider.lock.RUnlock()
+	return provider.credentials.GetRequestMetadata(ctx)
+}
+
+// BearerToken is a token sent in the Authorization header of calls.
+type BearerToken struct {
+	Token string
+	// When the token stops being valid. A zero value never expires.
+	ExpiresAt time.Time
+}
+
+// BearerTokenProvider authenticates calls with a bearer token, fetching a new one when the
+// current one is about to expire.
+type BearerTokenProvider struct {
+	fetch func(ctx context.Context) (BearerToken, error)
+	// How long before its expiry a token gets refreshed.
+	refreshMargin time.Duration
+
+	lock  sync.Mutex
+	token BearerToken
+}
+
+// NewBearerTokenProvider returns a provider getting its tokens from fetch, which is called with the
+// context of the call needing a new token. Tokens are refreshed refreshMargin before they expire.
+func NewBearerTokenProvider(fetch func(ctx context.Context) (BearerToken, error), refreshMargin time.Duration) *BearerTokenProvider {
+	return &BearerTokenProvider{
+		fetch:         fetch,
+		refreshMargin: refreshMargin,
+	}
+}
+
+// Invalidate makes the next call fetch a new token, e.g. after the server rejected the current one.
+func (provider *BearerTokenProvider) Invalidate() {
+	provider.lock.Lock()
+	defer provider.lock.Unlock()
+	provider.token = BearerToken{}
+}
+
+func (provider *BearerTokenProvider) GetRequestMetadata(ctx context.Context) (map[string]string, error) {
+	provider.lock.Lock()
+	defer provider.lock.Unlock()
+
+	if provider.needsRefresh() {
+		token, err := provider.fetch(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch a bearer token: %w", err)
+		}
+
+		provider.token = token
+	}
+
+	return map[string]string{
+		"Authorization": "Bearer " + provider.token.Token,
+	}, nil
+}
+
+func (provider *BearerTokenProvider) needsRefresh() bool {
+	if provider.token.Token == "" {
+		return true
+	}
+
+	if provider.token.ExpiresAt.IsZero() {
+		return false
+	}
+
+	return !time.Now().Add(provider.refreshMargin).Before(provider.token.ExpiresAt)
+}
+
+// callCredentials authenticates every call made on a connection, see CredentialsProvider.
+type callCredentials struct {
+	provider                 CredentialsProvider
+	requireTransportSecurity bool
+}
+
+func (c callCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	if credentials, ok := CredentialsFromContext(ctx); ok {
+		// The connection can't require transport security when the client has no credentials of its
+		// own, the ones of the call are checked instead.
+		if c.requireTransportSecurity && c.provider == nil {
+			info, _ := grpcCredentials.RequestInfoFromContext(ctx)
+			if err := grpcCredentials.CheckSecurityLevel(info.AuthInfo, grpcCredentials.PrivacyAndIntegrity); err != nil {
+				return nil, fmt.Errorf("the credentials require transport level security: %w", err)
+			}
+		}
+
+		return basicAuth{username: credentials.Login, password: credentials.Password}.GetRequestMetadata(ctx)
+	}
+
+	if c.provider == nil {
+		return nil, nil
+	}
+
+	return c.provider.GetRequestMetadata(ctx)
+}
+
+// RequireTransportSecurity only requires it when there are credentials to send, so that anonymous
+// clients work without TLS.
+func (c callCredentials) RequireTransportSecurity() bool {
+	return c.requireTransportSecurity && c.provider != nil
 }
@@ -0,0 +1,174 @@
+package esdb_test
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/EventStore/EventStore-Client-Go/esdb"
+	api "github.com/EventStore/EventStore-Client-Go/protos/streams"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/metadata"
+)
+
+// authorizationRecorder serves reads and records the Authorization header they were sent with.
+type authorizationRecorder struct {
+	lock   sync.Mutex
+	values []string
+}
+
+func (recorder *authorizationRecorder) server() *fakeStreamsServer {
+	return &fakeStreamsServer{
+		read: func(req *api.ReadReq, server api.Streams_ReadServer) error {
+			md, _ := metadata.FromIncomingContext(server.Context())
+			recorder.lock.Lock()
+			recorder.values = append(recorder.values, firstValue(md.Get("authorization")))
+			recorder.lock.Unlock()
+			return server.Send(fakeReadEvent("orders", 0))
+		},
+	}
+}
+
+func firstValue(values []string) string {
+	if len(values) != 1 {
+		return ""
+	}
+
+	return values[0]
+}
+
+func (recorder *authorizationRecorder) last() string {
+	recorder.lock.Lock()
+	defer recorder.lock.Unlock()
+	return recorder.values[len(recorder.values)-1]
+}
+
+func basic(login string, password string) string {
+	return "Basic " + base64.StdEncoding.EncodeToString([]byte(login+":"+password))
+}
+
+func startAuthorizationRecorder(t *testing.T, configure func(config *esdb.Configuration)) (*esdb.Client, *authorizationRecorder) {
+	recorder := &authorizationRecorder{}
+	address := startFakeLeader(t, recorder.server())
+
+	config, err := esdb.ParseConnectionString("esdb://" + address + "?tls=false")
+	require.NoError(t, err)
+	configure(config)
+
+	client, err := esdb.NewClient(config)
+	require.NoError(t, err)
+	t.Cleanup(func() { client.Close() })
+
+	return client, recorder
+}
+
+func readAs(t *testing.T, ctx context.Context, client *esdb.Client, auth *esdb.Credentials) {
+	stream, err := client.ReadStream(ctx, "orders", esdb.ReadStreamOptions{Authenticated: auth}, 1)
+	require.NoError(t, err)
+	stream.Close()
+}
+
+func TestCredentialsPrecedence(t *testing.T) {
+	provider := esdb.NewBasicCredentialsProvider("admin", "changeit")
+	client, recorder := startAuthorizationRecorder(t, func(config *esdb.Configuration) {
+		config.Username = "ignored"
+		config.Password = "ignored"
+		config.CredentialsProvider = provider
+	})
+
+	readAs(t, context.Background(), client, nil)
+	assert.Equal(t, basic("admin", "changeit"), recorder.last())
+
+	impersonated := esdb.ContextWithCredentials(context.Background(), esdb.Credentials{Login: "alice", Password: "secret"})
+	readAs(t, impersonated, client, nil)
+	assert.Equal(t, basic("alice", "secret"), recorder.last())
+
+	readAs(t, impersonated, client, &esdb.Credentials{Login: "bob", Password: "secret"})
+	assert.Equal(t, basic("bob", "secret"), recorder.last())
+
+	provider.Update("admin", "rotated")
+	readAs(t, context.Background(), client, nil)
+	assert.Equal(t, basic("admin", "rotated"), recorder.last())
+}
+
+func TestStaticCredentialsAreSentOnce(t *testing.T) {
+	client, recorder := startAuthorizationRecorder(t, func(config *esdb.Configuration) {
+		config.Username = "admin"
+		config.Password = "changeit"
+	})
+
+	readAs(t, context.Background(), client, &esdb.Credentials{Login: "bob", Password: "secret"})
+	assert.Equal(t, basic("bob", "secret"), recorder.last())
+
+	readAs(t, context.Background(), client, nil)
+	assert.Equal(t, basic("admin", "changeit"), recorder.last())
+}
+
+func TestBearerTokenProviderRefreshesExpiringTokens(t *testing.T) {
+	var lock sync.Mutex
+	fetched := 0
+	provider := esdb.NewBearerTokenProvider(func(ctx context.Context) (esdb.BearerToken, error) {
+		lock.Lock()
+		defer lock.Unlock()
+		fetched++
+
+		// The first token is about to expire, the next ones are valid for an hour.
+		expiresAt := time.Now().Add(time.Hour)
+		if fetched == 1 {
+			expiresAt = time.Now().Add(time.Second)
+		}
+
+		return esdb.BearerToken{Token: "token-" + string(rune('0'+fetched)), ExpiresAt: expiresAt}, nil
+	}, 10*time.Second)
+
+	client, recorder := startAuthorizationRecorder(t, func(config *esdb.Configuration) {
+		config.CredentialsProvider = provider
+	})
+
+	readAs(t, context.Background(), client, nil)
+	assert.Equal(t, "Bearer token-1", recorder.last())
+
+	readAs(t, context.Background(), client, nil)
+	assert.Equal(t, "Bearer token-2", recorder.last())
+
+	readAs(t, context.Background(), client, nil)
+	assert.Equal(t, "Bearer token-2", recorder.last())
+
+	provider.Invalidate()
+	readAs(t, context.Background(), client, nil)
+	assert.Equal(t, "Bearer token-3", recorder.last())
+}
+
+func TestCredentialsRequireTLS(t *testing.T) {
+	client, _ := startAuthorizationRecorder(t, func(config *esdb.Configuration) {
+		config.Username = "admin"
+		config.Password = "changeit"
+		config.CredentialsRequireTLS = true
+		config.MaxDiscoverAttempts = 1
+	})
+
+	_, err := client.ReadStream(context.Background(), "orders", esdb.ReadStreamOptions{}, 1)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "transport level security")
+}
+
+func TestCredentialsRequireTLSLetsAnonymousCallsThrough(t *testing.T) {
+	client, recorder := startAuthorizationRecorder(t, func(config *esdb.Configuration) {
+		config.CredentialsRequireTLS = true
+	})
+
+	readAs(t, context.Background(), client, nil)
+	assert.Equal(t, "", recorder.last())
+
+	// The credentials of a call still aren't sent without TLS.
+	_, err := client.ReadStream(context.Background(), "orders", esdb.ReadStreamOptions{
+		Authenticated: &esdb.Credentials{Login: "alice", Password: "secret"},
+	}, 1)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, esdb.ErrUnauthenticated), err.Error())
+	assert.Equal(t, "", recorder.last())
+}
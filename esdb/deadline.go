@@ -0,0 +1,145 @@
+package esdb
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// resolveDeadline returns the deadline of an operation, 0 meaning none. Operations without a
+// deadline of their own use Configuration.DefaultDeadline, unless ctx already has a deadline. A
+// negative deadline disables it.
+func (client *grpcClient) resolveDeadline(ctx context.Context, deadline time.Duration) time.Duration {
+	if deadline == 0 {
+		if _, ok := ctx.Deadline(); ok {
+			return 0
+		}
+
+		deadline = client.config.DefaultDeadline
+	}
+
+	if deadline < 0 {
+		return 0
+	}
+
+	return deadline
+}
+
+type deadlineKey struct{}
+
+// operationDeadline is stored in the context of an operation bounded by withDeadline.
+type operationDeadline struct {
+	operation string
+	deadline  time.Duration
+	// The context of the caller, telling if it ended the operation rather than the deadline.
+	parent context.Context
+}
+
+// withDeadline bounds a non-streaming operation by its deadline, see deadlineError.
+func (client *grpcClient) withDeadline(ctx context.Context, operation string, deadline time.Duration) (context.Context, context.CancelFunc) {
+	deadline = client.resolveDeadline(ctx, deadline)
+	if deadline == 0 {
+		return context.WithCancel(ctx)
+	}
+
+	bounded := context.WithValue(ctx, deadlineKey{}, operationDeadline{operation: operation, deadline: deadline, parent: ctx})
+	return context.WithTimeout(bounded, deadline)
+}
+
+// deadlineError returns a *DeadlineExceededError when an operation bounded by withDeadline failed
+// because its own deadline expired, rather than the one of the caller's context.
+func deadlineError(ctx context.Context, err error) error {
+	if err == nil || ctx.Err() != context.DeadlineExceeded {
+		return err
+	}
+
+	timeout, ok := ctx.Value(deadlineKey{}).(operationDeadline)
+	if !ok || timeout.parent.Err() != nil {
+		return err
+	}
+
+	return &DeadlineExceededError{
+		Operation: timeout.operation,
+		Deadline:  timeout.deadline,
+		Err:       err,
+	}
+}
+
+// handshakeDeadline bounds the start of a streaming operation, from acquiring a connection to
+// receiving the first response. The stream itself isn't bounded.
+type handshakeDeadline struct {
+	operation string
+	deadline  time.Duration
+	expiresAt time.Time
+
+	lock    sync.Mutex
+	timer   *time.Timer
+	expired bool
+}
+
+func (client *grpcClient) startHandshake(ctx context.Context, operation string, deadline time.Duration) *handshakeDeadline {
+	deadline = client.resolveDeadline(ctx, deadline)
+
+	return &handshakeDeadline{
+		operation: operation,
+		deadline:  deadline,
+		expiresAt: time.Now().Add(deadline),
+	}
+}
+
+// getConnectionHandle acquires the connection of the operation within the deadline.
+func (handshake *handshakeDeadline) getConnectionHandle(ctx context.Context, client *grpcClient, preference NodePreference, auth *Credentials) (connectionHandle, error) {
+	if handshake.deadline == 0 {
+		return client.getConnectionHandleFor(ctx, preference, auth)
+	}
+
+	bounded, cancel := context.WithDeadline(ctx, handshake.expiresAt)
+	defer cancel()
+
+	handle, err := client.getConnectionHandleFor(bounded, preference, auth)
+	if err != nil && bounded.Err() == context.DeadlineExceeded && ctx.Err() == nil {
+		handshake.lock.Lock()
+		handshake.expired = true
+		handshake.lock.Unlock()
+	}
+
+	return handle, err
+}
+
+// watch cancels the stream if the deadline expires before done is called.
+func (handshake *handshakeDeadline) watch(cancel context.CancelFunc) {
+	if handshake.deadline == 0 {
+		return
+	}
+
+	handshake.lock.Lock()
+	defer handshake.lock.Unlock()
+
+	handshake.timer = time.AfterFunc(time.Until(handshake.expiresAt), func() {
+		handshake.lock.Lock()
+		handshake.expired = true
+		handshake.lock.Unlock()
+		cancel()
+	})
+}
+
+// done stops the deadline once the handshake is over. It returns a *DeadlineExceededError
+// wrapping err when the handshake failed because the deadline expired.
+func (handshake *handshakeDeadline) done(err error) error {
+	handshake.lock.Lock()
+	defer handshake.lock.Unlock()
+
+	if handshake.timer != nil {
+		handshake.timer.Stop()
+	}
+
+	if err == nil || !handshake.expired {
+		return err
+	}
+
+	return &DeadlineExceededError{
+		Operation: handshake.operation,
+		Deadline:  handshake.deadline,
+		Err:       err,
+	}
+}
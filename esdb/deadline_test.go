@@ -0,0 +1,172 @@
+package esdb_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/EventStore/EventStore-Client-Go/esdb"
+	gossipApi "github.com/EventStore/EventStore-Client-Go/protos/gossip"
+	api "github.com/EventStore/EventStore-Client-Go/protos/streams"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+// hangingServer never answers deletes nor subscriptions. Reads send one event right away and a
+// second one after a delay.
+func hangingServer(secondEventDelay time.Duration) *fakeStreamsServer {
+	return &fakeStreamsServer{
+		read: func(req *api.ReadReq, server api.Streams_ReadServer) error {
+			if req.GetOptions().GetSubscription() != nil {
+				<-server.Context().Done()
+				return nil
+			}
+
+			if err := server.Send(fakeReadEvent("orders", 0)); err != nil {
+				return err
+			}
+
+			time.Sleep(secondEventDelay)
+			return server.Send(fakeReadEvent("orders", 1))
+		},
+		delete: func(ctx context.Context, req *api.DeleteReq) (*api.DeleteResp, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		},
+	}
+}
+
+func createDeadlineClient(t *testing.T, defaultDeadline string) *esdb.Client {
+	address := startFakeServer(t, func(server *grpc.Server) {
+		api.RegisterStreamsServer(server, hangingServer(200*time.Millisecond))
+	})
+
+	client := CreateClient("esdb://"+address+"?tls=false&defaultDeadline="+defaultDeadline, t)
+	t.Cleanup(func() { client.Close() })
+
+	return client
+}
+
+func assertDeadlineExceeded(t *testing.T, err error, operation string, deadline time.Duration) {
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+
+	var timeout *esdb.DeadlineExceededError
+	require.True(t, errors.As(err, &timeout))
+	assert.Equal(t, operation, timeout.Operation)
+	assert.Equal(t, deadline, timeout.Deadline)
+}
+
+func TestDefaultDeadlineBoundsOperations(t *testing.T) {
+	client := createDeadlineClient(t, "50")
+
+	_, err := client.DeleteStream(context.Background(), "orders", esdb.DeleteStreamOptions{})
+	assertDeadlineExceeded(t, err, "DeleteStream", 50*time.Millisecond)
+
+	_, err = client.SubscribeToStream(context.Background(), "orders", esdb.SubscribeToStreamOptions{})
+	assertDeadlineExceeded(t, err, "SubscribeToStream", 50*time.Millisecond)
+}
+
+func TestDefaultDeadlineBoundsGossipReads(t *testing.T) {
+	var hang int32
+	address := startFakeGossipServer(t, &fakeGossipServer{
+		read: func(ctx context.Context) (*gossipApi.ClusterInfo, error) {
+			if atomic.LoadInt32(&hang) == 1 {
+				// Gives up eventually should the default deadline not apply.
+				select {
+				case <-ctx.Done():
+				case <-time.After(5 * time.Second):
+				}
+				return nil, ctx.Err()
+			}
+
+			return &gossipApi.ClusterInfo{}, nil
+		},
+	})
+
+	client := CreateClient("esdb://"+address+"?tls=false&defaultDeadline=50", t)
+	defer client.Close()
+
+	_, err := client.ReadGossip(context.Background())
+	require.NoError(t, err)
+
+	atomic.StoreInt32(&hang, 1)
+	_, err = client.ReadGossip(context.Background())
+	assertDeadlineExceeded(t, err, "ReadGossip", 50*time.Millisecond)
+}
+
+func TestOperationDeadlineOverridesDefault(t *testing.T) {
+	client := createDeadlineClient(t, "-1")
+
+	_, err := client.DeleteStream(context.Background(), "orders", esdb.DeleteStreamOptions{Deadline: 50 * time.Millisecond})
+	assertDeadlineExceeded(t, err, "DeleteStream", 50*time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	// Without any deadline, only the caller's context ends the call.
+	_, err = client.DeleteStream(ctx, "orders", esdb.DeleteStreamOptions{})
+	require.Error(t, err)
+	var timeout *esdb.DeadlineExceededError
+	assert.False(t, errors.As(err, &timeout))
+}
+
+func TestDeadlineOnlyBoundsTheStartOfReads(t *testing.T) {
+	client := createDeadlineClient(t, "50")
+
+	stream, err := client.ReadStream(context.Background(), "orders", esdb.ReadStreamOptions{}, 2)
+	require.NoError(t, err)
+	defer stream.Close()
+
+	event, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(0), event.OriginalEvent().EventNumber)
+
+	// The second event arrives well after the deadline.
+	event, err = stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), event.OriginalEvent().EventNumber)
+}
+
+func TestDefaultDeadlineYieldsToCallerDeadline(t *testing.T) {
+	client := createDeadlineClient(t, "200")
+
+	for _, callerDeadline := range []time.Duration{50 * time.Millisecond, 500 * time.Millisecond} {
+		ctx, cancel := context.WithTimeout(context.Background(), callerDeadline)
+
+		started := time.Now()
+		_, err := client.DeleteStream(ctx, "orders", esdb.DeleteStreamOptions{})
+		elapsed := time.Since(started)
+		cancel()
+
+		// The caller's deadline applies instead of the default one, and it's not reported as ours.
+		require.Error(t, err)
+		var timeout *esdb.DeadlineExceededError
+		assert.False(t, errors.As(err, &timeout), err.Error())
+		assert.GreaterOrEqual(t, int64(elapsed), int64(callerDeadline))
+		assert.Less(t, int64(elapsed), int64(callerDeadline+time.Second))
+	}
+
+	// A deadline of the operation still applies within the caller's one.
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := client.DeleteStream(ctx, "orders", esdb.DeleteStreamOptions{Deadline: 50 * time.Millisecond})
+	assertDeadlineExceeded(t, err, "DeleteStream", 50*time.Millisecond)
+
+	// The caller's deadline expiring first isn't reported as the one of the operation.
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err = client.DeleteStream(ctx, "orders", esdb.DeleteStreamOptions{Deadline: time.Second})
+	require.Error(t, err)
+	var timeout *esdb.DeadlineExceededError
+	assert.False(t, errors.As(err, &timeout), err.Error())
+
+	_, err = client.SubscribeToStream(ctx, "orders", esdb.SubscribeToStreamOptions{})
+	require.Error(t, err)
+	assert.False(t, errors.As(err, &timeout), err.Error())
+}
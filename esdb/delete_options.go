@@ -1,8 +1,11 @@
 package esdb
 
+import "time"
+
 type DeleteStreamOptions struct {
 	ExpectedRevision ExpectedRevision
 	Authenticated    *Credentials
+	Deadline         time.Duration // Defaults to Configuration.DefaultDeadline, -1 disables it.
 }
 
 func (o *DeleteStreamOptions) setDefaults() {
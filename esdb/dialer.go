@@ -0,0 +1,200 @@
+package esdb
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// Dialer opens the network connections to the nodes, address being host:port.
+type Dialer func(ctx context.Context, address string) (net.Conn, error)
+
+// HTTPProxyDialer returns a Dialer tunnelling connections through the HTTP proxy at proxyURL with
+// CONNECT requests. The user info of proxyURL, if any, is sent as basic proxy authorization.
+func HTTPProxyDialer(proxyURL *url.URL) (Dialer, error) {
+	if proxyURL.Scheme != "http" {
+		return nil, fmt.Errorf("unsupported proxy scheme %q, only http proxies are supported", proxyURL.Scheme)
+	}
+
+	proxyAddress := proxyURL.Host
+	if proxyURL.Port() == "" {
+		proxyAddress = net.JoinHostPort(proxyURL.Hostname(), "80")
+	}
+
+	var authorization string
+	if proxyURL.User != nil {
+		password, _ := proxyURL.User.Password()
+		credentials := proxyURL.User.Username() + ":" + password
+		authorization = "Basic " + base64.StdEncoding.EncodeToString([]byte(credentials))
+	}
+
+	return func(ctx context.Context, address string) (net.Conn, error) {
+		var dialer net.Dialer
+		conn, err := dialer.DialContext(ctx, "tcp", proxyAddress)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to proxy %s: %w", proxyAddress, err)
+		}
+
+		tunnel, err := connectThroughProxy(ctx, conn, address, authorization)
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+
+		return tunnel, nil
+	}, nil
+}
+
+// ProxyDialerFromEnvironment returns a Dialer using the HTTP proxy of the HTTPS_PROXY environment
+// variable, or https_proxy, except for the addresses matching NO_PROXY, or no_proxy, which are
+// dialed directly. It returns nil when no proxy is configured.
+func ProxyDialerFromEnvironment() (Dialer, error) {
+	proxy := getEnvAny("HTTPS_PROXY", "https_proxy")
+	if proxy == "" {
+		return nil, nil
+	}
+
+	// Like net/http, a proxy without scheme is an http proxy.
+	if !strings.Contains(proxy, "://") {
+		proxy = "http://" + proxy
+	}
+
+	proxyURL, err := url.Parse(proxy)
+	if err != nil {
+		return nil, fmt.Errorf("invalid HTTPS_PROXY %q: %w", proxy, err)
+	}
+
+	proxied, err := HTTPProxyDialer(proxyURL)
+	if err != nil {
+		return nil, err
+	}
+
+	noProxy := strings.Split(getEnvAny("NO_PROXY", "no_proxy"), ",")
+	return func(ctx context.Context, address string) (net.Conn, error) {
+		if bypassesProxy(address, noProxy) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, "tcp", address)
+		}
+
+		return proxied(ctx, address)
+	}, nil
+}
+
+func getEnvAny(names ...string) string {
+	for _, name := range names {
+		if value := os.Getenv(name); value != "" {
+			return value
+		}
+	}
+
+	return ""
+}
+
+// bypassesProxy tells if address matches a NO_PROXY entry: "*", an IP, a CIDR, or a domain
+// matching itself and its subdomains, optionally restricted to a port. Like in net/http.
+func bypassesProxy(address string, noProxy []string) bool {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		host = address
+	}
+	host = strings.ToLower(host)
+
+	for _, entry := range noProxy {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if entry == "" {
+			continue
+		}
+
+		if entry == "*" {
+			return true
+		}
+
+		if _, network, err := net.ParseCIDR(entry); err == nil {
+			if ip := net.ParseIP(host); ip != nil && network.Contains(ip) {
+				return true
+			}
+
+			continue
+		}
+
+		if entryHost, entryPort, err := net.SplitHostPort(entry); err == nil {
+			if entryPort != port {
+				continue
+			}
+			entry = entryHost
+		}
+
+		// A leading "." or "*." only matches the subdomains.
+		entry = strings.TrimPrefix(entry, "*")
+		if strings.HasPrefix(entry, ".") {
+			if strings.HasSuffix(host, entry) {
+				return true
+			}
+
+			continue
+		}
+
+		if host == entry || strings.HasSuffix(host, "."+entry) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// connectThroughProxy asks the proxy conn is connected to for a tunnel to address.
+func connectThroughProxy(ctx context.Context, conn net.Conn, address string, authorization string) (net.Conn, error) {
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+		defer conn.SetDeadline(time.Time{})
+	}
+
+	request := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: address},
+		Host:   address,
+		Header: http.Header{},
+	}
+
+	if authorization != "" {
+		request.Header.Set("Proxy-Authorization", authorization)
+	}
+
+	if err := request.Write(conn); err != nil {
+		return nil, fmt.Errorf("failed to send CONNECT request to proxy: %w", err)
+	}
+
+	reader := bufio.NewReader(conn)
+	response, err := http.ReadResponse(reader, request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CONNECT response from proxy: %w", err)
+	}
+	response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("proxy refused to connect to %s: %s", address, response.Status)
+	}
+
+	// The server may speak first, its bytes would then be buffered by the reader.
+	if reader.Buffered() > 0 {
+		return &bufferedConn{Conn: conn, reader: reader}, nil
+	}
+
+	return conn, nil
+}
+
+type bufferedConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func (conn *bufferedConn) Read(b []byte) (int, error) {
+	return conn.reader.Read(b)
+}
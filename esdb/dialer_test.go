@@ -0,0 +1,268 @@
+package esdb_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/EventStore/EventStore-Client-Go/esdb"
+	api "github.com/EventStore/EventStore-Client-Go/protos/streams"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// connectProxy is an HTTP proxy only supporting CONNECT, recording the requested targets.
+type connectProxy struct {
+	address       string
+	authorization string
+	lock          sync.Mutex
+	targets       []string
+}
+
+func startConnectProxy(t *testing.T, authorization string) *connectProxy {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	proxy := &connectProxy{address: listener.Addr().String(), authorization: authorization}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			go proxy.serve(conn)
+		}
+	}()
+
+	return proxy
+}
+
+func (proxy *connectProxy) serve(conn net.Conn) {
+	defer conn.Close()
+
+	reader := bufio.NewReader(conn)
+	request, err := http.ReadRequest(reader)
+	if err != nil {
+		return
+	}
+
+	proxy.lock.Lock()
+	proxy.targets = append(proxy.targets, request.Host)
+	proxy.lock.Unlock()
+
+	if request.Method != http.MethodConnect || request.Header.Get("Proxy-Authorization") != proxy.authorization {
+		io.WriteString(conn, "HTTP/1.1 407 Proxy Authentication Required\r\n\r\n")
+		return
+	}
+
+	target, err := net.Dial("tcp", request.Host)
+	if err != nil {
+		io.WriteString(conn, "HTTP/1.1 502 Bad Gateway\r\n\r\n")
+		return
+	}
+	defer target.Close()
+
+	io.WriteString(conn, "HTTP/1.1 200 Connection established\r\n\r\n")
+	go io.Copy(target, reader)
+	io.Copy(conn, target)
+}
+
+func (proxy *connectProxy) requested() []string {
+	proxy.lock.Lock()
+	defer proxy.lock.Unlock()
+	return append([]string(nil), proxy.targets...)
+}
+
+func setEnv(t *testing.T, name string, value string) {
+	previous, existed := os.LookupEnv(name)
+	os.Setenv(name, value)
+	t.Cleanup(func() {
+		if existed {
+			os.Setenv(name, previous)
+		} else {
+			os.Unsetenv(name)
+		}
+	})
+}
+
+func readFirstEvent(client *esdb.Client) (*esdb.ResolvedEvent, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stream, err := client.ReadStream(ctx, "orders", esdb.ReadStreamOptions{}, 1)
+	if err != nil {
+		return nil, err
+	}
+	defer stream.Close()
+
+	return stream.Recv()
+}
+
+func TestInterceptorsAndDialOptionsApplyToGossipAndOperations(t *testing.T) {
+	var userAgents []string
+	var lock sync.Mutex
+	address := startFakeLeader(t, &fakeStreamsServer{
+		read: func(req *api.ReadReq, server api.Streams_ReadServer) error {
+			md, _ := metadata.FromIncomingContext(server.Context())
+			lock.Lock()
+			userAgents = append(userAgents, md.Get("user-agent")...)
+			lock.Unlock()
+			return server.Send(fakeReadEvent("orders", 0))
+		},
+	})
+
+	var unary, stream, dialed []string
+	config, err := esdb.ParseConnectionString("esdb+discover://" + address + "?tls=false")
+	require.NoError(t, err)
+	config.Dialer = func(ctx context.Context, address string) (net.Conn, error) {
+		lock.Lock()
+		dialed = append(dialed, address)
+		lock.Unlock()
+
+		var dialer net.Dialer
+		return dialer.DialContext(ctx, "tcp", address)
+	}
+	config.UnaryInterceptors = []grpc.UnaryClientInterceptor{
+		func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+			lock.Lock()
+			unary = append(unary, method)
+			lock.Unlock()
+			return invoker(ctx, method, req, reply, cc, opts...)
+		},
+	}
+	config.StreamInterceptors = []grpc.StreamClientInterceptor{
+		func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+			lock.Lock()
+			stream = append(stream, method)
+			lock.Unlock()
+			return streamer(ctx, desc, cc, method, opts...)
+		},
+	}
+	config.DialOptions = []grpc.DialOption{grpc.WithUserAgent("orders-service/1.0")}
+
+	client, err := esdb.NewClient(config)
+	require.NoError(t, err)
+	defer client.Close()
+
+	_, err = readFirstEvent(client)
+	require.NoError(t, err)
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	// The gossip read of the discovery, then the read on the selected node.
+	require.NotEmpty(t, unary)
+	assert.Equal(t, "/event_store.client.gossip.Gossip/Read", unary[0])
+	assert.Equal(t, []string{"/event_store.client.streams.Streams/Read"}, stream)
+	require.NotEmpty(t, dialed)
+	for _, target := range dialed {
+		assert.Equal(t, address, target)
+	}
+	require.Len(t, userAgents, 1)
+	assert.True(t, strings.HasPrefix(userAgents[0], "orders-service/1.0"), userAgents[0])
+}
+
+func TestHTTPProxyDialerTunnelsThroughConnect(t *testing.T) {
+	authorization := "Basic " + base64.StdEncoding.EncodeToString([]byte("proxy-user:secret"))
+	proxy := startConnectProxy(t, authorization)
+	address := startFakeServer(t, func(server *grpc.Server) {
+		api.RegisterStreamsServer(server, &fakeStreamsServer{
+			read: func(req *api.ReadReq, server api.Streams_ReadServer) error {
+				return server.Send(fakeReadEvent("orders", 0))
+			},
+		})
+	})
+
+	dialer, err := esdb.HTTPProxyDialer(&url.URL{Scheme: "http", Host: proxy.address, User: url.UserPassword("proxy-user", "secret")})
+	require.NoError(t, err)
+
+	config, err := esdb.ParseConnectionString("esdb://" + address + "?tls=false")
+	require.NoError(t, err)
+	config.Dialer = dialer
+
+	client, err := esdb.NewClient(config)
+	require.NoError(t, err)
+	defer client.Close()
+
+	event, err := readFirstEvent(client)
+	require.NoError(t, err)
+	assert.Equal(t, "orders", event.OriginalEvent().StreamID)
+	assert.Equal(t, []string{address}, proxy.requested())
+
+	_, err = esdb.HTTPProxyDialer(&url.URL{Scheme: "socks5", Host: proxy.address})
+	assert.Error(t, err)
+}
+
+func TestHTTPProxyDialerReportsRefusals(t *testing.T) {
+	proxy := startConnectProxy(t, "Basic expected")
+	dialer, err := esdb.HTTPProxyDialer(&url.URL{Scheme: "http", Host: proxy.address})
+	require.NoError(t, err)
+
+	_, err = dialer(context.Background(), "127.0.0.1:2113")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "407 Proxy Authentication Required")
+}
+
+func TestProxyDialerFromEnvironment(t *testing.T) {
+	setEnv(t, "HTTPS_PROXY", "")
+	setEnv(t, "https_proxy", "")
+	dialer, err := esdb.ProxyDialerFromEnvironment()
+	require.NoError(t, err)
+	assert.Nil(t, dialer)
+
+	proxy := startConnectProxy(t, "")
+	target, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer target.Close()
+	go func() {
+		for {
+			conn, err := target.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	_, port, _ := net.SplitHostPort(target.Addr().String())
+	tests := []struct {
+		noProxy string
+		proxied bool
+	}{
+		{"", true},
+		{"example.com,.internal", true},
+		{"127.0.0.1:1", true},
+		{"*", false},
+		{"127.0.0.1", false},
+		{"127.0.0.0/8", false},
+		{"localhost, 127.0.0.1:" + port, false},
+	}
+
+	setEnv(t, "HTTPS_PROXY", proxy.address)
+	for _, test := range tests {
+		setEnv(t, "NO_PROXY", test.noProxy)
+		dialer, err := esdb.ProxyDialerFromEnvironment()
+		require.NoError(t, err)
+		require.NotNil(t, dialer)
+
+		before := len(proxy.requested())
+		conn, err := dialer(context.Background(), target.Addr().String())
+		require.NoError(t, err, test.noProxy)
+		conn.Close()
+
+		assert.Equal(t, test.proxied, len(proxy.requested()) > before, test.noProxy)
+	}
+}
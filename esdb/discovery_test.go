@@ -0,0 +1,323 @@
+package esdb_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/EventStore/EventStore-Client-Go/esdb"
+	gossipApi "github.com/EventStore/EventStore-Client-Go/protos/gossip"
+	api "github.com/EventStore/EventStore-Client-Go/protos/streams"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// hangingGossip never answers, so discovery only ends when it is cancelled.
+func hangingGossip() *fakeGossipServer {
+	return &fakeGossipServer{
+		read: func(ctx context.Context) (*gossipApi.ClusterInfo, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		},
+	}
+}
+
+func TestConnectionAcquisitionHonoursContext(t *testing.T) {
+	address := startFakeGossipServer(t, hangingGossip())
+	client := CreateClient("esdb+discover://"+address+"?tls=false&gossipTimeout=30", t)
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	started := time.Now()
+	_, err := client.ReadStream(ctx, "orders", esdb.ReadStreamOptions{}, 1)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.Less(t, int64(time.Since(started)), int64(5*time.Second))
+}
+
+func TestCloseCancelsInFlightDiscovery(t *testing.T) {
+	address := startFakeGossipServer(t, hangingGossip())
+	client := CreateClient("esdb+discover://"+address+"?tls=false&gossipTimeout=30", t)
+
+	result := make(chan error, 1)
+	go func() {
+		_, err := client.ReadStream(context.Background(), "orders", esdb.ReadStreamOptions{}, 1)
+		result <- err
+	}()
+
+	// Let the read start the discovery.
+	time.Sleep(100 * time.Millisecond)
+	client.Close()
+
+	select {
+	case err := <-result:
+		assert.True(t, errors.Is(err, esdb.ErrClientClosed))
+	case <-time.After(5 * time.Second):
+		t.Fatalf("the read kept waiting for the discovery after Close")
+	}
+}
+
+func TestDiscoveryProbesSeedsConcurrently(t *testing.T) {
+	hangingProbeCancelled := make(chan struct{})
+	hanging := startFakeGossipServer(t, &fakeGossipServer{
+		read: func(ctx context.Context) (*gossipApi.ClusterInfo, error) {
+			<-ctx.Done()
+			close(hangingProbeCancelled)
+			return nil, ctx.Err()
+		},
+	})
+
+	leader := startFakeLeader(t, &fakeStreamsServer{
+		read: func(req *api.ReadReq, server api.Streams_ReadServer) error {
+			return server.Send(fakeReadEvent("orders", 0))
+		},
+	})
+
+	client := CreateClient("esdb://"+hanging+","+leader+"?tls=false&gossipTimeout=30", t)
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stream, err := client.ReadStream(ctx, "orders", esdb.ReadStreamOptions{}, 1)
+	require.NoError(t, err)
+	defer stream.Close()
+
+	event, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, "orders", event.OriginalEvent().StreamID)
+
+	select {
+	case <-hangingProbeCancelled:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("the losing probe wasn't cancelled")
+	}
+}
+
+func TestDiscoveryWaitsBetweenAttempts(t *testing.T) {
+	var attempts int32
+	failing := startFakeGossipServer(t, &fakeGossipServer{
+		read: func(ctx context.Context) (*gossipApi.ClusterInfo, error) {
+			atomic.AddInt32(&attempts, 1)
+			return &gossipApi.ClusterInfo{}, nil
+		},
+	})
+
+	// The first delay is between 100 and 200ms, the second between 200 and 400ms.
+	client := CreateClient("esdb+discover://"+failing+"?tls=false&maxDiscoverAttempts=3&discoveryInterval=200&maxDiscoveryInterval=400", t)
+	defer client.Close()
+
+	started := time.Now()
+	_, err := client.ReadStream(context.Background(), "orders", esdb.ReadStreamOptions{}, 1)
+	elapsed := time.Since(started)
+
+	require.Error(t, err)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&attempts))
+	assert.GreaterOrEqual(t, int64(elapsed), int64(300*time.Millisecond))
+	assert.Less(t, int64(elapsed), int64(5*time.Second))
+}
+
+func TestDiscoveryTranslatesAdvertisedAddresses(t *testing.T) {
+	gossip := &mutableGossip{}
+	leader := namedNode(t, "leader", gossip)
+	follower := namedNode(t, "follower", gossip)
+	gossip.set(
+		fakeMember("leader.internal:2113", gossipApi.MemberInfo_Leader),
+		fakeMember(follower, gossipApi.MemberInfo_Follower),
+	)
+
+	client := CreateClient("esdb+discover://"+follower+"?tls=false&addressMap=leader.internal:2113->"+leader, t)
+	defer client.Close()
+
+	assert.Equal(t, "leader", servedBy(t, client))
+}
+
+func TestLeaderRedirectTranslatesAdvertisedAddress(t *testing.T) {
+	recorder := &callRecorder{}
+	leader := recordingNode(t, "leader", &mutableGossip{}, recorder)
+
+	follower := startFakeServer(t, func(server *grpc.Server) {
+		api.RegisterStreamsServer(server, &fakeStreamsServer{
+			delete: func(ctx context.Context, req *api.DeleteReq) (*api.DeleteResp, error) {
+				grpc.SetTrailer(ctx, metadata.Pairs(
+					"exception", "not-leader",
+					"leader-endpoint-host", "leader.internal",
+					"leader-endpoint-port", "2113",
+				))
+				return nil, status.Error(codes.NotFound, "not leader")
+			},
+		})
+	})
+
+	config, err := esdb.ParseConnectionString("esdb://" + follower + "?tls=false")
+	require.NoError(t, err)
+
+	var translated []string
+	config.MapAddress = func(advertised esdb.EndPoint) esdb.EndPoint {
+		translated = append(translated, advertised.String())
+		endpoint, err := esdb.ParseEndPoint(leader)
+		require.NoError(t, err)
+		return *endpoint
+	}
+
+	client, err := esdb.NewClient(config)
+	require.NoError(t, err)
+	defer client.Close()
+
+	_, err = client.DeleteStream(context.Background(), "orders", esdb.DeleteStreamOptions{})
+	require.Error(t, err)
+
+	_, err = client.DeleteStream(context.Background(), "orders", esdb.DeleteStreamOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "leader", recorder.last().node)
+	assert.Equal(t, []string{"leader.internal:2113"}, translated)
+}
+
+func TestDiscoveryAndRedirectsWithIPv6Addresses(t *testing.T) {
+	var leader string
+	var deletes int32
+	gossip := &fakeGossipServer{
+		read: func(ctx context.Context) (*gossipApi.ClusterInfo, error) {
+			member := fakeMember(leader, gossipApi.MemberInfo_Leader)
+			// Advertised with brackets and not in canonical form.
+			member.HttpEndPoint.Address = "[0:0::1]"
+			return &gossipApi.ClusterInfo{Members: []*gossipApi.MemberInfo{member}}, nil
+		},
+	}
+	streams := &fakeStreamsServer{
+		read: func(req *api.ReadReq, server api.Streams_ReadServer) error {
+			return server.Send(fakeReadEvent("orders", 0))
+		},
+		delete: func(ctx context.Context, req *api.DeleteReq) (*api.DeleteResp, error) {
+			atomic.AddInt32(&deletes, 1)
+			return &api.DeleteResp{
+				PositionOption: &api.DeleteResp_Position_{Position: &api.DeleteResp_Position{}},
+			}, nil
+		},
+	}
+	leader = startFakeServerAt(t, "[::1]:0", func(server *grpc.Server) {
+		gossipApi.RegisterGossipServer(server, gossip)
+		api.RegisterStreamsServer(server, streams)
+	})
+
+	discovering := CreateClient("esdb+discover://"+leader+"?tls=false", t)
+	defer discovering.Close()
+
+	_, err := readFirstEvent(discovering)
+	require.NoError(t, err)
+
+	info, err := discovering.ReadGossip(context.Background())
+	require.NoError(t, err)
+	require.Len(t, info.Members, 1)
+	assert.Equal(t, leader, info.Members[0].HttpEndPoint.String())
+
+	_, port, err := net.SplitHostPort(leader)
+	require.NoError(t, err)
+
+	follower := startFakeServer(t, func(server *grpc.Server) {
+		api.RegisterStreamsServer(server, &fakeStreamsServer{
+			delete: func(ctx context.Context, req *api.DeleteReq) (*api.DeleteResp, error) {
+				grpc.SetTrailer(ctx, metadata.Pairs(
+					"exception", "not-leader",
+					"leader-endpoint-host", "[::1]",
+					"leader-endpoint-port", port,
+				))
+				return nil, status.Error(codes.NotFound, "not leader")
+			},
+		})
+	})
+
+	redirected := CreateClient("esdb://"+follower+"?tls=false", t)
+	defer redirected.Close()
+
+	_, err = redirected.DeleteStream(context.Background(), "orders", esdb.DeleteStreamOptions{})
+	require.Error(t, err)
+
+	_, err = redirected.DeleteStream(context.Background(), "orders", esdb.DeleteStreamOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&deletes))
+}
+
+// closeRecordingConn records when the client closes a connection.
+type closeRecordingConn struct {
+	net.Conn
+	closed *int32
+}
+
+func (conn closeRecordingConn) Close() error {
+	atomic.StoreInt32(conn.closed, 1)
+	return conn.Conn.Close()
+}
+
+func TestReplacedConnectionIsClosedOnceItsStreamsEnd(t *testing.T) {
+	recorder := &callRecorder{}
+	leader := recordingNode(t, "leader", &mutableGossip{}, recorder)
+
+	follower := startFakeServer(t, func(server *grpc.Server) {
+		api.RegisterStreamsServer(server, &fakeStreamsServer{
+			read: func(req *api.ReadReq, server api.Streams_ReadServer) error {
+				if err := server.Send(fakeSubscriptionConfirmation()); err != nil {
+					return err
+				}
+
+				<-server.Context().Done()
+				return nil
+			},
+			delete: func(ctx context.Context, req *api.DeleteReq) (*api.DeleteResp, error) {
+				grpc.SetTrailer(ctx, metadata.Pairs(
+					"exception", "not-leader",
+					"leader-endpoint-host", "127.0.0.1",
+					"leader-endpoint-port", strconv.Itoa(int(port(t, leader))),
+				))
+				return nil, status.Error(codes.NotFound, "not leader")
+			},
+		})
+	})
+
+	config, err := esdb.ParseConnectionString("esdb://" + follower + "?tls=false")
+	require.NoError(t, err)
+
+	var followerClosed int32
+	config.Dialer = func(ctx context.Context, address string) (net.Conn, error) {
+		var dialer net.Dialer
+		conn, err := dialer.DialContext(ctx, "tcp", address)
+		if err != nil || address != follower {
+			return conn, err
+		}
+
+		return closeRecordingConn{Conn: conn, closed: &followerClosed}, nil
+	}
+
+	client, err := esdb.NewClient(config)
+	require.NoError(t, err)
+	defer client.Close()
+
+	subscription, err := client.SubscribeToStream(context.Background(), "orders", esdb.SubscribeToStreamOptions{})
+	require.NoError(t, err)
+
+	// The redirect replaces the connection to the follower by one to the leader.
+	_, err = client.DeleteStream(context.Background(), "orders", esdb.DeleteStreamOptions{})
+	require.Error(t, err)
+	_, err = client.DeleteStream(context.Background(), "orders", esdb.DeleteStreamOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "leader", recorder.last().node)
+
+	// The subscription still uses the connection to the follower.
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, int32(0), atomic.LoadInt32(&followerClosed))
+
+	subscription.Close()
+	assert.Eventually(t, func() bool {
+		return atomic.LoadInt32(&followerClosed) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+}
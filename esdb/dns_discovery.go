@@ -0,0 +1,182 @@
+package esdb
+
+import (
+	"context"
+	"math/rand"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DnsDiscoverService is the service of the SRV records esdb+discover looks up, _esdb._tcp.{host}.
+const DnsDiscoverService = "esdb"
+
+// Resolver resolves the DNS name of esdb+discover connection strings. *net.Resolver implements it.
+type Resolver interface {
+	LookupSRV(ctx context.Context, service, proto, name string) (cname string, addrs []*net.SRV, err error)
+	LookupHost(ctx context.Context, host string) (addrs []string, err error)
+}
+
+// gossipCandidate is a node discovery reads gossip from.
+type gossipCandidate struct {
+	// host:port, as dialed and compared to the addresses advertised in gossip.
+	address string
+	// The resolved IP the connection is pinned to, "" to let the dialer resolve address.
+	ip string
+}
+
+func (candidate gossipCandidate) String() string {
+	if candidate.ip == "" {
+		return candidate.address
+	}
+
+	return candidate.address + " (" + candidate.ip + ")"
+}
+
+// configure returns the configuration to connect to the candidate with, dialing its IP when it
+// has one. The address is still used as target, so TLS verifies the certificate of the host.
+func (candidate gossipCandidate) configure(conf Configuration) Configuration {
+	if candidate.ip == "" {
+		return conf
+	}
+
+	_, port, _ := net.SplitHostPort(candidate.address)
+	target := net.JoinHostPort(candidate.ip, port)
+	conf.Dialer = func(ctx context.Context, _ string) (net.Conn, error) {
+		var dialer net.Dialer
+		return dialer.DialContext(ctx, "tcp", target)
+	}
+
+	return conf
+}
+
+// candidateGroup is a group of candidates probed concurrently.
+type candidateGroup struct {
+	candidates []gossipCandidate
+	// When set, the first candidate in order whose probe succeeds wins rather than the first one to
+	// answer, so that the order of SRV records by weight is honoured.
+	ordered bool
+}
+
+// gossipCandidates returns the candidates of a discovery, in groups probed one after the other.
+// Gossip seeds make a single group. The DNS name of esdb+discover is resolved again on every call:
+// its _esdb._tcp SRV records give one ordered group per priority, ordered by weight, and every A or
+// AAAA answer of a host is a candidate of its own.
+func gossipCandidates(ctx context.Context, conf *Configuration) []candidateGroup {
+	if !conf.DnsDiscover {
+		seeds := make([]gossipCandidate, 0, len(conf.GossipSeeds))
+		for _, seed := range conf.GossipSeeds {
+			seeds = append(seeds, gossipCandidate{address: seed.String()})
+		}
+
+		return []candidateGroup{{candidates: seeds}}
+	}
+
+	endpoint, err := ParseEndPoint(conf.Address)
+	if err != nil || net.ParseIP(endpoint.Host) != nil {
+		return []candidateGroup{{candidates: []gossipCandidate{{address: conf.Address}}}}
+	}
+
+	resolver := conf.resolver()
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(conf.GossipTimeout)*time.Second)
+	defer cancel()
+
+	_, records, err := resolver.LookupSRV(ctx, DnsDiscoverService, "tcp", endpoint.Host)
+	if err != nil || len(records) == 0 {
+		return []candidateGroup{{candidates: resolveHost(ctx, conf, endpoint.Host, strconv.Itoa(int(endpoint.Port)))}}
+	}
+
+	var groups []candidateGroup
+	for _, group := range orderSRV(records) {
+		var candidates []gossipCandidate
+		for _, record := range group {
+			target := strings.TrimSuffix(record.Target, ".")
+			candidates = append(candidates, resolveHost(ctx, conf, target, strconv.Itoa(int(record.Port)))...)
+		}
+
+		groups = append(groups, candidateGroup{candidates: candidates, ordered: true})
+	}
+
+	conf.logf("[info] resolved %d SRV records for %s", len(records), endpoint.Host)
+
+	return groups
+}
+
+// resolveHost returns a candidate per A or AAAA answer of host. The host is left to the dialer when
+// it can't be resolved, or when a Dialer is configured, as it may resolve names itself like a proxy.
+func resolveHost(ctx context.Context, conf *Configuration, host string, port string) []gossipCandidate {
+	address := net.JoinHostPort(host, port)
+	if conf.Dialer != nil {
+		return []gossipCandidate{{address: address}}
+	}
+
+	ips, err := conf.resolver().LookupHost(ctx, host)
+	if err != nil || len(ips) == 0 {
+		return []gossipCandidate{{address: address}}
+	}
+
+	candidates := make([]gossipCandidate, 0, len(ips))
+	for _, ip := range ips {
+		candidates = append(candidates, gossipCandidate{address: address, ip: ip})
+	}
+
+	return candidates
+}
+
+func (conf *Configuration) resolver() Resolver {
+	if conf.Resolver != nil {
+		return conf.Resolver
+	}
+
+	return net.DefaultResolver
+}
+
+// orderSRV groups SRV records by priority, lowest first, and orders each group by weight as
+// described by RFC 2782.
+func orderSRV(records []*net.SRV) [][]*net.SRV {
+	sorted := append([]*net.SRV(nil), records...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Priority < sorted[j].Priority
+	})
+
+	var groups [][]*net.SRV
+	for start := 0; start < len(sorted); {
+		end := start + 1
+		for end < len(sorted) && sorted[end].Priority == sorted[start].Priority {
+			end++
+		}
+
+		group := sorted[start:end]
+		shuffleByWeight(group)
+		groups = append(groups, group)
+		start = end
+	}
+
+	return groups
+}
+
+// shuffleByWeight orders records by repeatedly picking one with a probability proportional to its
+// weight. Records of weight 0 go last.
+func shuffleByWeight(records []*net.SRV) {
+	sum := 0
+	for _, record := range records {
+		sum += int(record.Weight)
+	}
+
+	for sum > 0 && len(records) > 1 {
+		pick := rand.Intn(sum)
+		total := 0
+		for i := range records {
+			total += int(records[i].Weight)
+			if total > pick {
+				records[0], records[i] = records[i], records[0]
+				break
+			}
+		}
+
+		sum -= int(records[0].Weight)
+		records = records[1:]
+	}
+}
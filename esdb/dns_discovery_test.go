@@ -0,0 +1,212 @@
+package esdb_test
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/EventStore/EventStore-Client-Go/esdb"
+	gossipApi "github.com/EventStore/EventStore-Client-Go/protos/gossip"
+	api "github.com/EventStore/EventStore-Client-Go/protos/streams"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+// fakeResolver answers DNS lookups from static records.
+type fakeResolver struct {
+	lock       sync.Mutex
+	srv        map[string][]*net.SRV
+	hosts      map[string][]string
+	srvLookups []string
+}
+
+func (resolver *fakeResolver) LookupSRV(ctx context.Context, service, proto, name string) (string, []*net.SRV, error) {
+	resolver.lock.Lock()
+	defer resolver.lock.Unlock()
+
+	query := "_" + service + "._" + proto + "." + name
+	resolver.srvLookups = append(resolver.srvLookups, query)
+	records, exists := resolver.srv[query]
+	if !exists {
+		return "", nil, &net.DNSError{Err: "no such host", Name: query, IsNotFound: true}
+	}
+
+	return query, records, nil
+}
+
+func (resolver *fakeResolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	resolver.lock.Lock()
+	defer resolver.lock.Unlock()
+
+	addresses, exists := resolver.hosts[host]
+	if !exists {
+		return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+
+	return addresses, nil
+}
+
+func (resolver *fakeResolver) lookups() []string {
+	resolver.lock.Lock()
+	defer resolver.lock.Unlock()
+	return append([]string(nil), resolver.srvLookups...)
+}
+
+func port(t *testing.T, address string) uint16 {
+	_, value, err := net.SplitHostPort(address)
+	require.NoError(t, err)
+
+	port, err := strconv.Atoi(value)
+	require.NoError(t, err)
+
+	return uint16(port)
+}
+
+// closedAddress returns a local address nothing listens on.
+func closedAddress(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	listener.Close()
+
+	return listener.Addr().String()
+}
+
+func createDnsDiscoverClient(t *testing.T, host string, resolver esdb.Resolver) *esdb.Client {
+	config, err := esdb.ParseConnectionString("esdb+discover://" + host + "?tls=false&maxDiscoverAttempts=1")
+	require.NoError(t, err)
+	config.Resolver = resolver
+
+	client, err := esdb.NewClient(config)
+	require.NoError(t, err)
+	t.Cleanup(func() { client.Close() })
+
+	return client
+}
+
+func TestDnsDiscoveryFollowsSrvPriorities(t *testing.T) {
+	leader := startFakeLeader(t, &fakeStreamsServer{
+		read: func(req *api.ReadReq, server api.Streams_ReadServer) error {
+			return server.Send(fakeReadEvent("orders", 0))
+		},
+	})
+
+	var backupProbes int32
+	backup := startFakeGossipServer(t, &fakeGossipServer{
+		read: func(ctx context.Context) (*gossipApi.ClusterInfo, error) {
+			atomic.AddInt32(&backupProbes, 1)
+			return &gossipApi.ClusterInfo{Members: []*gossipApi.MemberInfo{fakeMember(leader, gossipApi.MemberInfo_Leader)}}, nil
+		},
+	})
+
+	resolver := &fakeResolver{
+		srv: map[string][]*net.SRV{
+			"_esdb._tcp.cluster.test": {
+				{Target: "backup.cluster.test.", Port: port(t, backup), Priority: 30, Weight: 10},
+				{Target: "leader.cluster.test.", Port: port(t, leader), Priority: 20, Weight: 10},
+				{Target: "down.cluster.test.", Port: port(t, closedAddress(t)), Priority: 10, Weight: 10},
+			},
+		},
+		hosts: map[string][]string{
+			"backup.cluster.test": {"127.0.0.1"},
+			"leader.cluster.test": {"127.0.0.1"},
+			"down.cluster.test":   {"127.0.0.1"},
+		},
+	}
+
+	client := createDnsDiscoverClient(t, "cluster.test", resolver)
+
+	// The node of the first priority is down, the one of the second priority answers.
+	event, err := readFirstEvent(client)
+	require.NoError(t, err)
+	assert.Equal(t, "orders", event.OriginalEvent().StreamID)
+	assert.Equal(t, int32(0), atomic.LoadInt32(&backupProbes))
+	assert.Equal(t, []string{"_esdb._tcp.cluster.test"}, resolver.lookups())
+
+	// The failed delete triggers a new discovery, which resolves the records again.
+	_, err = client.DeleteStream(context.Background(), "orders", esdb.DeleteStreamOptions{})
+	require.Error(t, err)
+
+	_, err = readFirstEvent(client)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"_esdb._tcp.cluster.test", "_esdb._tcp.cluster.test"}, resolver.lookups())
+}
+
+func TestDnsDiscoveryProbesEveryAddressOfTheHost(t *testing.T) {
+	leader := startFakeLeader(t, &fakeStreamsServer{
+		read: func(req *api.ReadReq, server api.Streams_ReadServer) error {
+			return server.Send(fakeReadEvent("orders", 0))
+		},
+	})
+
+	// Without SRV records, the host itself is resolved. Nothing listens on 127.0.0.2.
+	resolver := &fakeResolver{
+		hosts: map[string][]string{"cluster.test": {"127.0.0.2", "127.0.0.1"}},
+	}
+
+	client := createDnsDiscoverClient(t, net.JoinHostPort("cluster.test", strconv.Itoa(int(port(t, leader)))), resolver)
+
+	event, err := readFirstEvent(client)
+	require.NoError(t, err)
+	assert.Equal(t, "orders", event.OriginalEvent().StreamID)
+	assert.Equal(t, []string{"_esdb._tcp.cluster.test"}, resolver.lookups())
+}
+
+// namedLeader is a leader serving reads of a stream named after it, answering gossip after delay.
+func namedLeader(t *testing.T, name string, delay time.Duration) string {
+	var address string
+	gossip := &fakeGossipServer{
+		read: func(ctx context.Context) (*gossipApi.ClusterInfo, error) {
+			time.Sleep(delay)
+			return &gossipApi.ClusterInfo{Members: []*gossipApi.MemberInfo{fakeMember(address, gossipApi.MemberInfo_Leader)}}, nil
+		},
+	}
+
+	address = startFakeServer(t, func(server *grpc.Server) {
+		gossipApi.RegisterGossipServer(server, gossip)
+		api.RegisterStreamsServer(server, &fakeStreamsServer{
+			read: func(req *api.ReadReq, server api.Streams_ReadServer) error {
+				return server.Send(fakeReadEvent(name, 0))
+			},
+		})
+	})
+
+	return address
+}
+
+func TestDnsDiscoveryFollowsSrvWeights(t *testing.T) {
+	// The slow node answers gossip after the fast one, so it only wins when its weight says so.
+	slow := namedLeader(t, "slow", 200*time.Millisecond)
+	fast := namedLeader(t, "fast", 0)
+
+	for _, weights := range []struct {
+		slow, fast uint16
+		expected   string
+	}{
+		{slow: 100, fast: 0, expected: "slow"},
+		{slow: 0, fast: 100, expected: "fast"},
+	} {
+		resolver := &fakeResolver{
+			srv: map[string][]*net.SRV{
+				"_esdb._tcp.cluster.test": {
+					{Target: "fast.cluster.test.", Port: port(t, fast), Priority: 10, Weight: weights.fast},
+					{Target: "slow.cluster.test.", Port: port(t, slow), Priority: 10, Weight: weights.slow},
+				},
+			},
+			hosts: map[string][]string{
+				"fast.cluster.test": {"127.0.0.1"},
+				"slow.cluster.test": {"127.0.0.1"},
+			},
+		}
+
+		client := createDnsDiscoverClient(t, "cluster.test", resolver)
+
+		event, err := readFirstEvent(client)
+		require.NoError(t, err)
+		assert.Equal(t, weights.expected, event.OriginalEvent().StreamID)
+	}
+}
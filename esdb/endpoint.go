@@ -1,73 +1,119 @@
 package esdb
 
 import (
+	"context"
 	"fmt"
 	"net"
 	"net/url"
 	"strconv"
 	"strings"
+
+	"github.com/gofrs/uuid"
 )
 
+// EndPoint is the address of a node. IPv6 hosts are stored without brackets.
 type EndPoint struct {
 	Host string
 	Port uint16
 }
 
+// String returns host:port, with IPv6 hosts enclosed in brackets as in [::1]:2113.
 func (e *EndPoint) String() string {
-	return fmt.Sprintf("%s:%d", e.Host, e.Port)
+	return net.JoinHostPort(e.Host, strconv.Itoa(int(e.Port)))
 }
 
+// ParseEndPoint parses {host}:{port} or {host}, the port defaulting to 2113. IPv6 addresses must be
+// enclosed in brackets, as in [::1]:2113 or [::1].
 func ParseEndPoint(s string) (*EndPoint, error) {
 	if strings.TrimSpace(s) == "" {
 		return nil, fmt.Errorf("an empty host is specified")
 	}
 
-	endpoint := &EndPoint{}
-	if strings.Contains(s, ":") {
-		tokens := strings.Split(s, ":")
-		if len(tokens) != 2 {
-			return nil, fmt.Errorf("too many colons specified in host, expecting {host}:{port}")
+	host, port := s, ""
+	if strings.HasPrefix(s, "[") {
+		closing := strings.Index(s, "]")
+		if closing == -1 {
+			return nil, fmt.Errorf("missing closing bracket in IPv6 address [%s]", s)
 		}
 
-		ip := net.ParseIP(tokens[0])
-		if ip == nil {
-			_, err := url.Parse(tokens[0])
+		host = s[1:closing]
+		if ip := net.ParseIP(host); ip == nil || !strings.Contains(host, ":") {
+			return nil, fmt.Errorf("invalid IPv6 address [%s]", host)
+		}
 
-			if err != nil {
-				return nil, fmt.Errorf("invalid hostname [%s]", tokens[0])
+		rest := s[closing+1:]
+		if rest != "" {
+			if !strings.HasPrefix(rest, ":") {
+				return nil, fmt.Errorf("unexpected characters after IPv6 address, expecting [{host}]:{port} got [%s]", s)
 			}
+
+			port = rest[1:]
 		}
+	} else if strings.Contains(s, ":") {
+		tokens := strings.Split(s, ":")
+		if len(tokens) != 2 {
+			if net.ParseIP(s) != nil {
+				return nil, fmt.Errorf("IPv6 addresses must be enclosed in brackets, expecting [{host}]:{port} got [%s]", s)
+			}
 
-		port, err := strconv.Atoi(tokens[1])
-		if err != nil || !(port >= 1 && port <= 65_535) {
-			return nil, fmt.Errorf("invalid port specified, expecting an integer value [%s]", tokens[1])
+			return nil, fmt.Errorf("too many colons specified in host, expecting {host}:{port}")
 		}
 
-		endpoint.Host = tokens[0]
-		endpoint.Port = uint16(port)
-	} else {
-		ip := net.ParseIP(s)
-		if ip == nil {
-			_, err := url.Parse(s)
+		host, port = tokens[0], tokens[1]
+	}
 
-			if err != nil {
-				return nil, fmt.Errorf("invalid hostname [%s]", s)
-			}
+	if net.ParseIP(host) == nil {
+		if _, err := url.Parse(host); err != nil {
+			return nil, fmt.Errorf("invalid hostname [%s]", host)
+		}
+	}
+
+	endpoint := &EndPoint{Host: normalizeHost(host), Port: 2_113}
+	if port != "" || strings.HasSuffix(s, ":") {
+		value, err := strconv.Atoi(port)
+		if err != nil || !(value >= 1 && value <= 65_535) {
+			return nil, fmt.Errorf("invalid port specified, expecting an integer value [%s]", port)
 		}
 
-		endpoint.Host = s
-		endpoint.Port = 2_113
+		endpoint.Port = uint16(value)
 	}
 
 	return endpoint, nil
 }
 
+// normalizeHost returns a host as stored in EndPoint: IPv6 addresses, which the server may
+// advertise with brackets, are unbracketed and canonical so they compare equal.
+func normalizeHost(host string) string {
+	unbracketed := strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+	if ip := net.ParseIP(unbracketed); ip != nil && strings.Contains(unbracketed, ":") {
+		return ip.String()
+	}
+
+	return host
+}
+
 func NewGrpcClient(config Configuration) *grpcClient {
+	if config.ConnectionName == "" {
+		config.ConnectionName = "esdb-" + uuid.Must(uuid.NewV4()).String()
+	}
+
 	channel := make(chan msg)
+	calls := newConnectionCalls()
 
-	go connectionStateMachine(config, channel)
+	go connectionStateMachine(calls.withInterceptors(config), channel, calls)
 
-	return &grpcClient{
-		channel: channel,
+	client := &grpcClient{
+		channel:                channel,
+		config:                 config,
+		certificateConnections: newCertificateConnections(),
+		operations:             newOperationTracker(),
 	}
+
+	if config.GossipWatchInterval > 0 && (config.DnsDiscover || len(config.GossipSeeds) > 0) {
+		ctx, cancel := context.WithCancel(context.Background())
+		client.stopWatching = cancel
+		go client.watchConnectedNode(ctx, config)
+	}
+
+	return client
 }
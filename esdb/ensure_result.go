@@ -0,0 +1,22 @@
+package esdb
+
+// EnsurePersistentSubscriptionResult ...
+type EnsurePersistentSubscriptionResult struct {
+	// True if the group didn't exist and has been created.
+	Created bool
+	// True if the group existed with other settings and has been updated. The group was left as is
+	// when neither Created nor Updated are set.
+	Updated bool
+}
+
+// Action returns what ensuring the group did.
+func (result *EnsurePersistentSubscriptionResult) Action() ManifestAction {
+	switch {
+	case result.Created:
+		return ManifestAction_Create
+	case result.Updated:
+		return ManifestAction_Update
+	default:
+		return ManifestAction_Unchanged
+	}
+}
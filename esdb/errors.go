@@ -1,8 +1,13 @@
 package esdb
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
 )
 
 // ErrWrongExpectedStreamRevision ...
@@ -14,6 +19,29 @@ var ErrPermissionDenied = errors.New("PermissionDenied")
 // ErrUnAuthenticated
 var ErrUnauthenticated = errors.New("Unauthenticated")
 
+// ErrAlreadyExists is returned when the server refuses to create a resource, like a persistent
+// subscription group, because it already exists.
+var ErrAlreadyExists = errors.New("AlreadyExists")
+
+// ErrSubscriptionIdleTimeout is the cause of a SubscriptionDropped when a subscription didn't
+// receive any message within its idle timeout.
+var ErrSubscriptionIdleTimeout = errors.New("SubscriptionIdleTimeout")
+
+// ErrSubscriptionClosed is the cause of a SubscriptionDropped when the subscription was closed.
+var ErrSubscriptionClosed = errors.New("SubscriptionClosed")
+
+// ErrServerShutdown is the cause of a SubscriptionDropped when the server ended the subscription.
+var ErrServerShutdown = errors.New("ServerShutdown")
+
+// ErrClientClosed is returned by operations started after the client was closed or started
+// shutting down, or that were waiting for a connection when it was closed. It is also the error of
+// the read streams and the cause of the subscriptions ended by Client.Shutdown.
+var ErrClientClosed = errors.New("ClientClosed")
+
+// ErrNodeNotReady is returned by Client.Ping when the node the client is connected to is in a
+// state operations can't use, like shutting down or catching up.
+var ErrNodeNotReady = errors.New("NodeNotReady")
+
 // ErrStreamNotFound is returned when a read requests gets a stream not found response
 // from the EventStore.
 // Example usage:
@@ -25,6 +53,26 @@ var ErrUnauthenticated = errors.New("Unauthenticated")
 // ```
 var ErrStreamNotFound = errors.New("Failed to perform read because the stream was not found")
 
+// DeadlineExceededError is returned when an operation didn't complete within its deadline, see
+// Configuration.DefaultDeadline. It matches context.DeadlineExceeded with errors.Is.
+type DeadlineExceededError struct {
+	Operation string
+	Deadline  time.Duration
+	Err       error
+}
+
+func (e *DeadlineExceededError) Error() string {
+	return fmt.Sprintf("%s didn't complete within its %v deadline: %v", e.Operation, e.Deadline, e.Err)
+}
+
+func (e *DeadlineExceededError) Unwrap() error {
+	return e.Err
+}
+
+func (e *DeadlineExceededError) Is(target error) bool {
+	return target == context.DeadlineExceeded
+}
+
 type StreamDeletedError struct {
 	StreamName string
 }
@@ -32,3 +80,82 @@ type StreamDeletedError struct {
 func (e *StreamDeletedError) Error() string {
 	return fmt.Sprintf("stream '%s' is deleted", e.StreamName)
 }
+
+// PersistentSubscriptionDeletedError is the cause of a SubscriptionDropped when the persistent
+// subscription group was deleted while connected, or doesn't exist.
+type PersistentSubscriptionDeletedError struct {
+	StreamName string
+	GroupName  string
+}
+
+func (e *PersistentSubscriptionDeletedError) Error() string {
+	return fmt.Sprintf("persistent subscription group '%s' on stream '%s' is deleted", e.GroupName, e.StreamName)
+}
+
+// PartitionFailedError is returned by ConsumePartitioned when an event exhausted its local retries
+// and no FailureAction was set. The event is left unacknowledged, so the server redelivers it.
+type PartitionFailedError struct {
+	PartitionKey string
+	EventID      uuid.UUID
+	Err          error
+}
+
+func (e *PartitionFailedError) Error() string {
+	return fmt.Sprintf("event %v of partition '%s' failed: %v", e.EventID, e.PartitionKey, e.Err)
+}
+
+func (e *PartitionFailedError) Unwrap() error {
+	return e.Err
+}
+
+// FieldError describes a single invalid field found by a Validate method.
+type FieldError struct {
+	Field  string
+	Reason string
+}
+
+func (e FieldError) Error() string {
+	return fmt.Sprintf("%s %s", e.Field, e.Reason)
+}
+
+// ValidationError is returned by Validate methods and lists every invalid field. Operations that
+// accept validated options return it before reaching the server.
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	reasons := make([]string, len(e.Fields))
+	for i, field := range e.Fields {
+		reasons[i] = field.Error()
+	}
+
+	return fmt.Sprintf("invalid options: %s", strings.Join(reasons, "; "))
+}
+
+func (e *ValidationError) add(field string, format string, args ...interface{}) {
+	e.Fields = append(e.Fields, FieldError{
+		Field:  field,
+		Reason: fmt.Sprintf(format, args...),
+	})
+}
+
+// merge appends the fields of a nested validation error, prefixing them with the given field name.
+func (e *ValidationError) merge(prefix string, err error) {
+	if nested, ok := err.(*ValidationError); ok {
+		for _, field := range nested.Fields {
+			e.Fields = append(e.Fields, FieldError{
+				Field:  prefix + "." + field.Field,
+				Reason: field.Reason,
+			})
+		}
+	}
+}
+
+func (e *ValidationError) orNil() error {
+	if len(e.Fields) == 0 {
+		return nil
+	}
+
+	return e
+}
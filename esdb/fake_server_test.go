@@ -0,0 +1,154 @@
+package esdb_test
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/EventStore/EventStore-Client-Go/esdb"
+	gossipApi "github.com/EventStore/EventStore-Client-Go/protos/gossip"
+	"github.com/EventStore/EventStore-Client-Go/protos/shared"
+	api "github.com/EventStore/EventStore-Client-Go/protos/streams"
+	"github.com/gofrs/uuid"
+	"google.golang.org/grpc"
+)
+
+// fakeStreamsServer is an in-process streams service used to test client behaviours that don't
+// need a real EventStoreDB, like stalled connections.
+type fakeStreamsServer struct {
+	api.UnimplementedStreamsServer
+	read   func(req *api.ReadReq, server api.Streams_ReadServer) error
+	delete func(ctx context.Context, req *api.DeleteReq) (*api.DeleteResp, error)
+	append func(server api.Streams_AppendServer) error
+}
+
+func (server *fakeStreamsServer) Read(req *api.ReadReq, stream api.Streams_ReadServer) error {
+	return server.read(req, stream)
+}
+
+func (server *fakeStreamsServer) Delete(ctx context.Context, req *api.DeleteReq) (*api.DeleteResp, error) {
+	if server.delete == nil {
+		return server.UnimplementedStreamsServer.Delete(ctx, req)
+	}
+
+	return server.delete(ctx, req)
+}
+
+func (server *fakeStreamsServer) Append(stream api.Streams_AppendServer) error {
+	if server.append == nil {
+		return server.UnimplementedStreamsServer.Append(stream)
+	}
+
+	return server.append(stream)
+}
+
+// fakeGossipServer is an in-process gossip service used to test discovery.
+type fakeGossipServer struct {
+	gossipApi.UnimplementedGossipServer
+	read func(ctx context.Context) (*gossipApi.ClusterInfo, error)
+}
+
+func (server *fakeGossipServer) Read(ctx context.Context, _ *shared.Empty) (*gossipApi.ClusterInfo, error) {
+	return server.read(ctx)
+}
+
+func startFakeGossipServer(t *testing.T, gossip *fakeGossipServer) string {
+	return startFakeServer(t, func(server *grpc.Server) {
+		gossipApi.RegisterGossipServer(server, gossip)
+	})
+}
+
+// fakeMember describes a live cluster member reachable at address, as seen in gossip.
+func fakeMember(address string, state gossipApi.MemberInfo_VNodeState) *gossipApi.MemberInfo {
+	host, port, _ := net.SplitHostPort(address)
+	portNumber, _ := strconv.Atoi(port)
+
+	return &gossipApi.MemberInfo{
+		InstanceId:   &shared.UUID{Value: &shared.UUID_String_{String_: uuid.Must(uuid.NewV4()).String()}},
+		State:        state,
+		IsAlive:      true,
+		HttpEndPoint: &gossipApi.EndPoint{Address: host, Port: uint32(portNumber)},
+	}
+}
+
+// startFakeLeader serves both gossip, describing the node as the only member of the cluster, and
+// the given streams service.
+func startFakeLeader(t *testing.T, streams api.StreamsServer) string {
+	var address string
+	gossip := &fakeGossipServer{
+		read: func(ctx context.Context) (*gossipApi.ClusterInfo, error) {
+			return &gossipApi.ClusterInfo{
+				Members: []*gossipApi.MemberInfo{fakeMember(address, gossipApi.MemberInfo_Leader)},
+			}, nil
+		},
+	}
+
+	address = startFakeServer(t, func(server *grpc.Server) {
+		gossipApi.RegisterGossipServer(server, gossip)
+		api.RegisterStreamsServer(server, streams)
+	})
+
+	return address
+}
+
+// startFakeServer serves the given services on a random local port and returns its address.
+func startFakeServer(t *testing.T, register func(server *grpc.Server), options ...grpc.ServerOption) string {
+	return startFakeServerAt(t, "127.0.0.1:0", register, options...)
+}
+
+// startFakeServerAt serves the given services on the given address and returns the address it
+// listens on.
+func startFakeServerAt(t *testing.T, address string, register func(server *grpc.Server), options ...grpc.ServerOption) string {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+
+	server := grpc.NewServer(options...)
+	register(server)
+
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	return listener.Addr().String()
+}
+
+func createFakeServerClient(t *testing.T, streams api.StreamsServer) *esdb.Client {
+	address := startFakeServer(t, func(server *grpc.Server) {
+		api.RegisterStreamsServer(server, streams)
+	})
+
+	client := CreateClient("esdb://"+address+"?tls=false", t)
+	t.Cleanup(func() { client.Close() })
+
+	return client
+}
+
+func fakeReadEvent(streamID string, revision uint64) *api.ReadResp {
+	return &api.ReadResp{
+		Content: &api.ReadResp_Event{
+			Event: &api.ReadResp_ReadEvent{
+				Event: &api.ReadResp_ReadEvent_RecordedEvent{
+					Id:               &shared.UUID{Value: &shared.UUID_String_{String_: uuid.Must(uuid.NewV4()).String()}},
+					StreamIdentifier: &shared.StreamIdentifier{StreamName: []byte(streamID)},
+					StreamRevision:   revision,
+					Metadata: map[string]string{
+						"type":         "TestEvent",
+						"content-type": "application/octet-stream",
+						"created":      strconv.FormatInt(time.Now().UnixNano()/100, 10),
+					},
+				},
+			},
+		},
+	}
+}
+
+func fakeSubscriptionConfirmation() *api.ReadResp {
+	return &api.ReadResp{
+		Content: &api.ReadResp_Confirmation{
+			Confirmation: &api.ReadResp_SubscriptionConfirmation{SubscriptionId: "fake"},
+		},
+	}
+}
@@ -0,0 +1,398 @@
+package esdb
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	gossipApi "github.com/EventStore/EventStore-Client-Go/protos/gossip"
+	"github.com/EventStore/EventStore-Client-Go/protos/shared"
+	"github.com/gofrs/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// VNodeState is the state of a cluster member, as reported by gossip.
+type VNodeState int32
+
+const (
+	VNodeState_Initializing       VNodeState = 0
+	VNodeState_DiscoverLeader     VNodeState = 1
+	VNodeState_Unknown            VNodeState = 2
+	VNodeState_PreReplica         VNodeState = 3
+	VNodeState_CatchingUp         VNodeState = 4
+	VNodeState_Clone              VNodeState = 5
+	VNodeState_Follower           VNodeState = 6
+	VNodeState_PreLeader          VNodeState = 7
+	VNodeState_Leader             VNodeState = 8
+	VNodeState_Manager            VNodeState = 9
+	VNodeState_ShuttingDown       VNodeState = 10
+	VNodeState_Shutdown           VNodeState = 11
+	VNodeState_ReadOnlyLeaderless VNodeState = 12
+	VNodeState_PreReadOnlyReplica VNodeState = 13
+	VNodeState_ReadOnlyReplica    VNodeState = 14
+	VNodeState_ResigningLeader    VNodeState = 15
+)
+
+func (state VNodeState) String() string {
+	return gossipApi.MemberInfo_VNodeState(state).String()
+}
+
+// MemberInfo describes a cluster member.
+type MemberInfo struct {
+	InstanceID uuid.UUID
+	// When the member last updated its gossip.
+	TimeStamp    time.Time
+	State        VNodeState
+	IsAlive      bool
+	HttpEndPoint EndPoint
+}
+
+// ClusterInfo is the view of the cluster gossiped by the node the client is connected to.
+type ClusterInfo struct {
+	Members []MemberInfo
+}
+
+// Leader returns the alive leader of the cluster, if any.
+func (info *ClusterInfo) Leader() *MemberInfo {
+	for i := range info.Members {
+		if info.Members[i].IsAlive && info.Members[i].State == VNodeState_Leader {
+			return &info.Members[i]
+		}
+	}
+
+	return nil
+}
+
+// ReadGossip returns the cluster members as seen by the node the client is connected to.
+func (client *Client) ReadGossip(ctx context.Context) (_ *ClusterInfo, err error) {
+	ctx, span := client.grpcClient.startSpan(ctx, "ReadGossip", "")
+	defer func() { span.end(err) }()
+
+	end, err := client.grpcClient.operations.begin()
+	if err != nil {
+		return nil, err
+	}
+	defer end()
+
+	ctx, cancel := client.grpcClient.withDeadline(ctx, "ReadGossip", 0)
+	defer cancel()
+	defer func() { err = deadlineError(ctx, err) }()
+
+	handle, err := client.grpcClient.getConnectionHandle(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("can't get a connection handle: %w", err)
+	}
+
+	return client.grpcClient.readGossip(ctx, handle)
+}
+
+func (client *grpcClient) readGossip(ctx context.Context, handle connectionHandle) (*ClusterInfo, error) {
+	var headers, trailers metadata.MD
+	gossipClient := gossipApi.NewGossipClient(handle.Connection())
+	info, err := gossipClient.Read(ctx, &shared.Empty{}, grpc.Header(&headers), grpc.Trailer(&trailers))
+	if err != nil {
+		err = client.handleError(handle, headers, trailers, err)
+		return nil, fmt.Errorf("failed to read gossip. Reason: %w", err)
+	}
+
+	return clusterInfoFromProto(info), nil
+}
+
+func clusterInfoFromProto(info *gossipApi.ClusterInfo) *ClusterInfo {
+	members := make([]MemberInfo, 0, len(info.GetMembers()))
+	for _, member := range info.GetMembers() {
+		members = append(members, memberInfoFromProto(member))
+	}
+
+	return &ClusterInfo{Members: members}
+}
+
+func memberInfoFromProto(member *gossipApi.MemberInfo) MemberInfo {
+	return MemberInfo{
+		InstanceID: uuidFromProto(member.GetInstanceId()),
+		// The timestamp is the number of .NET "ticks" (100ns increments) since the UNIX epoch.
+		TimeStamp: time.Unix(0, member.GetTimeStamp()*100).UTC(),
+		State:     VNodeState(member.GetState()),
+		IsAlive:   member.GetIsAlive(),
+		HttpEndPoint: EndPoint{
+			Host: normalizeHost(member.GetHttpEndPoint().GetAddress()),
+			Port: uint16(member.GetHttpEndPoint().GetPort()),
+		},
+	}
+}
+
+func uuidFromProto(id *shared.UUID) uuid.UUID {
+	if structured := id.GetStructured(); structured != nil {
+		var bytes [16]byte
+		binary.BigEndian.PutUint64(bytes[:8], uint64(structured.MostSignificantBits))
+		binary.BigEndian.PutUint64(bytes[8:], uint64(structured.LeastSignificantBits))
+		return uuid.UUID(bytes)
+	}
+
+	return uuid.FromStringOrNil(id.GetString_())
+}
+
+type TopologyEventType int32
+
+const (
+	// A member appeared in gossip.
+	TopologyEvent_MemberJoined TopologyEventType = 0
+	// A member disappeared from gossip.
+	TopologyEvent_MemberLeft TopologyEventType = 1
+	// A member changed state, see Previous for the former state.
+	TopologyEvent_MemberStateChanged TopologyEventType = 2
+	// A member is no longer alive.
+	TopologyEvent_MemberDied TopologyEventType = 3
+	// A dead member is alive again.
+	TopologyEvent_MemberRevived TopologyEventType = 4
+	// The cluster has a different leader, Member is nil when there is no leader anymore.
+	TopologyEvent_LeaderChanged TopologyEventType = 5
+	// Gossip couldn't be read, see Error.
+	TopologyEvent_GossipFailed TopologyEventType = 6
+)
+
+func (eventType TopologyEventType) String() string {
+	switch eventType {
+	case TopologyEvent_MemberJoined:
+		return "MemberJoined"
+	case TopologyEvent_MemberLeft:
+		return "MemberLeft"
+	case TopologyEvent_MemberStateChanged:
+		return "MemberStateChanged"
+	case TopologyEvent_MemberDied:
+		return "MemberDied"
+	case TopologyEvent_MemberRevived:
+		return "MemberRevived"
+	case TopologyEvent_LeaderChanged:
+		return "LeaderChanged"
+	case TopologyEvent_GossipFailed:
+		return "GossipFailed"
+	default:
+		return fmt.Sprintf("TopologyEventType(%d)", int32(eventType))
+	}
+}
+
+// TopologyEvent describes a change between two gossip polls.
+type TopologyEvent struct {
+	Type TopologyEventType
+	// The member the event is about, in its current state.
+	Member *MemberInfo
+	// The member before the change, when it was already known.
+	Previous *MemberInfo
+	// The gossip the change was detected in, nil for TopologyEvent_GossipFailed.
+	Cluster *ClusterInfo
+	Error   error
+}
+
+type WatchGossipOptions struct {
+	// Interval between two gossip reads.
+	Interval time.Duration // Defaults to 1 second.
+	// Number of events buffered ahead of the consumer, polling pauses when the buffer is full.
+	BufferSize int // Defaults to 16.
+}
+
+func (o *WatchGossipOptions) setDefaults() {
+	if o.Interval <= 0 {
+		o.Interval = time.Second
+	}
+
+	if o.BufferSize <= 0 {
+		o.BufferSize = 16
+	}
+}
+
+// GossipWatcher polls gossip in the background and emits the changes it detects.
+type GossipWatcher struct {
+	events chan TopologyEvent
+	cancel context.CancelFunc
+}
+
+// Events returns the detected changes. The first poll reports every member as joined, followed by
+// the leader. The channel is closed once the watcher stops.
+func (watcher *GossipWatcher) Events() <-chan TopologyEvent {
+	return watcher.events
+}
+
+// Close stops the watcher.
+func (watcher *GossipWatcher) Close() {
+	watcher.cancel()
+}
+
+// WatchGossip starts polling gossip until ctx is done or the watcher is closed.
+func (client *Client) WatchGossip(ctx context.Context, options WatchGossipOptions) *GossipWatcher {
+	options.setDefaults()
+	ctx, cancel := context.WithCancel(ctx)
+
+	watcher := &GossipWatcher{
+		events: make(chan TopologyEvent, options.BufferSize),
+		cancel: cancel,
+	}
+
+	go func() {
+		defer close(watcher.events)
+
+		ticker := time.NewTicker(options.Interval)
+		defer ticker.Stop()
+
+		previous := &ClusterInfo{}
+		for {
+			readCtx, cancelRead := context.WithTimeout(ctx, options.Interval)
+			current, err := client.ReadGossip(readCtx)
+			cancelRead()
+
+			var events []TopologyEvent
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+
+				events = []TopologyEvent{{Type: TopologyEvent_GossipFailed, Error: err}}
+			} else {
+				events = diffTopology(previous, current)
+				previous = current
+			}
+
+			for _, event := range events {
+				select {
+				case watcher.events <- event:
+				case <-ctx.Done():
+					return
+				}
+			}
+
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return watcher
+}
+
+// diffTopology lists the changes between two gossip reads, matching members by instance id.
+func diffTopology(previous *ClusterInfo, current *ClusterInfo) []TopologyEvent {
+	var events []TopologyEvent
+
+	known := make(map[uuid.UUID]*MemberInfo, len(previous.Members))
+	for i := range previous.Members {
+		known[previous.Members[i].InstanceID] = &previous.Members[i]
+	}
+
+	seen := make(map[uuid.UUID]bool, len(current.Members))
+	for i := range current.Members {
+		member := &current.Members[i]
+		seen[member.InstanceID] = true
+
+		before, exists := known[member.InstanceID]
+		if !exists {
+			events = append(events, TopologyEvent{Type: TopologyEvent_MemberJoined, Member: member, Cluster: current})
+			continue
+		}
+
+		if before.IsAlive && !member.IsAlive {
+			events = append(events, TopologyEvent{Type: TopologyEvent_MemberDied, Member: member, Previous: before, Cluster: current})
+		} else if !before.IsAlive && member.IsAlive {
+			events = append(events, TopologyEvent{Type: TopologyEvent_MemberRevived, Member: member, Previous: before, Cluster: current})
+		}
+
+		if before.State != member.State {
+			events = append(events, TopologyEvent{Type: TopologyEvent_MemberStateChanged, Member: member, Previous: before, Cluster: current})
+		}
+	}
+
+	for i := range previous.Members {
+		if before := &previous.Members[i]; !seen[before.InstanceID] {
+			events = append(events, TopologyEvent{Type: TopologyEvent_MemberLeft, Previous: before, Cluster: current})
+		}
+	}
+
+	previousLeader, currentLeader := previous.Leader(), current.Leader()
+	if leaderId(previousLeader) != leaderId(currentLeader) {
+		events = append(events, TopologyEvent{Type: TopologyEvent_LeaderChanged, Member: currentLeader, Previous: previousLeader, Cluster: current})
+	}
+
+	return events
+}
+
+func leaderId(leader *MemberInfo) uuid.UUID {
+	if leader == nil {
+		return uuid.Nil
+	}
+
+	return leader.InstanceID
+}
+
+// watchConnectedNode polls gossip through the connection of every route and starts a new discovery
+// for a route as soon as its node is no longer eligible for the node preference of the route.
+func (client *grpcClient) watchConnectedNode(ctx context.Context, config Configuration) {
+	ticker := time.NewTicker(config.GossipWatchInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+
+		readCtx, cancel := context.WithTimeout(ctx, config.GossipWatchInterval)
+		client.checkConnectedNodes(readCtx, &config)
+		cancel()
+	}
+}
+
+// checkConnectedNodes checks the nodes of the routes currently connected, routes waiting for a
+// discovery are left to the operations needing them.
+func (client *grpcClient) checkConnectedNodes(ctx context.Context, config *Configuration) {
+	msg := newGetRoutesMsg()
+
+	select {
+	case client.channel <- msg:
+	case <-ctx.Done():
+		return
+	}
+
+	var routes []connectedRoute
+	select {
+	case routes = <-msg.channel:
+	case <-ctx.Done():
+		return
+	}
+
+	for _, route := range routes {
+		client.checkConnectedNode(ctx, config, route)
+	}
+}
+
+func (client *grpcClient) checkConnectedNode(ctx context.Context, config *Configuration, route connectedRoute) {
+	info, err := client.readGossip(ctx, route.handle)
+	if err != nil {
+		config.logf("[warn] failed to read gossip while watching the connected node: %v", err)
+		return
+	}
+
+	target := route.handle.Connection().Target()
+	member := connectedMember(ctx, info, config, target)
+	if member == nil || (member.IsAlive && isEligibleNode(member.State, route.preference)) {
+		return
+	}
+
+	config.logf("[info] connected node %s is now %s, starting a new discovery for %s", target, member.State, route.preference)
+
+	select {
+	case client.channel <- reconnect{correlation: route.handle.Id()}:
+	case <-ctx.Done():
+	}
+}
+
+func isEligibleNode(state VNodeState, nodePreference NodePreference) bool {
+	if nodePreference == NodePreference_Leader && state != VNodeState_Leader {
+		return false
+	}
+
+	return isAllowedNodeState(state)
+}
@@ -0,0 +1,221 @@
+package esdb_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/EventStore/EventStore-Client-Go/esdb"
+	gossipApi "github.com/EventStore/EventStore-Client-Go/protos/gossip"
+	api "github.com/EventStore/EventStore-Client-Go/protos/streams"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+)
+
+// mutableGossip serves a cluster view that tests can change over time.
+type mutableGossip struct {
+	lock    sync.Mutex
+	members []*gossipApi.MemberInfo
+}
+
+func (gossip *mutableGossip) set(members ...*gossipApi.MemberInfo) {
+	gossip.lock.Lock()
+	defer gossip.lock.Unlock()
+	gossip.members = members
+}
+
+func (gossip *mutableGossip) server() *fakeGossipServer {
+	return &fakeGossipServer{
+		read: func(ctx context.Context) (*gossipApi.ClusterInfo, error) {
+			gossip.lock.Lock()
+			defer gossip.lock.Unlock()
+			return &gossipApi.ClusterInfo{Members: gossip.members}, nil
+		},
+	}
+}
+
+func withState(member *gossipApi.MemberInfo, state gossipApi.MemberInfo_VNodeState, alive bool) *gossipApi.MemberInfo {
+	changed := proto.Clone(member).(*gossipApi.MemberInfo)
+	changed.State = state
+	changed.IsAlive = alive
+	return changed
+}
+
+func TestReadGossip(t *testing.T) {
+	address := startFakeLeader(t, &fakeStreamsServer{})
+	client := CreateClient("esdb://"+address+"?tls=false", t)
+	defer client.Close()
+
+	info, err := client.ReadGossip(context.Background())
+	require.NoError(t, err)
+	require.Len(t, info.Members, 1)
+
+	member := info.Members[0]
+	assert.NotEqual(t, "00000000-0000-0000-0000-000000000000", member.InstanceID.String())
+	assert.Equal(t, esdb.VNodeState_Leader, member.State)
+	assert.True(t, member.IsAlive)
+	assert.Equal(t, address, member.HttpEndPoint.String())
+	assert.Equal(t, &info.Members[0], info.Leader())
+}
+
+func TestWatchGossipEmitsTopologyChanges(t *testing.T) {
+	first := fakeMember("10.0.0.1:2113", gossipApi.MemberInfo_Leader)
+	second := fakeMember("10.0.0.2:2113", gossipApi.MemberInfo_Follower)
+	gossip := &mutableGossip{}
+	gossip.set(first, second)
+
+	address := startFakeGossipServer(t, gossip.server())
+	client := CreateClient("esdb://"+address+"?tls=false", t)
+	defer client.Close()
+
+	watcher := client.WatchGossip(context.Background(), esdb.WatchGossipOptions{Interval: 20 * time.Millisecond})
+	defer watcher.Close()
+
+	next := func() esdb.TopologyEvent {
+		select {
+		case event := <-watcher.Events():
+			return event
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for a topology event")
+			return esdb.TopologyEvent{}
+		}
+	}
+
+	assert.Equal(t, esdb.TopologyEvent_MemberJoined, next().Type)
+	assert.Equal(t, esdb.TopologyEvent_MemberJoined, next().Type)
+	leader := next()
+	assert.Equal(t, esdb.TopologyEvent_LeaderChanged, leader.Type)
+	assert.Equal(t, "10.0.0.1:2113", leader.Member.HttpEndPoint.String())
+
+	gossip.set(
+		withState(first, gossipApi.MemberInfo_Leader, false),
+		withState(second, gossipApi.MemberInfo_Leader, true),
+	)
+
+	died := next()
+	assert.Equal(t, esdb.TopologyEvent_MemberDied, died.Type)
+	assert.Equal(t, "10.0.0.1:2113", died.Member.HttpEndPoint.String())
+
+	promoted := next()
+	assert.Equal(t, esdb.TopologyEvent_MemberStateChanged, promoted.Type)
+	assert.Equal(t, esdb.VNodeState_Follower, promoted.Previous.State)
+	assert.Equal(t, esdb.VNodeState_Leader, promoted.Member.State)
+
+	leader = next()
+	assert.Equal(t, esdb.TopologyEvent_LeaderChanged, leader.Type)
+	assert.Equal(t, "10.0.0.2:2113", leader.Member.HttpEndPoint.String())
+	assert.Equal(t, "10.0.0.1:2113", leader.Previous.HttpEndPoint.String())
+
+	gossip.set(withState(second, gossipApi.MemberInfo_Leader, true))
+
+	left := next()
+	assert.Equal(t, esdb.TopologyEvent_MemberLeft, left.Type)
+	assert.Equal(t, "10.0.0.1:2113", left.Previous.HttpEndPoint.String())
+
+	watcher.Close()
+	for range watcher.Events() {
+	}
+}
+
+// namedNode serves gossip from a shared view and answers reads with an event from a stream named
+// after the node, so tests can tell which node served a request.
+func namedNode(t *testing.T, name string, gossip *mutableGossip) string {
+	streams := &fakeStreamsServer{
+		read: func(req *api.ReadReq, server api.Streams_ReadServer) error {
+			return server.Send(fakeReadEvent(name, 0))
+		},
+	}
+
+	return startFakeServer(t, func(server *grpc.Server) {
+		gossipApi.RegisterGossipServer(server, gossip.server())
+		api.RegisterStreamsServer(server, streams)
+	})
+}
+
+func servedBy(t *testing.T, client *esdb.Client) string {
+	return servedByPreference(t, client, "")
+}
+
+func servedByPreference(t *testing.T, client *esdb.Client, preference esdb.NodePreference) string {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	stream, err := client.ReadStream(ctx, "orders", esdb.ReadStreamOptions{NodePreference: preference}, 1)
+	if err != nil {
+		return ""
+	}
+	defer stream.Close()
+
+	event, err := stream.Recv()
+	if err != nil {
+		return ""
+	}
+
+	return event.OriginalEvent().StreamID
+}
+
+func TestClientReconnectsWhenConnectedNodeIsNoLongerEligible(t *testing.T) {
+	gossip := &mutableGossip{}
+	first := namedNode(t, "first", gossip)
+	second := namedNode(t, "second", gossip)
+
+	firstMember := fakeMember(first, gossipApi.MemberInfo_Leader)
+	secondMember := fakeMember(second, gossipApi.MemberInfo_Follower)
+	gossip.set(firstMember, secondMember)
+
+	config, err := esdb.ParseConnectionString("esdb+discover://" + first + "?tls=false")
+	require.NoError(t, err)
+	config.NodePreference = esdb.NodePreference_Leader
+	config.GossipWatchInterval = 20 * time.Millisecond
+
+	client, err := esdb.NewClient(config)
+	require.NoError(t, err)
+	defer client.Close()
+
+	assert.Equal(t, "first", servedBy(t, client))
+
+	gossip.set(
+		withState(firstMember, gossipApi.MemberInfo_ShuttingDown, true),
+		withState(secondMember, gossipApi.MemberInfo_Leader, true),
+	)
+
+	assert.Eventually(t, func() bool {
+		return servedBy(t, client) == "second"
+	}, 5*time.Second, 20*time.Millisecond)
+}
+
+func TestClientReconnectsEveryRouteWhoseNodeIsNoLongerEligible(t *testing.T) {
+	gossip := &mutableGossip{}
+	first := namedNode(t, "first", gossip)
+	second := namedNode(t, "second", gossip)
+
+	firstMember := fakeMember(first, gossipApi.MemberInfo_Leader)
+	secondMember := fakeMember(second, gossipApi.MemberInfo_Follower)
+	gossip.set(firstMember, secondMember)
+
+	config, err := esdb.ParseConnectionString("esdb+discover://" + first + "?tls=false")
+	require.NoError(t, err)
+	config.NodePreference = esdb.NodePreference_Follower
+	config.GossipWatchInterval = 20 * time.Millisecond
+
+	client, err := esdb.NewClient(config)
+	require.NoError(t, err)
+	defer client.Close()
+
+	assert.Equal(t, "second", servedBy(t, client))
+	assert.Equal(t, "first", servedByPreference(t, client, esdb.NodePreference_Leader))
+
+	gossip.set(
+		withState(firstMember, gossipApi.MemberInfo_Follower, true),
+		withState(secondMember, gossipApi.MemberInfo_Leader, true),
+	)
+
+	// Only the leader route is pinned to a node that is no longer eligible.
+	assert.Eventually(t, func() bool {
+		return servedByPreference(t, client, esdb.NodePreference_Leader) == "second"
+	}, 5*time.Second, 20*time.Millisecond)
+	assert.Equal(t, "second", servedBy(t, client))
+}
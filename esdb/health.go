@@ -0,0 +1,156 @@
+package esdb
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+)
+
+// NodeHealth describes the node the client is connected to, as returned by Client.Health.
+type NodeHealth struct {
+	// The address of the node, as dialed by the client.
+	EndPoint EndPoint
+	// The node as described by its own gossip, nil when it can't be found in it, e.g. when a
+	// cluster member advertises an address that neither translates nor resolves to the one the
+	// client dials.
+	Member *MemberInfo
+	// The state of the node, VNodeState_Unknown when Member is nil.
+	State VNodeState
+	// The round-trip time of the gossip read.
+	RoundTrip time.Duration
+}
+
+// Health connects to a node if needed, reads its gossip and returns its state and the round-trip
+// time of the read. It is bounded by DefaultDeadline when ctx has no deadline.
+func (client *Client) Health(ctx context.Context) (_ *NodeHealth, err error) {
+	ctx, span := client.grpcClient.startSpan(ctx, "Health", "")
+	defer func() { span.end(err) }()
+
+	return client.health(ctx, "Health")
+}
+
+// Ping checks that the client can reach a node in a state operations can use, like a leader or a
+// follower, and returns the round-trip time. It is meant for readiness probes, so it fails with
+// ErrNodeNotReady when the node is dead or can't be found in its own gossip.
+func (client *Client) Ping(ctx context.Context) (_ time.Duration, err error) {
+	ctx, span := client.grpcClient.startSpan(ctx, "Ping", "")
+	defer func() { span.end(err) }()
+
+	health, err := client.health(ctx, "Ping")
+	if err != nil {
+		return 0, err
+	}
+
+	if health.Member == nil {
+		return health.RoundTrip, fmt.Errorf("%w: %s can't be found in its gossip", ErrNodeNotReady, health.EndPoint.String())
+	}
+
+	if !health.Member.IsAlive {
+		return health.RoundTrip, fmt.Errorf("%w: %s is dead", ErrNodeNotReady, health.EndPoint.String())
+	}
+
+	if !isAllowedNodeState(health.State) {
+		return health.RoundTrip, fmt.Errorf("%w: %s is %s", ErrNodeNotReady, health.EndPoint.String(), health.State)
+	}
+
+	return health.RoundTrip, nil
+}
+
+func (client *Client) health(ctx context.Context, operation string) (_ *NodeHealth, err error) {
+	end, err := client.grpcClient.operations.begin()
+	if err != nil {
+		return nil, err
+	}
+	defer end()
+
+	ctx, cancel := client.grpcClient.withDeadline(ctx, operation, 0)
+	defer cancel()
+	defer func() { err = deadlineError(ctx, err) }()
+
+	handle, err := client.grpcClient.getConnectionHandle(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("can't get a connection handle: %w", err)
+	}
+
+	target := handle.Connection().Target()
+	endpoint, err := ParseEndPoint(target)
+	if err != nil {
+		return nil, fmt.Errorf("invalid address of the connected node %s: %w", target, err)
+	}
+
+	started := time.Now()
+	info, err := client.grpcClient.readGossip(ctx, handle)
+	if err != nil {
+		return nil, err
+	}
+
+	health := &NodeHealth{
+		EndPoint:  *endpoint,
+		State:     VNodeState_Unknown,
+		RoundTrip: time.Since(started),
+	}
+
+	if member := connectedMember(ctx, info, &client.grpcClient.config, target); member != nil {
+		health.Member = member
+		health.State = member.State
+	}
+
+	return health, nil
+}
+
+// connectedMember returns the member of the gossip the client reaches at target, if any. Nodes are
+// often reached under another name than the one they advertise, like localhost or a docker host
+// name, so a single node client takes the lone member of the gossip, and the addresses of the
+// members are resolved otherwise.
+func connectedMember(ctx context.Context, info *ClusterInfo, conf *Configuration, target string) *MemberInfo {
+	for i := range info.Members {
+		if endpoint := conf.translateAddress(info.Members[i].HttpEndPoint); endpoint.String() == target {
+			return &info.Members[i]
+		}
+	}
+
+	if !conf.DnsDiscover && len(conf.GossipSeeds) == 0 && len(info.Members) == 1 {
+		return &info.Members[0]
+	}
+
+	reached, err := ParseEndPoint(target)
+	if err != nil {
+		return nil
+	}
+
+	reachedAddresses := hostAddresses(ctx, conf, reached.Host)
+	for i := range info.Members {
+		endpoint := conf.translateAddress(info.Members[i].HttpEndPoint)
+		if endpoint.Port != reached.Port {
+			continue
+		}
+
+		for address := range hostAddresses(ctx, conf, endpoint.Host) {
+			if reachedAddresses[address] {
+				return &info.Members[i]
+			}
+		}
+	}
+
+	return nil
+}
+
+// hostAddresses returns the IP addresses of a host, none if it can't be resolved.
+func hostAddresses(ctx context.Context, conf *Configuration, host string) map[string]bool {
+	if ip := net.ParseIP(host); ip != nil {
+		return map[string]bool{ip.String(): true}
+	}
+
+	resolved, err := conf.resolver().LookupHost(ctx, host)
+	if err != nil {
+		return nil
+	}
+
+	addresses := make(map[string]bool, len(resolved))
+	for _, address := range resolved {
+		addresses[net.ParseIP(address).String()] = true
+	}
+
+	return addresses
+}
@@ -0,0 +1,140 @@
+package esdb_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/EventStore/EventStore-Client-Go/esdb"
+	gossipApi "github.com/EventStore/EventStore-Client-Go/protos/gossip"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealthReportsConnectedNode(t *testing.T) {
+	gossip := &mutableGossip{}
+	address := startFakeGossipServer(t, gossip.server())
+	gossip.set(
+		fakeMember("10.0.0.2:2113", gossipApi.MemberInfo_Leader),
+		fakeMember(address, gossipApi.MemberInfo_Follower),
+	)
+
+	client := CreateClient("esdb://"+address+"?tls=false", t)
+	defer client.Close()
+
+	health, err := client.Health(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, address, health.EndPoint.String())
+	require.NotNil(t, health.Member)
+	assert.Equal(t, address, health.Member.HttpEndPoint.String())
+	assert.Equal(t, esdb.VNodeState_Follower, health.State)
+	assert.Greater(t, int64(health.RoundTrip), int64(0))
+
+	roundTrip, err := client.Ping(context.Background())
+	require.NoError(t, err)
+	assert.Greater(t, int64(roundTrip), int64(0))
+
+	// A node leaving the states operations can use isn't ready anymore.
+	gossip.set(fakeMember(address, gossipApi.MemberInfo_ShuttingDown))
+
+	health, err = client.Health(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, esdb.VNodeState_ShuttingDown, health.State)
+
+	_, err = client.Ping(context.Background())
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, esdb.ErrNodeNotReady), err.Error())
+	assert.Equal(t, "NodeNotReady", esdb.ErrorType(err))
+
+	// The node can't be found in a gossip advertising other addresses.
+	gossip.set(
+		fakeMember("10.0.0.2:2113", gossipApi.MemberInfo_Leader),
+		fakeMember("10.0.0.3:2113", gossipApi.MemberInfo_Follower),
+	)
+
+	health, err = client.Health(context.Background())
+	require.NoError(t, err)
+	assert.Nil(t, health.Member)
+	assert.Equal(t, esdb.VNodeState_Unknown, health.State)
+
+	_, err = client.Ping(context.Background())
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, esdb.ErrNodeNotReady), err.Error())
+
+	// Neither is a node its gossip describes as dead.
+	dead := fakeMember(address, gossipApi.MemberInfo_Leader)
+	dead.IsAlive = false
+	gossip.set(dead)
+
+	_, err = client.Ping(context.Background())
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, esdb.ErrNodeNotReady), err.Error())
+}
+
+func TestHealthFindsNodeReachedUnderAnotherName(t *testing.T) {
+	gossip := &mutableGossip{}
+	address := startFakeGossipServer(t, gossip.server())
+	_, port, err := net.SplitHostPort(address)
+	require.NoError(t, err)
+
+	client := CreateClient("esdb://localhost:"+port+"?tls=false", t)
+	defer client.Close()
+
+	// The advertised address of the node resolves to the one the client dials.
+	gossip.set(
+		fakeMember("10.0.0.2:2113", gossipApi.MemberInfo_Leader),
+		fakeMember(address, gossipApi.MemberInfo_Follower),
+	)
+
+	health, err := client.Health(context.Background())
+	require.NoError(t, err)
+	require.NotNil(t, health.Member)
+	assert.Equal(t, address, health.Member.HttpEndPoint.String())
+	assert.Equal(t, esdb.VNodeState_Follower, health.State)
+
+	// A single node is the lone member of its gossip, whatever address it advertises.
+	gossip.set(fakeMember("eventstore.internal:2113", gossipApi.MemberInfo_Leader))
+
+	health, err = client.Health(context.Background())
+	require.NoError(t, err)
+	require.NotNil(t, health.Member)
+	assert.Equal(t, esdb.VNodeState_Leader, health.State)
+
+	_, err = client.Ping(context.Background())
+	assert.NoError(t, err)
+}
+
+func TestPingIsBoundedByDefaultDeadline(t *testing.T) {
+	address := startFakeGossipServer(t, hangingGossip())
+	client := CreateClient("esdb://"+address+"?tls=false&defaultDeadline=100", t)
+	defer client.Close()
+
+	_, err := client.Ping(context.Background())
+	assertDeadlineExceeded(t, err, "Ping", 100*time.Millisecond)
+}
+
+func TestEagerConnect(t *testing.T) {
+	leader := startFakeLeader(t, &fakeStreamsServer{})
+
+	config, err := esdb.ParseConnectionString("esdb+discover://" + leader + "?tls=false&eagerConnectTimeout=5000")
+	require.NoError(t, err)
+	assert.Equal(t, 5*time.Second, config.EagerConnectTimeout)
+
+	client, err := esdb.NewClient(config)
+	require.NoError(t, err)
+	defer client.Close()
+
+	for _, address := range []string{closedAddress(t), startFakeGossipServer(t, hangingGossip())} {
+		config, err := esdb.ParseConnectionString("esdb://" + address + "?tls=false&eagerConnectTimeout=200")
+		require.NoError(t, err)
+
+		started := time.Now()
+		client, err := esdb.NewClient(config)
+		require.Error(t, err)
+		assert.Nil(t, client)
+		assert.Contains(t, err.Error(), "failed to connect within 200ms")
+		assert.Less(t, int64(time.Since(started)), int64(5*time.Second))
+	}
+}
@@ -0,0 +1,204 @@
+package esdb_test
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/EventStore/EventStore-Client-Go/esdb"
+	api "github.com/EventStore/EventStore-Client-Go/protos/streams"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// stallingServer confirms subscriptions then never sends anything. Reads succeed unless
+// stallReads is set, in which case they hang as well.
+func stallingServer(stallReads bool, probes *int32) *fakeStreamsServer {
+	return &fakeStreamsServer{
+		read: func(req *api.ReadReq, server api.Streams_ReadServer) error {
+			if req.GetOptions().GetSubscription() != nil {
+				if err := server.Send(fakeSubscriptionConfirmation()); err != nil {
+					return err
+				}
+
+				<-server.Context().Done()
+				return nil
+			}
+
+			atomic.AddInt32(probes, 1)
+			if stallReads {
+				<-server.Context().Done()
+				return nil
+			}
+
+			return server.Send(fakeReadEvent("probe", 0))
+		},
+	}
+}
+
+func recvWithTimeout(t *testing.T, subscription *esdb.Subscription, timeout time.Duration) *esdb.SubscriptionEvent {
+	events := make(chan *esdb.SubscriptionEvent, 1)
+	go func() {
+		events <- subscription.Recv()
+	}()
+
+	select {
+	case event := <-events:
+		return event
+	case <-time.After(timeout):
+		t.Fatalf("timed out waiting for a subscription event")
+		return nil
+	}
+}
+
+func TestFilteredSubscriptionDropsWhenIdle(t *testing.T) {
+	var probes int32
+	client := createFakeServerClient(t, stallingServer(false, &probes))
+
+	subscription, err := client.SubscribeToAll(context.Background(), esdb.SubscribeToAllOptions{
+		Filter:      esdb.ExcludeSystemEventsFilter(),
+		IdleTimeout: 100 * time.Millisecond,
+	})
+	require.NoError(t, err)
+	defer subscription.Close()
+
+	event := recvWithTimeout(t, subscription, 5*time.Second)
+	require.NotNil(t, event.SubscriptionDropped)
+	assert.Equal(t, esdb.SubscriptionDropReason_IdleTimeout, event.SubscriptionDropped.Reason)
+	assert.True(t, errors.Is(event.SubscriptionDropped.Error, esdb.ErrSubscriptionIdleTimeout))
+	assert.Zero(t, atomic.LoadInt32(&probes))
+}
+
+func TestIdleSubscriptionIsKeptWhenProbeSucceeds(t *testing.T) {
+	var probes int32
+	client := createFakeServerClient(t, stallingServer(false, &probes))
+
+	subscription, err := client.SubscribeToStream(context.Background(), "a-stream", esdb.SubscribeToStreamOptions{
+		IdleTimeout: 50 * time.Millisecond,
+	})
+	require.NoError(t, err)
+
+	events := make(chan *esdb.SubscriptionEvent, 1)
+	go func() {
+		events <- subscription.Recv()
+	}()
+
+	require.Eventually(t, func() bool { return atomic.LoadInt32(&probes) >= 3 }, 5*time.Second, 10*time.Millisecond)
+	select {
+	case <-events:
+		t.Fatalf("the subscription shouldn't have dropped")
+	default:
+	}
+
+	subscription.Close()
+	event := <-events
+	require.NotNil(t, event.SubscriptionDropped)
+	assert.False(t, errors.Is(event.SubscriptionDropped.Error, esdb.ErrSubscriptionIdleTimeout))
+}
+
+func TestIdleSubscriptionDropsWhenProbeFails(t *testing.T) {
+	var probes int32
+	client := createFakeServerClient(t, stallingServer(true, &probes))
+
+	subscription, err := client.SubscribeToAll(context.Background(), esdb.SubscribeToAllOptions{
+		IdleTimeout: 100 * time.Millisecond,
+	})
+	require.NoError(t, err)
+	defer subscription.Close()
+
+	event := recvWithTimeout(t, subscription, 5*time.Second)
+	require.NotNil(t, event.SubscriptionDropped)
+	assert.Equal(t, esdb.SubscriptionDropReason_IdleTimeout, event.SubscriptionDropped.Reason)
+	assert.True(t, errors.Is(event.SubscriptionDropped.Error, esdb.ErrSubscriptionIdleTimeout))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&probes))
+}
+
+func TestIdleSubscriptionIsKeptWhenProbeIsDenied(t *testing.T) {
+	var probes int32
+	client := createFakeServerClient(t, &fakeStreamsServer{
+		read: func(req *api.ReadReq, server api.Streams_ReadServer) error {
+			if req.GetOptions().GetSubscription() != nil {
+				if err := server.Send(fakeSubscriptionConfirmation()); err != nil {
+					return err
+				}
+
+				<-server.Context().Done()
+				return nil
+			}
+
+			// The user of the subscription isn't allowed to read the probed stream.
+			atomic.AddInt32(&probes, 1)
+			return status.Error(codes.PermissionDenied, "access denied")
+		},
+	})
+
+	subscription, err := client.SubscribeToAll(context.Background(), esdb.SubscribeToAllOptions{
+		IdleTimeout: 50 * time.Millisecond,
+	})
+	require.NoError(t, err)
+	defer subscription.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := make(chan *esdb.SubscriptionEvent, 1)
+	go func() {
+		event, err := subscription.RecvContext(ctx)
+		if err == nil {
+			events <- event
+		}
+	}()
+
+	require.Eventually(t, func() bool { return atomic.LoadInt32(&probes) >= 3 }, 5*time.Second, 10*time.Millisecond)
+	select {
+	case event := <-events:
+		t.Fatalf("the subscription shouldn't have dropped: %+v", event.SubscriptionDropped)
+	default:
+	}
+}
+
+func TestIdleProbeUsesTheConnectionOfTheSubscription(t *testing.T) {
+	recorder := &callRecorder{}
+	leader := recordingNode(t, "leader", &mutableGossip{}, recorder)
+
+	var probes int32
+	follower := stallingServer(true, &probes)
+	follower.delete = func(ctx context.Context, req *api.DeleteReq) (*api.DeleteResp, error) {
+		grpc.SetTrailer(ctx, metadata.Pairs(
+			"exception", "not-leader",
+			"leader-endpoint-host", "127.0.0.1",
+			"leader-endpoint-port", strconv.Itoa(int(port(t, leader))),
+		))
+		return nil, status.Error(codes.NotFound, "not leader")
+	}
+
+	address := startFakeServer(t, func(server *grpc.Server) {
+		api.RegisterStreamsServer(server, follower)
+	})
+	client := CreateClient("esdb://"+address+"?tls=false", t)
+	defer client.Close()
+
+	subscription, err := client.SubscribeToStream(context.Background(), "a-stream", esdb.SubscribeToStreamOptions{
+		IdleTimeout: 100 * time.Millisecond,
+	})
+	require.NoError(t, err)
+	defer subscription.Close()
+
+	// The redirect replaces the connection to the follower by one to the leader, which answers.
+	_, err = client.DeleteStream(context.Background(), "orders", esdb.DeleteStreamOptions{})
+	require.Error(t, err)
+	_, err = client.DeleteStream(context.Background(), "orders", esdb.DeleteStreamOptions{})
+	require.NoError(t, err)
+
+	// The probe goes through the stalled connection of the subscription, not the leader's.
+	event := recvWithTimeout(t, subscription, 5*time.Second)
+	require.NotNil(t, event.SubscriptionDropped)
+	assert.Equal(t, esdb.SubscriptionDropReason_IdleTimeout, event.SubscriptionDropped.Reason)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&probes))
+}
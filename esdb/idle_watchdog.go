@@ -0,0 +1,106 @@
+package esdb
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// idleWatchdog cancels a subscription when a pending receive doesn't get any message before the
+// idle timeout. With a probe, the connection is first checked and the subscription is only
+// cancelled if the probe fails.
+type idleWatchdog struct {
+	conf     *Configuration
+	timeout  time.Duration
+	probe    func(ctx context.Context) error
+	cancel   context.CancelFunc
+	lock     sync.Mutex
+	timer    *time.Timer
+	armed    bool
+	timedOut bool
+	// Incremented on every arm so a late timer from a previous receive is ignored.
+	generation uint64
+}
+
+func newIdleWatchdog(conf *Configuration, timeout time.Duration, probe func(ctx context.Context) error, cancel context.CancelFunc) *idleWatchdog {
+	return &idleWatchdog{
+		conf:    conf,
+		timeout: timeout,
+		probe:   probe,
+		cancel:  cancel,
+	}
+}
+
+// arm starts watching a receive. A nil watchdog never fires.
+func (watchdog *idleWatchdog) arm() {
+	if watchdog == nil {
+		return
+	}
+
+	watchdog.lock.Lock()
+	defer watchdog.lock.Unlock()
+
+	watchdog.armed = true
+	watchdog.generation++
+	watchdog.schedule(watchdog.generation)
+}
+
+func (watchdog *idleWatchdog) schedule(generation uint64) {
+	watchdog.timer = time.AfterFunc(watchdog.timeout, func() {
+		watchdog.fire(generation)
+	})
+}
+
+func (watchdog *idleWatchdog) current(generation uint64) bool {
+	return watchdog.armed && watchdog.generation == generation
+}
+
+// disarm stops watching the current receive and tells if the watchdog cancelled it.
+func (watchdog *idleWatchdog) disarm() bool {
+	if watchdog == nil {
+		return false
+	}
+
+	watchdog.lock.Lock()
+	defer watchdog.lock.Unlock()
+
+	watchdog.armed = false
+	if watchdog.timer != nil {
+		watchdog.timer.Stop()
+		watchdog.timer = nil
+	}
+
+	return watchdog.timedOut
+}
+
+func (watchdog *idleWatchdog) fire(generation uint64) {
+	if watchdog.probe != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), watchdog.timeout)
+		err := watchdog.probe(ctx)
+		cancel()
+
+		if err == nil {
+			watchdog.lock.Lock()
+			defer watchdog.lock.Unlock()
+
+			// The connection is alive, the subscription is just quiet.
+			if watchdog.current(generation) {
+				watchdog.schedule(generation)
+			}
+
+			return
+		}
+
+		watchdog.conf.logf("[warn] idle subscription probe failed: %v", err)
+	}
+
+	watchdog.lock.Lock()
+	if !watchdog.current(generation) {
+		watchdog.lock.Unlock()
+		return
+	}
+	watchdog.timedOut = true
+	watchdog.lock.Unlock()
+
+	watchdog.cancel()
+}
@@ -2,12 +2,13 @@ package esdb
 
 import (
 	"context"
+	"crypto/sha256"
 	"crypto/tls"
 	"encoding/base64"
 	"fmt"
-	"log"
 	"math/rand"
 	"strconv"
+	"sync"
 	"time"
 
 	gossipApi "github.com/EventStore/EventStore-Client-Go/protos/gossip"
@@ -23,6 +24,13 @@ import (
 
 type grpcClient struct {
 	channel chan msg
+	config  Configuration
+	// Stops watching the connected node, set when Configuration.GossipWatchInterval is.
+	stopWatching context.CancelFunc
+	// Connections of the operations authenticated with a client certificate.
+	certificateConnections *certificateConnections
+	// Operations in flight and open streams, waited for and closed by Client.Shutdown.
+	operations *operationTracker
 }
 
 func (client *grpcClient) handleError(handle connectionHandle, headers metadata.MD, trailers metadata.MD, err error) error {
@@ -33,7 +41,7 @@ func (client *grpcClient) handleError(handle connectionHandle, headers metadata.
 		portValues := trailers.Get("leader-endpoint-port")
 
 		if hostValues != nil && portValues != nil {
-			host := hostValues[0]
+			host := normalizeHost(hostValues[0])
 			port, err := strconv.Atoi(portValues[0])
 
 			if err == nil {
@@ -48,15 +56,22 @@ func (client *grpcClient) handleError(handle connectionHandle, headers metadata.
 				}
 
 				client.channel <- msg
-				log.Printf("[error] Not leader exception occurred")
+				metricsOf(&client.config).Reconnected("NotLeader")
+				client.config.logf("[error] Not leader exception occurred")
 				return fmt.Errorf("not leader exception")
 			}
 		}
 	}
 
-	log.Printf("[error] unexpected exception: %v", err)
-
 	status, _ := status.FromError(err)
+
+	// The caller gave up, that says nothing about the connection other operations share.
+	if status.Code() == codes.DeadlineExceeded || status.Code() == codes.Canceled {
+		return err
+	}
+
+	client.config.logf("[error] unexpected exception: %v", err)
+
 	if status.Code() == codes.FailedPrecondition { // Precondition -> ErrWrongExpectedStreamRevision
 		return fmt.Errorf("%w, reason: %s", ErrWrongExpectedStreamRevision, err.Error())
 	}
@@ -66,92 +81,330 @@ func (client *grpcClient) handleError(handle connectionHandle, headers metadata.
 	if status.Code() == codes.Unauthenticated { // PermissionDenied -> ErrUnauthenticated
 		return fmt.Errorf("%w", ErrUnauthenticated)
 	}
+	if status.Code() == codes.AlreadyExists { // AlreadyExists -> ErrAlreadyExists
+		return fmt.Errorf("%w, reason: %s", ErrAlreadyExists, err.Error())
+	}
 
 	msg := reconnect{
 		correlation: handle.Id(),
 	}
 
 	client.channel <- msg
+	metricsOf(&client.config).Reconnected(ErrorType(err))
 
 	return err
 }
 
-func (client *grpcClient) getConnectionHandle() (connectionHandle, error) {
-	msg := newGetConnectionMsg()
-	client.channel <- msg
+// getConnectionHandle returns the connection matching the node preference of the configuration,
+// waiting for a discovery to complete if there is none. It gives up when ctx is done, without
+// cancelling the discovery other callers may be waiting for.
+func (client *grpcClient) getConnectionHandle(ctx context.Context) (connectionHandle, error) {
+	return client.getConnectionHandleFor(ctx, "", nil)
+}
+
+// getConnectionHandleFor returns the connection to a node matching the given preference, an empty
+// preference standing for the one of the configuration. Operations authenticated with a client
+// certificate get a connection to the same node using that certificate.
+func (client *grpcClient) getConnectionHandleFor(ctx context.Context, preference NodePreference, auth *Credentials) (connectionHandle, error) {
+	msg := newGetConnectionMsg(preference)
+
+	select {
+	case client.channel <- msg:
+	case <-ctx.Done():
+		return newErroredConnectionHandle(ctx.Err()), ctx.Err()
+	}
+
+	select {
+	case resp := <-msg.channel:
+		if resp.err != nil || auth == nil || auth.Certificate == nil {
+			return resp, resp.err
+		}
+
+		connection, err := client.certificateConnections.get(&client.config, resp.Connection().Target(), auth.Certificate)
+		if err != nil {
+			return newErroredConnectionHandle(err), err
+		}
 
-	resp := <-msg.channel
+		resp.connection = connection
+		return resp, nil
+	case <-ctx.Done():
+		return newErroredConnectionHandle(ctx.Err()), ctx.Err()
+	}
+}
 
-	return resp, resp.err
+// getLeaderConnectionHandle returns the connection to the leader, used by operations the server
+// only accepts on the leader.
+func (client *grpcClient) getLeaderConnectionHandle(ctx context.Context, auth *Credentials) (connectionHandle, error) {
+	return client.getConnectionHandleFor(ctx, NodePreference_Leader, auth)
 }
 
 func (client *grpcClient) close() {
+	if client.stopWatching != nil {
+		client.stopWatching()
+	}
+
 	channel := make(chan bool)
 	client.channel <- closeConnection{channel}
 	<-channel
+
+	client.certificateConnections.close()
+}
+
+type certificateConnectionKey struct {
+	target string
+	// SHA-256 of the DER encoded leaf certificate.
+	certificate [sha256.Size]byte
+}
+
+// certificateConnections caches a connection per node and client certificate. A client certificate
+// is part of the TLS handshake, so it can't be changed for a single call.
+type certificateConnections struct {
+	lock        sync.Mutex
+	closed      bool
+	connections map[certificateConnectionKey]*grpc.ClientConn
+}
+
+func newCertificateConnections() *certificateConnections {
+	return &certificateConnections{
+		connections: make(map[certificateConnectionKey]*grpc.ClientConn),
+	}
+}
+
+func (cache *certificateConnections) get(conf *Configuration, target string, certificate *tls.Certificate) (*grpc.ClientConn, error) {
+	if len(certificate.Certificate) == 0 {
+		return nil, fmt.Errorf("the client certificate of the credentials is empty")
+	}
+
+	key := certificateConnectionKey{
+		target:      target,
+		certificate: sha256.Sum256(certificate.Certificate[0]),
+	}
+
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+
+	if cache.closed {
+		return nil, ErrClientClosed
+	}
+
+	if connection, ok := cache.connections[key]; ok {
+		return connection, nil
+	}
+
+	// The certificate is the only identity of these connections.
+	certificateConf := *conf
+	certificateConf.Username = ""
+	certificateConf.Password = ""
+	certificateConf.CredentialsProvider = nil
+	certificateConf.ClientCertificateFile = ""
+	certificateConf.ClientKeyFile = ""
+	certificateConf.ClientCertificate = nil
+	certificateConf.ClientKey = nil
+
+	connection, err := createGrpcConnectionWithCertificate(&certificateConf, target, certificate)
+	if err != nil {
+		return nil, err
+	}
+
+	cache.connections[key] = connection
+	return connection, nil
+}
+
+func (cache *certificateConnections) close() {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+
+	cache.closed = true
+	for key, connection := range cache.connections {
+		connection.Close()
+		delete(cache.connections, key)
+	}
 }
 
 type getConnection struct {
+	preference NodePreference
+	// Buffered so the state machine never waits on a caller that gave up.
 	channel chan connectionHandle
 }
 
-func newGetConnectionMsg() getConnection {
+func newGetConnectionMsg(preference NodePreference) getConnection {
 	return getConnection{
-		channel: make(chan connectionHandle),
+		preference: preference,
+		channel:    make(chan connectionHandle, 1),
 	}
 }
 
 func (msg getConnection) handle(state *connectionState) {
-	// Means we need to create a grpc connection.
-	if state.correlation == uuid.Nil {
-		conn, err := discoverNode(state.config)
+	preference := state.resolvePreference(msg.preference)
+	route := state.route(preference)
 
-		if err != nil {
-			state.lastError = err
-			resp := newErroredConnectionHandle(err)
-			msg.channel <- resp
-			return
-		}
+	if route.correlation != uuid.Nil {
+		msg.channel <- newRoutedConnectionHandle(route.correlation, route.connection, preference)
+		return
+	}
+
+	// Means we need to create a grpc connection. Discovery runs in its own goroutine so the state
+	// machine keeps serving other messages, callers wait for it to complete.
+	route.waiting = append(route.waiting, msg)
+	state.startDiscovery(route)
+}
 
-		id, err := uuid.NewV4()
+type connectionState struct {
+	config    Configuration
+	lastError error
+	closed    bool
+	channel   chan msg
+	// Calls in progress on the connections, replaced connections being closed once they're over.
+	calls *connectionCalls
+	// Connections by node preference. A single node only has the one of the configuration.
+	routes map[NodePreference]*routeState
+}
 
-		if err != nil {
-			state.lastError = fmt.Errorf("error when trying to generate a random UUID: %v", err)
-			return
+// routeState is the connection to a node matching a node preference.
+type routeState struct {
+	preference  NodePreference
+	correlation uuid.UUID
+	connection  *grpc.ClientConn
+	// Set while a discovery is in flight.
+	cancelDiscovery context.CancelFunc
+	// Callers waiting for the discovery to complete.
+	waiting []getConnection
+}
+
+func newConnectionState(config Configuration, channel chan msg, calls *connectionCalls) connectionState {
+	return connectionState{
+		config:    config,
+		lastError: nil,
+		closed:    false,
+		channel:   channel,
+		calls:     calls,
+		routes:    make(map[NodePreference]*routeState),
+	}
+}
+
+// resolvePreference replaces an empty preference with the one of the configuration, which
+// defaults to the leader.
+func (state *connectionState) resolvePreference(preference NodePreference) NodePreference {
+	if preference == "" {
+		preference = state.config.NodePreference
+	}
+
+	if preference == "" {
+		preference = NodePreference_Leader
+	}
+
+	return preference
+}
+
+// route returns the connection used for a node preference, creating it if needed. Without a
+// cluster to pick from, every preference shares the connection to the single node.
+func (state *connectionState) route(preference NodePreference) *routeState {
+	if !state.config.DnsDiscover && len(state.config.GossipSeeds) == 0 {
+		preference = state.resolvePreference("")
+	}
+
+	route, exists := state.routes[preference]
+	if !exists {
+		route = &routeState{preference: preference}
+		state.routes[preference] = route
+	}
+
+	return route
+}
+
+// routeConfig returns the configuration used to discover the node of a route. The node selector
+// only applies to the node preference of the configuration.
+func (state *connectionState) routeConfig(route *routeState) Configuration {
+	config := state.config
+	if route.preference != state.resolvePreference("") {
+		config.NodePreference = route.preference
+		config.NodeSelector = nil
+	}
+
+	return config
+}
+
+func (state *connectionState) routeByCorrelation(correlation uuid.UUID) *routeState {
+	for _, route := range state.routes {
+		if route.correlation == correlation {
+			return route
 		}
+	}
 
-		state.correlation = id
-		state.connection = conn
+	return nil
+}
 
-		resp := newConnectionHandle(id, conn)
-		msg.channel <- resp
-	} else {
-		handle := connectionHandle{
-			id:         state.correlation,
-			connection: state.connection,
-			err:        nil,
+func (state *connectionState) startDiscovery(route *routeState) {
+	if route.cancelDiscovery != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	route.cancelDiscovery = cancel
+
+	go func(config Configuration, preference NodePreference, channel chan msg) {
+		conn, err := discoverNode(ctx, config)
+		channel <- discoveryCompleted{
+			preference: preference,
+			connection: conn,
+			err:        err,
 		}
+	}(state.routeConfig(route), route.preference, state.channel)
+}
 
-		msg.channel <- handle
+// replyToWaiting sends the outcome of a discovery to the callers waiting for it.
+func (route *routeState) replyToWaiting(handle connectionHandle) {
+	for _, waiting := range route.waiting {
+		waiting.channel <- handle
 	}
+
+	route.waiting = nil
 }
 
-type connectionState struct {
-	correlation uuid.UUID
-	connection  *grpc.ClientConn
-	config      Configuration
-	lastError   error
-	closed      bool
+type discoveryCompleted struct {
+	preference NodePreference
+	connection *grpc.ClientConn
+	err        error
 }
 
-func newConnectionState(config Configuration) connectionState {
-	return connectionState{
-		correlation: uuid.Nil,
-		connection:  nil,
-		config:      config,
-		lastError:   nil,
-		closed:      false,
+func (msg discoveryCompleted) handle(state *connectionState) {
+	route := state.routes[msg.preference]
+	route.cancelDiscovery()
+	route.cancelDiscovery = nil
+
+	if msg.err != nil {
+		state.lastError = msg.err
+		route.replyToWaiting(newErroredConnectionHandle(msg.err))
+		return
 	}
+
+	id, err := uuid.NewV4()
+
+	if err != nil {
+		msg.connection.Close()
+		state.lastError = fmt.Errorf("error when trying to generate a random UUID: %v", err)
+		route.replyToWaiting(newErroredConnectionHandle(state.lastError))
+		return
+	}
+
+	state.replaceConnection(route, msg.connection)
+	route.correlation = id
+
+	// Callers may have asked for different preferences sharing this route.
+	for _, waiting := range route.waiting {
+		waiting.channel <- newRoutedConnectionHandle(id, msg.connection, state.resolvePreference(waiting.preference))
+	}
+	route.waiting = nil
+}
+
+// replaceConnection makes connection the one of the route, the previous one being closed once the
+// calls still using it are over.
+func (state *connectionState) replaceConnection(route *routeState, connection *grpc.ClientConn) {
+	if route.connection != nil && route.connection != connection {
+		state.calls.retire(route.connection)
+	}
+
+	route.connection = connection
 }
 
 type msg interface {
@@ -162,6 +415,8 @@ type connectionHandle struct {
 	id         uuid.UUID
 	connection *grpc.ClientConn
 	err        error
+	// Set when the call must be served by the leader.
+	requiresLeader bool
 }
 
 func (handle connectionHandle) Id() uuid.UUID {
@@ -172,6 +427,16 @@ func (handle connectionHandle) Connection() *grpc.ClientConn {
 	return handle.connection
 }
 
+// routeContext tells the server the call must be served by the leader, when it must, so a node
+// that isn't the leader answers with a not-leader exception pointing to the leader.
+func routeContext(ctx context.Context, handle connectionHandle) context.Context {
+	if !handle.requiresLeader {
+		return ctx
+	}
+
+	return metadata.AppendToOutgoingContext(ctx, "requires-leader", "true")
+}
+
 func newErroredConnectionHandle(err error) connectionHandle {
 	return connectionHandle{
 		id:         uuid.Nil,
@@ -188,8 +453,14 @@ func newConnectionHandle(id uuid.UUID, connection *grpc.ClientConn) connectionHa
 	}
 }
 
-func connectionStateMachine(config Configuration, channel chan msg) {
-	state := newConnectionState(config)
+func newRoutedConnectionHandle(id uuid.UUID, connection *grpc.ClientConn, preference NodePreference) connectionHandle {
+	handle := newConnectionHandle(id, connection)
+	handle.requiresLeader = preference == NodePreference_Leader
+	return handle
+}
+
+func connectionStateMachine(config Configuration, channel chan msg, calls *connectionCalls) {
+	state := newConnectionState(config, channel, calls)
 
 	for {
 		msg := <-channel
@@ -198,14 +469,23 @@ func connectionStateMachine(config Configuration, channel chan msg) {
 			switch evt := msg.(type) {
 			case getConnection:
 				{
-					evt.channel <- connectionHandle{
-						err: fmt.Errorf("esdb connection is closed"),
-					}
+					evt.channel <- newErroredConnectionHandle(ErrClientClosed)
+				}
+			case getRoutes:
+				{
+					evt.channel <- nil
 				}
 			case closeConnection:
 				{
 					evt.channel <- true
 				}
+			case discoveryCompleted:
+				{
+					// The discovery was cancelled by Close but may have connected in the meantime.
+					if evt.connection != nil {
+						evt.connection.Close()
+					}
+				}
 			default:
 				// No-op
 			}
@@ -216,42 +496,77 @@ func connectionStateMachine(config Configuration, channel chan msg) {
 	}
 }
 
+// getRoutes asks for the connections of the routes currently connected.
+type getRoutes struct {
+	// Buffered so the state machine never waits on a caller that gave up.
+	channel chan []connectedRoute
+}
+
+// connectedRoute is the connection of a route along with the node preference it was discovered for.
+type connectedRoute struct {
+	preference NodePreference
+	handle     connectionHandle
+}
+
+func newGetRoutesMsg() getRoutes {
+	return getRoutes{channel: make(chan []connectedRoute, 1)}
+}
+
+func (msg getRoutes) handle(state *connectionState) {
+	var routes []connectedRoute
+	for _, route := range state.routes {
+		if route.correlation != uuid.Nil {
+			routes = append(routes, connectedRoute{
+				preference: route.preference,
+				handle:     newRoutedConnectionHandle(route.correlation, route.connection, route.preference),
+			})
+		}
+	}
+
+	msg.channel <- routes
+}
+
 type reconnect struct {
 	correlation uuid.UUID
 	endpoint    *EndPoint
 }
 
 func (msg reconnect) handle(state *connectionState) {
-	if msg.correlation == state.correlation {
-		if msg.endpoint == nil {
-			// Means that in the next iteration cycle, the discovery process will start.
-			state.correlation = uuid.Nil
-			log.Printf("[info] Starting a new discovery process")
-			return
-		}
-
-		log.Printf("[info] Connecting to leader node %s ...", msg.endpoint.String())
-		conn, err := createGrpcConnection(&state.config, msg.endpoint.String())
+	route := state.routeByCorrelation(msg.correlation)
+	if route == nil {
+		return
+	}
 
-		if err != nil {
-			log.Printf("[error] exception when connecting to suggested node %s", msg.endpoint.String())
-			state.correlation = uuid.Nil
-			return
-		}
+	if msg.endpoint == nil {
+		// Means that in the next iteration cycle, the discovery process will start.
+		route.correlation = uuid.Nil
+		state.config.logf("[info] Starting a new discovery process")
+		return
+	}
 
-		id, err := uuid.NewV4()
+	endpoint := state.config.translateAddress(*msg.endpoint)
+	state.config.logf("[info] Connecting to leader node %s ...", endpoint.String())
+	conn, err := createGrpcConnection(&state.config, endpoint.String())
 
-		if err != nil {
-			log.Printf("[error] exception when generating a correlation id after reconnected to %s : %v", msg.endpoint.String(), err)
-			state.correlation = uuid.Nil
-			return
-		}
+	if err != nil {
+		state.config.logf("[error] exception when connecting to suggested node %s", endpoint.String())
+		route.correlation = uuid.Nil
+		return
+	}
 
-		state.correlation = id
-		state.connection = conn
+	id, err := uuid.NewV4()
 
-		log.Printf("[info] Successfully connected to leader node %s", msg.endpoint.String())
+	if err != nil {
+		state.config.logf("[error] exception when generating a correlation id after reconnected to %s : %v", endpoint.String(), err)
+		conn.Close()
+		route.correlation = uuid.Nil
+		return
 	}
+
+	state.replaceConnection(route, conn)
+	route.correlation = id
+
+	state.config.logf("[info] Successfully connected to leader node %s", endpoint.String())
 }
 
 type closeConnection struct {
@@ -260,33 +575,68 @@ type closeConnection struct {
 
 func (msg closeConnection) handle(state *connectionState) {
 	state.closed = true
-	if state.connection != nil {
-		defer func() {
-			state.connection.Close()
-			state.connection = nil
-		}()
+
+	for _, route := range state.routes {
+		if route.cancelDiscovery != nil {
+			route.cancelDiscovery()
+			route.cancelDiscovery = nil
+		}
+		route.replyToWaiting(newErroredConnectionHandle(ErrClientClosed))
+
+		if route.connection != nil {
+			defer func(connection *grpc.ClientConn) {
+				connection.Close()
+			}(route.connection)
+			route.connection = nil
+		}
 	}
 
+	state.calls.close()
 	msg.channel <- true
 }
 
+// tlsConfig returns the TLS configuration of the connections to the nodes, authenticating with
+// the given client certificate rather than the one of the configuration when set.
+func (conf *Configuration) tlsConfig(certificate *tls.Certificate) *tls.Config {
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: conf.SkipCertificateVerification,
+		RootCAs:            conf.RootCAs,
+	}
+
+	if certificate != nil {
+		tlsConfig.Certificates = []tls.Certificate{*certificate}
+	} else if conf.ClientCertificateFile != "" || conf.ClientCertificate != nil {
+		// Loaded on every handshake so reconnections pick up rotated certificates.
+		tlsConfig.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return conf.clientCertificate()
+		}
+	}
+
+	return tlsConfig
+}
+
 func createGrpcConnection(conf *Configuration, address string) (*grpc.ClientConn, error) {
+	return createGrpcConnectionWithCertificate(conf, address, nil)
+}
+
+// createGrpcConnectionWithCertificate connects to address, authenticating with the given client
+// certificate rather than the one of the configuration when set.
+func createGrpcConnectionWithCertificate(conf *Configuration, address string, certificate *tls.Certificate) (*grpc.ClientConn, error) {
 	var opts []grpc.DialOption
 
 	if conf.DisableTLS {
+		if certificate != nil {
+			return nil, fmt.Errorf("client certificates require TLS")
+		}
+
 		opts = append(opts, grpc.WithInsecure())
 	} else {
-		opts = append(opts,
-			grpc.WithTransportCredentials(credentials.NewTLS(
-				&tls.Config{
-					InsecureSkipVerify: conf.SkipCertificateVerification,
-					RootCAs:            conf.RootCAs,
-				})))
+		opts = append(opts, grpc.WithTransportCredentials(credentials.NewTLS(conf.tlsConfig(certificate))))
 	}
 
-	opts = append(opts, grpc.WithPerRPCCredentials(basicAuth{
-		username: conf.Username,
-		password: conf.Password,
+	opts = append(opts, grpc.WithPerRPCCredentials(callCredentials{
+		provider:                 conf.credentialsProvider(),
+		requireTransportSecurity: conf.CredentialsRequireTLS,
 	}))
 
 	if conf.KeepAliveInterval >= 0 {
@@ -297,20 +647,61 @@ func createGrpcConnection(conf *Configuration, address string) (*grpc.ClientConn
 		}))
 	}
 
+	if conf.Dialer != nil {
+		opts = append(opts, grpc.WithContextDialer(conf.Dialer))
+	}
+
+	unaryInterceptors := conf.UnaryInterceptors
+	streamInterceptors := conf.StreamInterceptors
+	if conf.ConnectionName != "" {
+		opts = append(opts, grpc.WithUserAgent(fmt.Sprintf("EventStore-Client-Go (%s)", conf.ConnectionName)))
+		unaryInterceptors = append([]grpc.UnaryClientInterceptor{connectionNameUnaryInterceptor(conf.ConnectionName)}, unaryInterceptors...)
+		streamInterceptors = append([]grpc.StreamClientInterceptor{connectionNameStreamInterceptor(conf.ConnectionName)}, streamInterceptors...)
+	}
+
+	if len(unaryInterceptors) > 0 {
+		opts = append(opts, grpc.WithChainUnaryInterceptor(unaryInterceptors...))
+	}
+
+	if len(streamInterceptors) > 0 {
+		opts = append(opts, grpc.WithChainStreamInterceptor(streamInterceptors...))
+	}
+
+	opts = append(opts, conf.DialOptions...)
+
 	conn, err := grpc.Dial(address, opts...)
 	if err != nil {
-		return nil, fmt.Errorf("Failed to initialize connection to %+v. Reason: %v", conf, err)
+		return nil, fmt.Errorf("Failed to initialize connection to %s. Reason: %v", address, err)
 	}
 
 	return conn, nil
 }
 
+// connectionNameUnaryInterceptor and connectionNameStreamInterceptor send the connection name
+// with every call.
+func connectionNameUnaryInterceptor(name string) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return invoker(metadata.AppendToOutgoingContext(ctx, "connection-name", name), method, req, reply, cc, opts...)
+	}
+}
+
+func connectionNameStreamInterceptor(name string) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		return streamer(metadata.AppendToOutgoingContext(ctx, "connection-name", name), desc, cc, method, opts...)
+	}
+}
+
 type basicAuth struct {
 	username string
 	password string
 }
 
 func (b basicAuth) GetRequestMetadata(tx context.Context, in ...string) (map[string]string, error) {
+	// Without a login, the client is either anonymous or authenticated by its certificate.
+	if b.username == "" && b.password == "" {
+		return nil, nil
+	}
+
 	auth := b.username + ":" + b.password
 	enc := base64.StdEncoding.EncodeToString([]byte(auth))
 	return map[string]string{
@@ -318,174 +709,273 @@ func (b basicAuth) GetRequestMetadata(tx context.Context, in ...string) (map[str
 	}, nil
 }
 
-func (basicAuth) RequireTransportSecurity() bool {
-	return false
-}
-func allowedNodeState() []gossipApi.MemberInfo_VNodeState {
-	return []gossipApi.MemberInfo_VNodeState{
-		gossipApi.MemberInfo_Follower,
-		gossipApi.MemberInfo_Leader,
-		gossipApi.MemberInfo_ReadOnlyLeaderless,
-		gossipApi.MemberInfo_PreReadOnlyReplica,
-		gossipApi.MemberInfo_ReadOnlyReplica,
+// isAllowedNodeState tells if a client can connect to a node in the given state.
+func isAllowedNodeState(state VNodeState) bool {
+	switch state {
+	case VNodeState_Follower,
+		VNodeState_Leader,
+		VNodeState_ReadOnlyLeaderless,
+		VNodeState_PreReadOnlyReplica,
+		VNodeState_ReadOnlyReplica:
+		return true
+	default:
+		return false
 	}
 }
 
-func discoverNode(conf Configuration) (*grpc.ClientConn, error) {
-	var connection *grpc.ClientConn = nil
-	attempt := 1
+// discoverNode connects to the node picked by the configuration. It gives up when ctx is done.
+func discoverNode(ctx context.Context, conf Configuration) (*grpc.ClientConn, error) {
+	started := time.Now()
+	connection, attempts, err := connectToNode(ctx, conf)
+	metricsOf(&conf).DiscoveryCompleted(attempts, time.Since(started), err)
 
+	return connection, err
+}
+
+// connectToNode implements discoverNode and also returns the number of attempts made.
+func connectToNode(ctx context.Context, conf Configuration) (*grpc.ClientConn, int, error) {
 	if conf.DnsDiscover || len(conf.GossipSeeds) > 0 {
-		var candidates []string
+		for attempt := 1; attempt <= conf.MaxDiscoverAttempts; attempt++ {
+			conf.logf("[info] discovery attempt %v/%v", attempt, conf.MaxDiscoverAttempts)
 
-		if conf.DnsDiscover {
-			candidates = append(candidates, conf.Address)
-		} else {
-			for _, seed := range conf.GossipSeeds {
-				candidates = append(candidates, seed.String())
+			connection, err := discoverFromGroups(ctx, conf, gossipCandidates(ctx, &conf))
+			if err == nil {
+				return connection, attempt, nil
 			}
-		}
 
-		shuffleCandidates(candidates)
+			if ctx.Err() != nil {
+				return nil, attempt, ctx.Err()
+			}
 
-		for attempt <= conf.MaxDiscoverAttempts {
-			log.Printf("[info] discovery attempt %v/%v", attempt, conf.MaxDiscoverAttempts)
-			for _, candidate := range candidates {
-				log.Printf("[info] Attempting to gossip via %s", candidate)
-				connection, err := createGrpcConnection(&conf, candidate)
-				if err != nil {
-					log.Printf("[warn] Error when creating a grpc connection for candidate %s", candidate)
+			conf.logf("[warn] discovery attempt %v/%v failed: %v", attempt, conf.MaxDiscoverAttempts, err)
 
-					continue
+			if attempt < conf.MaxDiscoverAttempts {
+				if err := sleepWithContext(ctx, discoveryBackoff(&conf, attempt)); err != nil {
+					return nil, attempt, err
 				}
+			}
+		}
 
-				client := gossipApi.NewGossipClient(connection)
-				context, cancel := context.WithTimeout(context.Background(), time.Duration(conf.GossipTimeout)*time.Second)
-				defer cancel()
-				info, err := client.Read(context, &shared.Empty{})
+		return nil, conf.MaxDiscoverAttempts, fmt.Errorf("maximum discovery attempt count reached")
+	}
 
-				if err != nil {
-					log.Printf("[warn] Error when reading gossip from candidate %s: %v", candidate, err)
-					continue
-				}
+	var lastErr error
+	for attempt := 1; attempt <= conf.MaxDiscoverAttempts; attempt++ {
+		connection, err := createGrpcConnection(&conf, conf.Address)
+		if err == nil {
+			return connection, attempt, nil
+		}
 
-				info.Members = shuffleMembers(info.Members)
-				selected, err := pickBestCandidate(info, conf.NodePreference)
+		lastErr = err
+		conf.logf("[warn] error when creating a single node connection to %s: %v", conf.Address, err)
 
-				if err != nil {
-					log.Printf("[warn] Eror when picking best candidate out of %s gossip response: %v", candidate, err)
-					continue
-				}
+		if attempt < conf.MaxDiscoverAttempts {
+			if err := sleepWithContext(ctx, discoveryBackoff(&conf, attempt)); err != nil {
+				return nil, attempt, err
+			}
+		}
+	}
+
+	return nil, conf.MaxDiscoverAttempts, fmt.Errorf("unable to connect to single node %s: %w", conf.Address, lastErr)
+}
 
-				selectedAddress := fmt.Sprintf("%s:%d", selected.GetHttpEndPoint().GetAddress(), selected.GetHttpEndPoint().GetPort())
-				log.Printf("[info] Best candidate found. %s (%s)", selectedAddress, selected.State.String())
+// discoveryBackoff returns the delay before the next discovery attempt. It starts at
+// DiscoveryInterval and doubles on every attempt up to MaxDiscoveryInterval, with half of it
+// randomized so clients restarted together don't retry in lockstep.
+func discoveryBackoff(conf *Configuration, attempt int) time.Duration {
+	interval := time.Duration(conf.DiscoveryInterval) * time.Millisecond
+	maxInterval := time.Duration(conf.MaxDiscoveryInterval) * time.Millisecond
+	if maxInterval <= 0 {
+		maxInterval = 5 * time.Second
+	}
 
-				if candidate != selectedAddress {
-					connection, err = createGrpcConnection(&conf, selectedAddress)
+	if interval <= 0 {
+		return 0
+	}
 
-					if err != nil {
-						log.Printf("[warn] Error when creating gRPC connection for best candidate %v", err)
-						continue
-					}
-				}
+	delay := interval
+	for i := 1; i < attempt && delay < maxInterval; i++ {
+		delay *= 2
+	}
 
-				log.Printf("[info] Successfully connected to best candidate %s (%s)", selectedAddress, selected.State.String())
+	if delay > maxInterval {
+		delay = maxInterval
+	}
 
-				return connection, nil
-			}
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+type gossipProbe struct {
+	// The position of the candidate in its group.
+	index      int
+	candidate  gossipCandidate
+	connection *grpc.ClientConn
+	selected   *MemberInfo
+	err        error
+}
 
-			attempt += 1
-			time.Sleep(time.Duration(conf.DiscoveryInterval))
+// discoverFromGroups discovers from the groups of candidates in order, until one of them succeeds.
+func discoverFromGroups(ctx context.Context, conf Configuration, groups []candidateGroup) (*grpc.ClientConn, error) {
+	var lastErr error
+	for _, group := range groups {
+		connection, err := discoverFromSeeds(ctx, conf, group)
+		if err == nil {
+			return connection, nil
 		}
 
-		return nil, fmt.Errorf("maximum discovery attempt count reached")
+		if ctx.Err() != nil {
+			return nil, err
+		}
 
-	} else {
-		for attempt <= conf.MaxDiscoverAttempts {
-			grpcConn, err := createGrpcConnection(&conf, conf.Address)
+		lastErr = err
+	}
 
-			if err == nil {
-				connection = grpcConn
-				break
-			}
+	if lastErr == nil {
+		lastErr = fmt.Errorf("no gossip seed to discover from")
+	}
+
+	return nil, lastErr
+}
 
-			log.Printf("[warn] error when creating a single node connection to %s", conf.Address)
+// discoverFromSeeds reads the gossip of every candidate of the group concurrently. The first
+// candidate giving a usable member wins or, for ordered groups, the first one in order once the
+// candidates before it failed. The other probes are cancelled and their connections closed.
+func discoverFromSeeds(ctx context.Context, conf Configuration, group candidateGroup) (*grpc.ClientConn, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	candidates := group.candidates
+	probes := make(chan gossipProbe, len(candidates))
+	for i, candidate := range candidates {
+		go func(index int, candidate gossipCandidate) {
+			probe := probeGossipSeed(ctx, conf, candidate)
+			probe.index = index
+			probes <- probe
+		}(i, candidate)
+	}
 
-			attempt += 1
-			time.Sleep(time.Duration(conf.DiscoveryInterval))
+	results := make([]*gossipProbe, len(candidates))
+	next := 0
+	var lastErr error
+	for remaining := len(candidates); remaining > 0; remaining-- {
+		probe := <-probes
+		results[probe.index] = &probe
+		if probe.err != nil {
+			conf.logf("[warn] %v", probe.err)
+			lastErr = probe.err
 		}
 
-		if connection == nil {
-			return nil, fmt.Errorf("unable to connect to single node %s", conf.Address)
+		var winner *gossipProbe
+		if !group.ordered {
+			if probe.err == nil {
+				winner = &probe
+			}
+		} else {
+			for next < len(results) && results[next] != nil && results[next].err != nil {
+				next++
+			}
+
+			if next < len(results) && results[next] != nil {
+				winner = results[next]
+			}
 		}
-	}
 
-	return connection, nil
-}
+		if winner == nil {
+			continue
+		}
 
-func shuffleCandidates(src []string) []string {
-	rand.Seed(time.Now().UnixNano())
-	rand.Shuffle(len(src), func(i, j int) {
-		src[i], src[j] = src[j], src[i]
-	})
+		// Successful probes waiting for the candidates before them lost too.
+		for _, result := range results {
+			if result != nil && result != winner && result.connection != nil {
+				result.connection.Close()
+			}
+		}
 
-	return src
-}
+		// Probes still running are cancelled when returning, their connections are closed as they
+		// come back.
+		go func(remaining int) {
+			for ; remaining > 0; remaining-- {
+				if loser := <-probes; loser.connection != nil {
+					loser.connection.Close()
+				}
+			}
+		}(remaining - 1)
 
-func shuffleMembers(src []*gossipApi.MemberInfo) []*gossipApi.MemberInfo {
-	rand.Seed(time.Now().UnixNano())
-	rand.Shuffle(len(src), func(i, j int) {
-		src[i], src[j] = src[j], src[i]
-	})
+		selected := winner.selected
+		endpoint := conf.translateAddress(selected.HttpEndPoint)
+		selectedAddress := endpoint.String()
+		if advertised := selected.HttpEndPoint.String(); advertised != selectedAddress {
+			conf.logf("[info] Best candidate found. %s (%s), reachable at %s", advertised, selected.State.String(), selectedAddress)
+		} else {
+			conf.logf("[info] Best candidate found. %s (%s)", selectedAddress, selected.State.String())
+		}
 
-	return src
-}
+		connection := winner.connection
+		if winner.candidate.address != selectedAddress {
+			winner.connection.Close()
 
-func sortByState(members []*gossipApi.MemberInfo, nodeState gossipApi.MemberInfo_VNodeState) []*gossipApi.MemberInfo {
-	sorted := make([]*gossipApi.MemberInfo, 0)
-	for _, member := range members {
-		if member.State == nodeState {
-			sorted = append(sorted, member)
+			var err error
+			connection, err = createGrpcConnection(&conf, selectedAddress)
+			if err != nil {
+				return nil, fmt.Errorf("error when creating gRPC connection for best candidate %s: %v", selectedAddress, err)
+			}
 		}
+
+		conf.logf("[info] Successfully connected to best candidate %s (%s)", selectedAddress, selected.State.String())
+
+		return connection, nil
 	}
-	for _, member := range members {
-		if member.State != nodeState {
-			sorted = append(sorted, member)
-		}
+
+	if lastErr == nil {
+		lastErr = fmt.Errorf("no gossip seed to discover from")
 	}
-	return sorted
+
+	return nil, lastErr
 }
 
-func pickBestCandidate(response *gossipApi.ClusterInfo, nodePreference NodePreference) (*gossipApi.MemberInfo, error) {
-	if len(response.Members) == 0 {
-		return nil, fmt.Errorf("there are no members to determine the best candidate from")
+// probeGossipSeed reads the gossip of a candidate and picks the best member out of it. The
+// connection to the candidate is only kept if the probe succeeds.
+func probeGossipSeed(ctx context.Context, conf Configuration, candidate gossipCandidate) gossipProbe {
+	conf.logf("[info] Attempting to gossip via %s", candidate)
+
+	candidateConf := candidate.configure(conf)
+	connection, err := createGrpcConnection(&candidateConf, candidate.address)
+	if err != nil {
+		return gossipProbe{candidate: candidate, err: fmt.Errorf("error when creating a grpc connection for candidate %s: %v", candidate, err)}
+	}
+
+	client := gossipApi.NewGossipClient(connection)
+	gossipCtx, cancel := context.WithTimeout(ctx, time.Duration(conf.GossipTimeout)*time.Second)
+	info, err := client.Read(gossipCtx, &shared.Empty{})
+	cancel()
+
+	if err != nil {
+		connection.Close()
+		return gossipProbe{candidate: candidate, err: fmt.Errorf("error when reading gossip from candidate %s: %v", candidate, err)}
 	}
-	allowedMembers := make([]*gossipApi.MemberInfo, 0)
-	for _, member := range response.Members {
-		for _, allowedState := range allowedNodeState() {
-			if member.State == allowedState && member.GetIsAlive() {
-				allowedMembers = append(allowedMembers, member)
-			}
-		}
+
+	selected, err := selectNode(ctx, &conf, clusterInfoFromProto(info))
+	if err != nil {
+		connection.Close()
+		return gossipProbe{candidate: candidate, err: fmt.Errorf("error when picking best candidate out of %s gossip response: %v", candidate, err)}
 	}
-	if len(allowedMembers) == 0 {
-		return nil, fmt.Errorf("no nodes are eligable to be a candidate")
+
+	return gossipProbe{
+		candidate:  candidate,
+		connection: connection,
+		selected:   selected,
 	}
-	switch nodePreference {
-	case NodePreference_Leader:
-		{
-			allowedMembers = sortByState(allowedMembers, gossipApi.MemberInfo_Leader)
-		}
-	case NodePreference_Follower:
-		{
-			allowedMembers = sortByState(allowedMembers, gossipApi.MemberInfo_Follower)
-		}
-	case NodePreference_ReadOnlyReplica:
-		{
-			allowedMembers = sortByState(allowedMembers, gossipApi.MemberInfo_ReadOnlyReplica)
-			allowedMembers = sortByState(allowedMembers, gossipApi.MemberInfo_PreReadOnlyReplica)
-			allowedMembers = sortByState(allowedMembers, gossipApi.MemberInfo_ReadOnlyLeaderless)
-		}
+}
+
+func sleepWithContext(ctx context.Context, duration time.Duration) error {
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
 	}
-	return allowedMembers[0], nil
 }
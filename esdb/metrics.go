@@ -0,0 +1,157 @@
+package esdb
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"google.golang.org/grpc/status"
+)
+
+// Metrics receives measurements of the client. Every measurement comes with the ConnectionName of
+// the client, so several clients can share a Metrics. Implementations must be safe for concurrent
+// use and shouldn't block, they are called on the hot path of operations and subscriptions. See
+// PrometheusMetrics for a ready to use implementation.
+type Metrics interface {
+	// OperationCompleted is called once per Client operation, err is nil when it succeeded. Use
+	// ErrorType to classify err.
+	OperationCompleted(connectionName string, operation string, duration time.Duration, err error)
+	// BytesAppended is called after a successful append with the size of the data and metadata of
+	// the appended events.
+	BytesAppended(connectionName string, bytes int)
+	// BytesRead is called for every event read or delivered by a subscription with the size of its
+	// data and metadata.
+	BytesRead(connectionName string, bytes int)
+	// DiscoveryCompleted is called at the end of every discovery, err is nil when it succeeded.
+	DiscoveryCompleted(connectionName string, attempts int, duration time.Duration, err error)
+	// Reconnected is called when a failed operation triggers a new discovery. reason is "NotLeader"
+	// for leader redirects, the ErrorType of the failure otherwise.
+	Reconnected(connectionName string, reason string)
+	// SubscriptionStarted and SubscriptionStopped are called when a subscription, catch-up or
+	// persistent, is confirmed by the server and when it is dropped or closed.
+	SubscriptionStarted(connectionName string, subscriptionId string)
+	SubscriptionStopped(connectionName string, subscriptionId string, reason SubscriptionDropReason)
+	// EventDelivered is called for every event delivered by a subscription.
+	EventDelivered(connectionName string, subscriptionId string)
+}
+
+// clientMetrics reports the measurements of a client to its Metrics, if any.
+type clientMetrics struct {
+	metrics        Metrics
+	connectionName string
+}
+
+// metricsOf returns the metrics of a configuration, which may be nil.
+func metricsOf(conf *Configuration) clientMetrics {
+	if conf == nil {
+		return clientMetrics{}
+	}
+
+	return clientMetrics{metrics: conf.Metrics, connectionName: conf.ConnectionName}
+}
+
+func (client clientMetrics) OperationCompleted(operation string, duration time.Duration, err error) {
+	if client.metrics != nil {
+		client.metrics.OperationCompleted(client.connectionName, operation, duration, err)
+	}
+}
+
+func (client clientMetrics) BytesAppended(bytes int) {
+	if client.metrics != nil {
+		client.metrics.BytesAppended(client.connectionName, bytes)
+	}
+}
+
+func (client clientMetrics) BytesRead(bytes int) {
+	if client.metrics != nil {
+		client.metrics.BytesRead(client.connectionName, bytes)
+	}
+}
+
+func (client clientMetrics) DiscoveryCompleted(attempts int, duration time.Duration, err error) {
+	if client.metrics != nil {
+		client.metrics.DiscoveryCompleted(client.connectionName, attempts, duration, err)
+	}
+}
+
+func (client clientMetrics) Reconnected(reason string) {
+	if client.metrics != nil {
+		client.metrics.Reconnected(client.connectionName, reason)
+	}
+}
+
+func (client clientMetrics) SubscriptionStarted(subscriptionId string) {
+	if client.metrics != nil {
+		client.metrics.SubscriptionStarted(client.connectionName, subscriptionId)
+	}
+}
+
+func (client clientMetrics) SubscriptionStopped(subscriptionId string, reason SubscriptionDropReason) {
+	if client.metrics != nil {
+		client.metrics.SubscriptionStopped(client.connectionName, subscriptionId, reason)
+	}
+}
+
+func (client clientMetrics) EventDelivered(subscriptionId string) {
+	if client.metrics != nil {
+		client.metrics.EventDelivered(client.connectionName, subscriptionId)
+	}
+}
+
+// ErrorType classifies an error returned by the client, to be used as a metric label. It returns ""
+// for nil, the name of the matching typed error when there is one, the gRPC status code when the
+// error comes from the server and "Unknown" otherwise.
+func ErrorType(err error) string {
+	if err == nil {
+		return ""
+	}
+
+	var deadlineExceeded *DeadlineExceededError
+	var streamDeleted *StreamDeletedError
+	var persistentSubscriptionDeleted *PersistentSubscriptionDeletedError
+	var validation *ValidationError
+	var grpcError interface{ GRPCStatus() *status.Status }
+
+	switch {
+	case errors.As(err, &deadlineExceeded), errors.Is(err, context.DeadlineExceeded):
+		return "DeadlineExceeded"
+	case errors.Is(err, context.Canceled):
+		return "Canceled"
+	case errors.Is(err, ErrWrongExpectedStreamRevision):
+		return "WrongExpectedStreamRevision"
+	case errors.Is(err, ErrPermissionDenied):
+		return "PermissionDenied"
+	case errors.Is(err, ErrUnauthenticated):
+		return "Unauthenticated"
+	case errors.Is(err, ErrAlreadyExists):
+		return "AlreadyExists"
+	case errors.Is(err, ErrStreamNotFound):
+		return "StreamNotFound"
+	case errors.Is(err, ErrClientClosed):
+		return "ClientClosed"
+	case errors.Is(err, ErrNodeNotReady):
+		return "NodeNotReady"
+	case errors.As(err, &streamDeleted):
+		return "StreamDeleted"
+	case errors.As(err, &persistentSubscriptionDeleted):
+		return "PersistentSubscriptionDeleted"
+	case errors.As(err, &validation):
+		return "Validation"
+	case errors.As(err, &grpcError):
+		return grpcError.GRPCStatus().Code().String()
+	default:
+		return "Unknown"
+	}
+}
+
+// eventSize is the size of the data and metadata of an event, as counted by Metrics.
+func eventSize(event *ResolvedEvent) int {
+	size := 0
+	for _, recorded := range []*RecordedEvent{event.Event, event.Link} {
+		if recorded != nil {
+			size += len(recorded.Data) + len(recorded.UserMetadata)
+		}
+	}
+
+	return size
+}
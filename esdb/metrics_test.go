@@ -0,0 +1,160 @@
+package esdb_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/EventStore/EventStore-Client-Go/esdb"
+	api "github.com/EventStore/EventStore-Client-Go/protos/streams"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func createMeasuredClient(t *testing.T, streams api.StreamsServer, metrics esdb.Metrics) *esdb.Client {
+	address := startFakeServer(t, func(server *grpc.Server) {
+		api.RegisterStreamsServer(server, streams)
+	})
+
+	config, err := esdb.ParseConnectionString("esdb://" + address + "?tls=false")
+	require.NoError(t, err)
+	config.ConnectionName = "orders-service"
+	config.Metrics = metrics
+
+	client, err := esdb.NewClient(config)
+	require.NoError(t, err)
+	t.Cleanup(func() { client.Close() })
+
+	return client
+}
+
+func scrape(t *testing.T, metrics *esdb.PrometheusMetrics) string {
+	server := httptest.NewServer(metrics)
+	defer server.Close()
+
+	response, err := server.Client().Get(server.URL)
+	require.NoError(t, err)
+	defer response.Body.Close()
+
+	assert.Equal(t, "text/plain; version=0.0.4; charset=utf-8", response.Header.Get("Content-Type"))
+	body, err := ioutil.ReadAll(response.Body)
+	require.NoError(t, err)
+
+	return string(body)
+}
+
+func TestPrometheusMetricsMeasuresOperationsAndSubscriptions(t *testing.T) {
+	metrics := esdb.NewPrometheusMetrics(0.5, 10)
+	appended := &appendedMetadata{}
+	client := createMeasuredClient(t, appended.server(), metrics)
+
+	_, err := client.AppendToStream(context.Background(), "orders", esdb.AppendToStreamOptions{},
+		esdb.EventData{EventType: "OrderPlaced", ContentType: esdb.BinaryContentType, Data: []byte("12345"), Metadata: []byte("678")})
+	require.NoError(t, err)
+
+	// The fake server doesn't implement deletes.
+	_, err = client.DeleteStream(context.Background(), "orders", esdb.DeleteStreamOptions{})
+	require.Error(t, err)
+
+	subscription, err := client.SubscribeToStream(context.Background(), "orders", esdb.SubscribeToStreamOptions{})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	event, err := subscription.RecvContext(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, event.EventAppeared)
+
+	exposed := scrape(t, metrics)
+	for _, line := range []string{
+		`esdb_operation_duration_seconds_bucket{connection="orders-service",operation="AppendToStream",le="10"} 1`,
+		`esdb_operation_duration_seconds_bucket{connection="orders-service",operation="AppendToStream",le="+Inf"} 1`,
+		`esdb_operation_duration_seconds_count{connection="orders-service",operation="DeleteStream"} 1`,
+		`esdb_operation_duration_seconds_count{connection="orders-service",operation="SubscribeToStream"} 1`,
+		`esdb_operation_errors_total{connection="orders-service",operation="DeleteStream",error="Unimplemented"} 1`,
+		`esdb_appended_bytes_total{connection="orders-service"} 8`,
+		`esdb_read_bytes_total{connection="orders-service"} 3`,
+		// The initial discovery and the one triggered by the failed delete.
+		`esdb_discovery_attempts_total{connection="orders-service",result="success"} 2`,
+		`esdb_discovery_duration_seconds_count{connection="orders-service",result="success"} 2`,
+		`esdb_reconnects_total{connection="orders-service",reason="Unimplemented"} 1`,
+		`esdb_active_subscriptions{connection="orders-service"} 1`,
+		`esdb_subscription_events_delivered_total{connection="orders-service",subscription="fake"} 1`,
+		`# TYPE esdb_operation_duration_seconds histogram`,
+	} {
+		assert.Contains(t, exposed, line+"\n")
+	}
+	assert.NotContains(t, exposed, `esdb_operation_errors_total{connection="orders-service",operation="AppendToStream"`)
+
+	subscription.Close()
+	assert.Eventually(t, func() bool {
+		exposed := scrape(t, metrics)
+		return strings.Contains(exposed, `esdb_active_subscriptions{connection="orders-service"} 0`+"\n") &&
+			!strings.Contains(exposed, `esdb_subscription_events_delivered_total{connection="orders-service",subscription="fake"}`)
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestPrometheusMetricsEscapesLabels(t *testing.T) {
+	metrics := esdb.NewPrometheusMetrics()
+	metrics.SubscriptionStarted("orders\nservice", "orders::\"group\"\\1")
+
+	var exposed strings.Builder
+	_, err := metrics.WriteTo(&exposed)
+	require.NoError(t, err)
+	assert.Contains(t, exposed.String(), `esdb_subscription_events_delivered_total{connection="orders\nservice",subscription="orders::\"group\"\\1"} 0`+"\n")
+}
+
+func TestErrorType(t *testing.T) {
+	tests := []struct {
+		err      error
+		expected string
+	}{
+		{nil, ""},
+		{&esdb.DeadlineExceededError{Operation: "DeleteStream", Err: context.DeadlineExceeded}, "DeadlineExceeded"},
+		{fmt.Errorf("wrapped: %w", context.Canceled), "Canceled"},
+		{fmt.Errorf("%w, reason: conflict", esdb.ErrWrongExpectedStreamRevision), "WrongExpectedStreamRevision"},
+		{esdb.ErrPermissionDenied, "PermissionDenied"},
+		{esdb.ErrUnauthenticated, "Unauthenticated"},
+		{esdb.ErrAlreadyExists, "AlreadyExists"},
+		{esdb.ErrStreamNotFound, "StreamNotFound"},
+		{esdb.ErrClientClosed, "ClientClosed"},
+		{&esdb.StreamDeletedError{StreamName: "orders"}, "StreamDeleted"},
+		{&esdb.PersistentSubscriptionDeletedError{StreamName: "orders", GroupName: "group"}, "PersistentSubscriptionDeleted"},
+		{fmt.Errorf("failed: %w", status.Error(codes.Unavailable, "gone")), "Unavailable"},
+		{errors.New("boom"), "Unknown"},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, esdb.ErrorType(test.err), fmt.Sprint(test.err))
+	}
+}
+
+func TestTimedOutOperationsDoNotReconnect(t *testing.T) {
+	metrics := esdb.NewPrometheusMetrics(0.5, 10)
+	client := createMeasuredClient(t, hangingServer(0), metrics)
+
+	_, err := client.DeleteStream(context.Background(), "orders", esdb.DeleteStreamOptions{Deadline: 50 * time.Millisecond})
+	assertDeadlineExceeded(t, err, "DeleteStream", 50*time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	_, err = client.DeleteStream(ctx, "orders", esdb.DeleteStreamOptions{})
+	require.Error(t, err)
+
+	// The fake server doesn't implement appends, which resets the connection.
+	_, err = client.AppendToStream(context.Background(), "orders", esdb.AppendToStreamOptions{},
+		esdb.EventData{EventType: "OrderPlaced", ContentType: esdb.BinaryContentType, Data: []byte("{}")})
+	require.Error(t, err)
+
+	exposed := scrape(t, metrics)
+	assert.Contains(t, exposed, `esdb_reconnects_total{connection="orders-service",reason="Unimplemented"} 1`)
+	assert.Equal(t, 1, strings.Count(exposed, "esdb_reconnects_total{"), exposed)
+}
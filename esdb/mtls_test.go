@@ -0,0 +1,207 @@
+package esdb_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/EventStore/EventStore-Client-Go/esdb"
+	api "github.com/EventStore/EventStore-Client-Go/protos/streams"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// testAuthority issues the certificates of the mTLS tests.
+type testAuthority struct {
+	certificate *x509.Certificate
+	key         *ecdsa.PrivateKey
+	pool        *x509.CertPool
+}
+
+func newTestAuthority(t *testing.T) *testAuthority {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	certificate, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(certificate)
+
+	return &testAuthority{certificate: certificate, key: key, pool: pool}
+}
+
+// issue returns the PEM encoded certificate and key of a leaf certificate for the given name.
+func (authority *testAuthority) issue(t *testing.T, name string, usage x509.ExtKeyUsage) ([]byte, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, authority.certificate, &key.PublicKey, authority.key)
+	require.NoError(t, err)
+
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})
+}
+
+func (authority *testAuthority) writeClientCertificate(t *testing.T, dir string, name string) (string, string) {
+	certificate, key := authority.issue(t, name, x509.ExtKeyUsageClientAuth)
+	certFile, keyFile := filepath.Join(dir, "user.crt"), filepath.Join(dir, "user.key")
+	require.NoError(t, ioutil.WriteFile(certFile, certificate, 0600))
+	require.NoError(t, ioutil.WriteFile(keyFile, key, 0600))
+	return certFile, keyFile
+}
+
+// startMutualTLSServer requires a client certificate issued by the authority and answers reads
+// with an event from a stream named after the certificate. Deletes fail as if the node became
+// unavailable, which makes the client reconnect.
+func startMutualTLSServer(t *testing.T, authority *testAuthority) string {
+	certificate, key := authority.issue(t, "node", x509.ExtKeyUsageServerAuth)
+	serverCertificate, err := tls.X509KeyPair(certificate, key)
+	require.NoError(t, err)
+
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{serverCertificate},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    authority.pool,
+	}
+
+	streams := &fakeStreamsServer{
+		read: func(req *api.ReadReq, server api.Streams_ReadServer) error {
+			client, _ := peer.FromContext(server.Context())
+			tlsInfo := client.AuthInfo.(credentials.TLSInfo)
+			return server.Send(fakeReadEvent(tlsInfo.State.PeerCertificates[0].Subject.CommonName, 0))
+		},
+		delete: func(ctx context.Context, req *api.DeleteReq) (*api.DeleteResp, error) {
+			return nil, status.Error(codes.Unavailable, "node unavailable")
+		},
+	}
+
+	return startFakeServer(t, func(server *grpc.Server) {
+		api.RegisterStreamsServer(server, streams)
+	}, grpc.Creds(credentials.NewTLS(tlsConfig)))
+}
+
+func authenticatedAs(t *testing.T, client *esdb.Client, auth *esdb.Credentials) string {
+	stream, err := client.ReadStream(context.Background(), "orders", esdb.ReadStreamOptions{Authenticated: auth}, 1)
+	require.NoError(t, err)
+	defer stream.Close()
+
+	event, err := stream.Recv()
+	require.NoError(t, err)
+
+	return event.OriginalEvent().StreamID
+}
+
+func TestClientCertificateFilesAreReloadedOnReconnection(t *testing.T) {
+	authority := newTestAuthority(t)
+	address := startMutualTLSServer(t, authority)
+	certFile, keyFile := authority.writeClientCertificate(t, t.TempDir(), "admin")
+
+	config, err := esdb.ParseConnectionString("esdb://" + address + "?userCertFile=" + certFile + "&userKeyFile=" + keyFile)
+	require.NoError(t, err)
+	config.RootCAs = authority.pool
+
+	client, err := esdb.NewClient(config)
+	require.NoError(t, err)
+	defer client.Close()
+
+	assert.Equal(t, "admin", authenticatedAs(t, client, nil))
+
+	// Rotate the certificate, then make the client reconnect.
+	authority.writeClientCertificate(t, filepath.Dir(certFile), "rotated")
+	_, err = client.DeleteStream(context.Background(), "orders", esdb.DeleteStreamOptions{})
+	require.Error(t, err)
+
+	assert.Equal(t, "rotated", authenticatedAs(t, client, nil))
+}
+
+func TestPerOperationClientCertificate(t *testing.T) {
+	authority := newTestAuthority(t)
+	address := startMutualTLSServer(t, authority)
+
+	certificate, key := authority.issue(t, "admin", x509.ExtKeyUsageClientAuth)
+	config, err := esdb.ParseConnectionString("esdb://" + address)
+	require.NoError(t, err)
+	config.RootCAs = authority.pool
+	config.ClientCertificate = certificate
+	config.ClientKey = key
+
+	client, err := esdb.NewClient(config)
+	require.NoError(t, err)
+	defer client.Close()
+
+	operatorCertificate, operatorKey := authority.issue(t, "operator", x509.ExtKeyUsageClientAuth)
+	operator, err := tls.X509KeyPair(operatorCertificate, operatorKey)
+	require.NoError(t, err)
+
+	assert.Equal(t, "operator", authenticatedAs(t, client, &esdb.Credentials{Certificate: &operator}))
+	assert.Equal(t, "admin", authenticatedAs(t, client, nil))
+	assert.Equal(t, "operator", authenticatedAs(t, client, &esdb.Credentials{Certificate: &operator}))
+}
+
+func TestConnectionStringWithClientCertificateFiles(t *testing.T) {
+	authority := newTestAuthority(t)
+	certFile, keyFile := authority.writeClientCertificate(t, t.TempDir(), "admin")
+
+	config, err := esdb.ParseConnectionString("esdb://localhost?userCertFile=" + certFile + "&userKeyFile=" + keyFile)
+	require.NoError(t, err)
+	assert.Equal(t, certFile, config.ClientCertificateFile)
+	assert.Equal(t, keyFile, config.ClientKeyFile)
+
+	config, err = esdb.ParseConnectionString("esdb://localhost?userCertFile=" + certFile)
+	require.Error(t, err)
+	assert.Nil(t, config)
+	assert.Contains(t, err.Error(), "must be specified together")
+
+	config, err = esdb.ParseConnectionString("esdb://localhost?tls=false&userCertFile=" + certFile + "&userKeyFile=" + keyFile)
+	require.Error(t, err)
+	assert.Nil(t, config)
+	assert.Contains(t, err.Error(), "require TLS")
+
+	config, err = esdb.ParseConnectionString("esdb://localhost?userCertFile=" + certFile + "&userKeyFile=" + certFile)
+	require.Error(t, err)
+	assert.Nil(t, config)
+	assert.Contains(t, err.Error(), "failed to load the client certificate")
+}
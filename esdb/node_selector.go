@@ -0,0 +1,224 @@
+package esdb
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net"
+	"sort"
+	"sync"
+	"time"
+)
+
+// NodeCandidate is a cluster member the client may connect to.
+type NodeCandidate struct {
+	Member MemberInfo
+	// Time needed to open a TCP connection to the member. Only measured for selectors implementing
+	// LatencyAwareNodeSelector, 0 when not measured or when the member couldn't be reached.
+	RTT time.Duration
+}
+
+// NodeSelector picks the node to connect to during discovery. Candidates are the alive members in
+// a state a client can connect to, in random order. The client connects to the first member
+// returned, an empty result fails the discovery attempt.
+type NodeSelector interface {
+	SelectNodes(candidates []NodeCandidate) []MemberInfo
+}
+
+// LatencyAwareNodeSelector is implemented by selectors that need the RTT of the candidates.
+type LatencyAwareNodeSelector interface {
+	NodeSelector
+	RequiresLatency() bool
+}
+
+// LeaderNodeSelector prefers the leader. It's the selector used for NodePreference_Leader.
+type LeaderNodeSelector struct{}
+
+func (LeaderNodeSelector) SelectNodes(candidates []NodeCandidate) []MemberInfo {
+	return orderByStates(candidates, VNodeState_Leader)
+}
+
+// FollowerNodeSelector prefers followers. It's the selector used for NodePreference_Follower.
+type FollowerNodeSelector struct{}
+
+func (FollowerNodeSelector) SelectNodes(candidates []NodeCandidate) []MemberInfo {
+	return orderByStates(candidates, VNodeState_Follower)
+}
+
+// ReadOnlyReplicaNodeSelector prefers read-only replicas. It's the selector used for
+// NodePreference_ReadOnlyReplica.
+type ReadOnlyReplicaNodeSelector struct{}
+
+func (ReadOnlyReplicaNodeSelector) SelectNodes(candidates []NodeCandidate) []MemberInfo {
+	return orderByStates(candidates, VNodeState_ReadOnlyLeaderless, VNodeState_PreReadOnlyReplica, VNodeState_ReadOnlyReplica)
+}
+
+// RandomNodeSelector picks any candidate. It's the selector used for NodePreference_Random.
+type RandomNodeSelector struct{}
+
+func (RandomNodeSelector) SelectNodes(candidates []NodeCandidate) []MemberInfo {
+	return orderByStates(candidates)
+}
+
+// LatencyNodeSelector prefers the candidates with the lowest RTT. When Preference is set, the
+// candidates matching it come first, each group being ordered by RTT.
+type LatencyNodeSelector struct {
+	Preference NodePreference
+}
+
+func (LatencyNodeSelector) RequiresLatency() bool {
+	return true
+}
+
+func (selector LatencyNodeSelector) SelectNodes(candidates []NodeCandidate) []MemberInfo {
+	sorted := make([]NodeCandidate, len(candidates))
+	copy(sorted, candidates)
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		left, right := sorted[i], sorted[j]
+		leftPreferred, rightPreferred := matchesPreference(left.Member.State, selector.Preference), matchesPreference(right.Member.State, selector.Preference)
+		if leftPreferred != rightPreferred {
+			return leftPreferred
+		}
+
+		// Unreachable candidates go last.
+		if left.RTT == 0 || right.RTT == 0 {
+			return right.RTT == 0 && left.RTT != 0
+		}
+
+		return left.RTT < right.RTT
+	})
+
+	members := make([]MemberInfo, len(sorted))
+	for i, candidate := range sorted {
+		members[i] = candidate.Member
+	}
+
+	return members
+}
+
+func matchesPreference(state VNodeState, preference NodePreference) bool {
+	switch preference {
+	case NodePreference_Leader:
+		return state == VNodeState_Leader
+	case NodePreference_Follower:
+		return state == VNodeState_Follower
+	case NodePreference_ReadOnlyReplica:
+		return state == VNodeState_ReadOnlyReplica || state == VNodeState_PreReadOnlyReplica || state == VNodeState_ReadOnlyLeaderless
+	default:
+		return true
+	}
+}
+
+// orderByStates moves the candidates in the given states first, the first state being the most
+// preferred, while keeping the order of the candidates otherwise.
+func orderByStates(candidates []NodeCandidate, states ...VNodeState) []MemberInfo {
+	members := make([]MemberInfo, len(candidates))
+	for i, candidate := range candidates {
+		members[i] = candidate.Member
+	}
+
+	rank := func(state VNodeState) int {
+		for i, preferred := range states {
+			if state == preferred {
+				return i
+			}
+		}
+
+		return len(states)
+	}
+
+	sort.SliceStable(members, func(i, j int) bool {
+		return rank(members[i].State) < rank(members[j].State)
+	})
+
+	return members
+}
+
+// nodeSelector returns the selector of the configuration, falling back on the one matching the
+// node preference.
+func nodeSelector(conf *Configuration) NodeSelector {
+	if conf.NodeSelector != nil {
+		return conf.NodeSelector
+	}
+
+	switch conf.NodePreference {
+	case NodePreference_Follower:
+		return FollowerNodeSelector{}
+	case NodePreference_ReadOnlyReplica:
+		return ReadOnlyReplicaNodeSelector{}
+	case NodePreference_Random:
+		return RandomNodeSelector{}
+	default:
+		return LeaderNodeSelector{}
+	}
+}
+
+// selectNode picks the member to connect to out of a gossip response.
+func selectNode(ctx context.Context, conf *Configuration, info *ClusterInfo) (*MemberInfo, error) {
+	if len(info.Members) == 0 {
+		return nil, fmt.Errorf("there are no members to determine the best candidate from")
+	}
+
+	candidates := make([]NodeCandidate, 0, len(info.Members))
+	for _, member := range info.Members {
+		if member.IsAlive && isAllowedNodeState(member.State) {
+			candidates = append(candidates, NodeCandidate{Member: member})
+		}
+	}
+
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("no nodes are eligable to be a candidate")
+	}
+
+	rand.Seed(time.Now().UnixNano())
+	rand.Shuffle(len(candidates), func(i, j int) {
+		candidates[i], candidates[j] = candidates[j], candidates[i]
+	})
+
+	selector := nodeSelector(conf)
+	if latencyAware, ok := selector.(LatencyAwareNodeSelector); ok && latencyAware.RequiresLatency() {
+		measureLatencies(ctx, conf, candidates)
+	}
+
+	selected := selector.SelectNodes(candidates)
+	if len(selected) == 0 {
+		return nil, fmt.Errorf("the node selector didn't select any of the %d candidates", len(candidates))
+	}
+
+	return &selected[0], nil
+}
+
+// measureLatencies times a TCP connection to every candidate concurrently.
+func measureLatencies(ctx context.Context, conf *Configuration, candidates []NodeCandidate) {
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(conf.GossipTimeout)*time.Second)
+	defer cancel()
+
+	dial := conf.Dialer
+	if dial == nil {
+		var dialer net.Dialer
+		dial = func(ctx context.Context, address string) (net.Conn, error) {
+			return dialer.DialContext(ctx, "tcp", address)
+		}
+	}
+
+	var wait sync.WaitGroup
+	for i := range candidates {
+		wait.Add(1)
+		go func(candidate *NodeCandidate) {
+			defer wait.Done()
+
+			started := time.Now()
+			endpoint := conf.translateAddress(candidate.Member.HttpEndPoint)
+			conn, err := dial(ctx, endpoint.String())
+			if err != nil {
+				return
+			}
+
+			candidate.RTT = time.Since(started)
+			conn.Close()
+		}(&candidates[i])
+	}
+
+	wait.Wait()
+}
@@ -0,0 +1,117 @@
+package esdb_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/EventStore/EventStore-Client-Go/esdb"
+	gossipApi "github.com/EventStore/EventStore-Client-Go/protos/gossip"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func candidate(host string, state esdb.VNodeState, rtt time.Duration) esdb.NodeCandidate {
+	return esdb.NodeCandidate{
+		Member: esdb.MemberInfo{
+			State:        state,
+			IsAlive:      true,
+			HttpEndPoint: esdb.EndPoint{Host: host, Port: 2113},
+		},
+		RTT: rtt,
+	}
+}
+
+func hosts(members []esdb.MemberInfo) []string {
+	result := make([]string, len(members))
+	for i, member := range members {
+		result[i] = member.HttpEndPoint.Host
+	}
+
+	return result
+}
+
+func TestBuiltInNodeSelectors(t *testing.T) {
+	candidates := []esdb.NodeCandidate{
+		candidate("follower-1", esdb.VNodeState_Follower, 0),
+		candidate("replica", esdb.VNodeState_ReadOnlyReplica, 0),
+		candidate("leader", esdb.VNodeState_Leader, 0),
+		candidate("follower-2", esdb.VNodeState_Follower, 0),
+	}
+
+	assert.Equal(t, []string{"leader", "follower-1", "replica", "follower-2"}, hosts(esdb.LeaderNodeSelector{}.SelectNodes(candidates)))
+	assert.Equal(t, []string{"follower-1", "follower-2", "replica", "leader"}, hosts(esdb.FollowerNodeSelector{}.SelectNodes(candidates)))
+	assert.Equal(t, []string{"replica", "follower-1", "leader", "follower-2"}, hosts(esdb.ReadOnlyReplicaNodeSelector{}.SelectNodes(candidates)))
+	assert.Equal(t, []string{"follower-1", "replica", "leader", "follower-2"}, hosts(esdb.RandomNodeSelector{}.SelectNodes(candidates)))
+}
+
+func TestLatencyNodeSelector(t *testing.T) {
+	candidates := []esdb.NodeCandidate{
+		candidate("far", esdb.VNodeState_Follower, 30*time.Millisecond),
+		candidate("unreachable", esdb.VNodeState_Follower, 0),
+		candidate("leader", esdb.VNodeState_Leader, 20*time.Millisecond),
+		candidate("near", esdb.VNodeState_Follower, time.Millisecond),
+	}
+
+	assert.Equal(t, []string{"near", "leader", "far", "unreachable"}, hosts(esdb.LatencyNodeSelector{}.SelectNodes(candidates)))
+	assert.Equal(t, []string{"near", "far", "unreachable", "leader"}, hosts(esdb.LatencyNodeSelector{Preference: esdb.NodePreference_Follower}.SelectNodes(candidates)))
+}
+
+// recordingSelector picks the member listening on a given address and records what it was given.
+type recordingSelector struct {
+	lock       sync.Mutex
+	address    string
+	candidates []esdb.NodeCandidate
+}
+
+func (selector *recordingSelector) RequiresLatency() bool {
+	return true
+}
+
+func (selector *recordingSelector) SelectNodes(candidates []esdb.NodeCandidate) []esdb.MemberInfo {
+	selector.lock.Lock()
+	defer selector.lock.Unlock()
+	selector.candidates = candidates
+
+	for _, candidate := range candidates {
+		if candidate.Member.HttpEndPoint.String() == selector.address {
+			return []esdb.MemberInfo{candidate.Member}
+		}
+	}
+
+	return nil
+}
+
+func TestDiscoveryUsesConfiguredNodeSelector(t *testing.T) {
+	gossip := &mutableGossip{}
+	first := namedNode(t, "first", gossip)
+	second := namedNode(t, "second", gossip)
+	gossip.set(
+		fakeMember(first, gossipApi.MemberInfo_Leader),
+		fakeMember(second, gossipApi.MemberInfo_Follower),
+		fakeMember("127.0.0.1:1", gossipApi.MemberInfo_ShuttingDown),
+	)
+
+	config, err := esdb.ParseConnectionString("esdb+discover://" + first + "?tls=false")
+	require.NoError(t, err)
+
+	selector := &recordingSelector{address: second}
+	config.NodeSelector = selector
+
+	client, err := esdb.NewClient(config)
+	require.NoError(t, err)
+	defer client.Close()
+
+	assert.Equal(t, "second", servedBy(t, client))
+
+	selector.lock.Lock()
+	defer selector.lock.Unlock()
+
+	// The member shutting down isn't a candidate, and both nodes were reachable.
+	require.Len(t, selector.candidates, 2)
+	for _, candidate := range selector.candidates {
+		assert.NotZero(t, candidate.RTT)
+	}
+}
+
+var _ esdb.LatencyAwareNodeSelector = (*recordingSelector)(nil)
@@ -0,0 +1,350 @@
+package esdb
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// PartitionKey maps an event to the key of the partition it belongs to. Events sharing a key are
+// handled one at a time, in the order they were received.
+type PartitionKey func(event *ResolvedEvent) string
+
+// PartitionByCorrelationId uses the $correlationId property of the event JSON metadata, falling
+// back on the stream the event belongs to when there is none.
+func PartitionByCorrelationId(event *ResolvedEvent) string {
+	recorded := partitionedEvent(event)
+
+	if len(recorded.UserMetadata) > 0 {
+		var metadata struct {
+			CorrelationId string `json:"$correlationId"`
+		}
+
+		if err := json.Unmarshal(recorded.UserMetadata, &metadata); err == nil && metadata.CorrelationId != "" {
+			return metadata.CorrelationId
+		}
+	}
+
+	return recorded.StreamID
+}
+
+// PartitionByStream uses the stream the event belongs to. When the event was resolved from a link,
+// the stream of the resolved event is used.
+func PartitionByStream(event *ResolvedEvent) string {
+	return partitionedEvent(event).StreamID
+}
+
+func partitionedEvent(event *ResolvedEvent) *RecordedEvent {
+	if event.Event != nil {
+		return event.Event
+	}
+
+	return event.Link
+}
+
+// PartitionedHandler handles a single event. Returning an error retries the event locally, so
+// later events of the same partition never overtake it.
+type PartitionedHandler func(ctx context.Context, event *ResolvedEvent) error
+
+type PartitionedConsumerOptions struct {
+	// Defaults to PartitionByCorrelationId.
+	PartitionKey PartitionKey
+	// The maximum number of events queued per partition. When a partition queue is full, reading
+	// from the subscription pauses until the partition catches up.
+	QueueSize int // Defaults to 100.
+	// The maximum number of partitions handling an event at the same time. Use 0 for no limit.
+	MaxConcurrency int
+	// How many times a failing event is retried locally before FailureAction is sent to the server.
+	// Use 0 to handle every event only once.
+	MaxRetryCount *int // Defaults to 10.
+	// The delay between local retries.
+	RetryDelay time.Duration // Defaults to 100 milliseconds.
+	// Sent to the server once an event has exhausted its local retries, after which the partition
+	// moves on to its next event. Any action lets later events of the same partition overtake the
+	// failed one. When left unset, ConsumePartitioned stops with a *PartitionFailedError instead,
+	// keeping the order of the partition.
+	FailureAction Nack_Action
+}
+
+func (o *PartitionedConsumerOptions) setDefaults() {
+	if o.PartitionKey == nil {
+		o.PartitionKey = PartitionByCorrelationId
+	}
+
+	if o.QueueSize == 0 {
+		o.QueueSize = 100
+	}
+
+	if o.MaxRetryCount == nil {
+		maxRetryCount := 10
+		o.MaxRetryCount = &maxRetryCount
+	}
+
+	if o.RetryDelay == 0 {
+		o.RetryDelay = 100 * time.Millisecond
+	}
+}
+
+// Validate checks the sizes, counts and delays are not negative. It returns a *ValidationError
+// listing every invalid field.
+func (o PartitionedConsumerOptions) Validate() error {
+	err := &ValidationError{}
+
+	if o.QueueSize < 0 {
+		err.add("QueueSize", "must not be negative, got %d", o.QueueSize)
+	}
+
+	if o.MaxConcurrency < 0 {
+		err.add("MaxConcurrency", "must not be negative, got %d", o.MaxConcurrency)
+	}
+
+	if o.MaxRetryCount != nil && *o.MaxRetryCount < 0 {
+		err.add("MaxRetryCount", "must not be negative, got %d", *o.MaxRetryCount)
+	}
+
+	if o.RetryDelay < 0 {
+		err.add("RetryDelay", "must not be negative, got %v", o.RetryDelay)
+	}
+
+	return err.orNil()
+}
+
+type partition struct {
+	key   string
+	queue chan *ResolvedEvent
+	// Ids of the events queued or being handled, used to ignore server redeliveries of events
+	// this partition already holds.
+	pending map[uuid.UUID]bool
+}
+
+type partitionedConsumer struct {
+	subscription *PersistentSubscription
+	options      PartitionedConsumerOptions
+	handler      PartitionedHandler
+	ctx          context.Context
+	cancel       context.CancelFunc
+	lock         sync.Mutex
+	// The first event that exhausted its retries when no FailureAction is set.
+	failure    *PartitionFailedError
+	partitions map[string]*partition
+	semaphore  chan struct{}
+	workers    sync.WaitGroup
+}
+
+// ConsumePartitioned delivers events to handler, keeping one bounded in-order queue per
+// partition. Partitions are handled concurrently while events of a partition are handled one at a
+// time, and each event is acknowledged by its partition once handled. It is meant to be used with
+// the Pinned consumer strategy, or on its own to order events sharing a correlation id.
+//
+// When an event exhausts its local retries and FailureAction is unset, ConsumePartitioned stops
+// and returns a *PartitionFailedError, leaving the event unacknowledged. Setting FailureAction
+// nacks the event instead and carries on, which lets later events of the same partition overtake
+// the failed one, parking included.
+//
+// ConsumePartitioned returns when the subscription drops, or when ctx is cancelled, in which case
+// the subscription is closed. It waits for the events being handled before returning, queued
+// events are left unacknowledged so the server redelivers them.
+func (connection *PersistentSubscription) ConsumePartitioned(
+	ctx context.Context,
+	options PartitionedConsumerOptions,
+	handler PartitionedHandler,
+) error {
+	if err := options.Validate(); err != nil {
+		return err
+	}
+
+	options.setDefaults()
+	ctx, cancel := context.WithCancel(ctx)
+
+	consumer := &partitionedConsumer{
+		subscription: connection,
+		options:      options,
+		handler:      handler,
+		ctx:          ctx,
+		cancel:       cancel,
+		partitions:   make(map[string]*partition),
+	}
+
+	if options.MaxConcurrency > 0 {
+		consumer.semaphore = make(chan struct{}, options.MaxConcurrency)
+	}
+
+	go func() {
+		<-ctx.Done()
+		connection.Close()
+	}()
+
+	defer func() {
+		cancel()
+		consumer.workers.Wait()
+	}()
+
+	for {
+		event := connection.Recv()
+
+		if event.SubscriptionDropped != nil {
+			if ctx.Err() != nil {
+				return consumer.stopped()
+			}
+
+			return event.SubscriptionDropped.Error
+		}
+
+		if event.EventAppeared != nil {
+			if !consumer.dispatch(event.EventAppeared) {
+				return consumer.stopped()
+			}
+		}
+	}
+}
+
+// stopped returns why the consumer stopped, once its context is done.
+func (consumer *partitionedConsumer) stopped() error {
+	consumer.lock.Lock()
+	defer consumer.lock.Unlock()
+
+	if consumer.failure != nil {
+		return consumer.failure
+	}
+
+	return consumer.ctx.Err()
+}
+
+// fail stops the consumer because of an event that exhausted its retries.
+func (consumer *partitionedConsumer) fail(part *partition, event *ResolvedEvent, err error) {
+	consumer.lock.Lock()
+	if consumer.failure == nil {
+		consumer.failure = &PartitionFailedError{
+			PartitionKey: part.key,
+			EventID:      event.OriginalEvent().EventID,
+			Err:          err,
+		}
+	}
+	consumer.lock.Unlock()
+
+	consumer.cancel()
+}
+
+// dispatch queues the event in its partition, starting a worker for it if none runs. Returns false
+// if the consumer stopped while waiting for room in the queue.
+func (consumer *partitionedConsumer) dispatch(event *ResolvedEvent) bool {
+	key := consumer.options.PartitionKey(event)
+	id := event.OriginalEvent().EventID
+
+	consumer.lock.Lock()
+	part, exists := consumer.partitions[key]
+	if !exists {
+		part = &partition{
+			key:     key,
+			queue:   make(chan *ResolvedEvent, consumer.options.QueueSize),
+			pending: make(map[uuid.UUID]bool),
+		}
+
+		consumer.partitions[key] = part
+		consumer.workers.Add(1)
+		go consumer.work(part)
+	}
+
+	if part.pending[id] {
+		consumer.lock.Unlock()
+		return true
+	}
+
+	part.pending[id] = true
+	consumer.lock.Unlock()
+
+	select {
+	case part.queue <- event:
+		return true
+	case <-consumer.ctx.Done():
+		return false
+	}
+}
+
+// work handles the events of a partition in order. It exits once the queue is drained, so idle
+// partitions don't hold a goroutine.
+func (consumer *partitionedConsumer) work(part *partition) {
+	defer consumer.workers.Done()
+
+	for {
+		consumer.lock.Lock()
+		if len(part.pending) == 0 {
+			delete(consumer.partitions, part.key)
+			consumer.lock.Unlock()
+			return
+		}
+		consumer.lock.Unlock()
+
+		var event *ResolvedEvent
+		select {
+		case event = <-part.queue:
+		case <-consumer.ctx.Done():
+			return
+		}
+
+		if !consumer.handle(part, event) {
+			return
+		}
+
+		consumer.lock.Lock()
+		delete(part.pending, event.OriginalEvent().EventID)
+		consumer.lock.Unlock()
+	}
+}
+
+// handle runs the handler until it succeeds or runs out of retries, then acknowledges the event.
+// Returns false if the consumer stopped in the meantime, or stops it because no FailureAction is
+// set.
+func (consumer *partitionedConsumer) handle(part *partition, event *ResolvedEvent) bool {
+	if consumer.semaphore != nil {
+		select {
+		case consumer.semaphore <- struct{}{}:
+			defer func() { <-consumer.semaphore }()
+		case <-consumer.ctx.Done():
+			return false
+		}
+	}
+
+	var err error
+	for attempt := 0; attempt <= *consumer.options.MaxRetryCount; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-time.After(consumer.options.RetryDelay):
+			case <-consumer.ctx.Done():
+				return false
+			}
+		}
+
+		err = consumer.handler(consumer.ctx, event)
+		if err == nil {
+			break
+		}
+
+		if consumer.ctx.Err() != nil {
+			return false
+		}
+	}
+
+	if err != nil && consumer.options.FailureAction == Nack_Unknown {
+		consumer.subscription.conf.logf("[error] partitioned consumer stopped on event %v: %v", event.OriginalEvent().EventID, err)
+		consumer.fail(part, event, err)
+		return false
+	}
+
+	if err == nil {
+		err = consumer.subscription.Ack(event)
+	} else {
+		consumer.subscription.conf.logf("[error] partitioned consumer gave up on event %v: %v", event.OriginalEvent().EventID, err)
+		err = consumer.subscription.Nack(fmt.Sprintf("handler failed: %v", err), consumer.options.FailureAction, event)
+	}
+
+	if err != nil {
+		consumer.subscription.conf.logf("[error] partitioned consumer failed to acknowledge event %v: %v", event.OriginalEvent().EventID, err)
+	}
+
+	return true
+}
@@ -0,0 +1,327 @@
+package esdb_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/EventStore/EventStore-Client-Go/esdb"
+	"github.com/EventStore/EventStore-Client-Go/protos/persistent"
+	"github.com/EventStore/EventStore-Client-Go/protos/shared"
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// fakePersistentReadClient replays a set of responses then blocks until closed.
+type fakePersistentReadClient struct {
+	grpc.ClientStream
+	responses chan *persistent.ReadResp
+	closed    chan struct{}
+	err       error
+	trailer   metadata.MD
+	lock      sync.Mutex
+	acked     []string
+	nacked    []string
+}
+
+func newFakePersistentReadClient(responses ...*persistent.ReadResp) *fakePersistentReadClient {
+	client := &fakePersistentReadClient{
+		responses: make(chan *persistent.ReadResp, len(responses)),
+		closed:    make(chan struct{}),
+	}
+
+	for _, resp := range responses {
+		client.responses <- resp
+	}
+
+	return client
+}
+
+func (client *fakePersistentReadClient) Recv() (*persistent.ReadResp, error) {
+	select {
+	case resp := <-client.responses:
+		return resp, nil
+	case <-client.closed:
+		if client.err != nil {
+			return nil, client.err
+		}
+
+		return nil, io.EOF
+	}
+}
+
+func (client *fakePersistentReadClient) Trailer() metadata.MD {
+	return client.trailer
+}
+
+func (client *fakePersistentReadClient) Send(req *persistent.ReadReq) error {
+	client.lock.Lock()
+	defer client.lock.Unlock()
+
+	if ack := req.GetAck(); ack != nil {
+		for _, id := range ack.Ids {
+			client.acked = append(client.acked, id.GetString_())
+		}
+	}
+
+	if nack := req.GetNack(); nack != nil {
+		for _, id := range nack.Ids {
+			client.nacked = append(client.nacked, id.GetString_())
+		}
+	}
+
+	return nil
+}
+
+func (client *fakePersistentReadClient) close() {
+	close(client.closed)
+}
+
+// fail ends the stream the way the server does, with an error status and trailers.
+func (client *fakePersistentReadClient) fail(err error, trailer metadata.MD) {
+	client.err = err
+	client.trailer = trailer
+	close(client.closed)
+}
+
+func (client *fakePersistentReadClient) ackedIds() []string {
+	client.lock.Lock()
+	defer client.lock.Unlock()
+	return append([]string(nil), client.acked...)
+}
+
+func persistentEventResp(id uuid.UUID, streamID string, revision uint64, userMetadata string) *persistent.ReadResp {
+	return &persistent.ReadResp{
+		Content: &persistent.ReadResp_Event{
+			Event: &persistent.ReadResp_ReadEvent{
+				Event: &persistent.ReadResp_ReadEvent_RecordedEvent{
+					Id:               &shared.UUID{Value: &shared.UUID_String_{String_: id.String()}},
+					StreamIdentifier: &shared.StreamIdentifier{StreamName: []byte(streamID)},
+					StreamRevision:   revision,
+					Metadata: map[string]string{
+						"type":         "TestEvent",
+						"content-type": "application/json",
+						"created":      strconv.FormatInt(time.Now().UnixNano()/100, 10),
+					},
+					CustomMetadata: []byte(userMetadata),
+				},
+			},
+		},
+	}
+}
+
+func TestPartitionKeys(t *testing.T) {
+	withCorrelation := esdb.ResolvedEvent{Event: &esdb.RecordedEvent{
+		StreamID:     "orders-1",
+		UserMetadata: []byte(`{"$correlationId":"abc"}`),
+	}}
+	withoutCorrelation := esdb.ResolvedEvent{Event: &esdb.RecordedEvent{
+		StreamID:     "orders-2",
+		UserMetadata: []byte(`not json`),
+	}}
+
+	assert.Equal(t, "abc", esdb.PartitionByCorrelationId(&withCorrelation))
+	assert.Equal(t, "orders-2", esdb.PartitionByCorrelationId(&withoutCorrelation))
+	assert.Equal(t, "orders-1", esdb.PartitionByStream(&withCorrelation))
+}
+
+func TestConsumePartitionedKeepsPartitionOrder(t *testing.T) {
+	type delivery struct {
+		id     uuid.UUID
+		stream string
+	}
+
+	var deliveries []delivery
+	var responses []*persistent.ReadResp
+	for i := 0; i < 10; i++ {
+		stream := fmt.Sprintf("stream-%d", i%3)
+		d := delivery{id: uuid.Must(uuid.NewV4()), stream: stream}
+		deliveries = append(deliveries, d)
+		responses = append(responses, persistentEventResp(d.id, stream, uint64(i/3), ""))
+	}
+
+	readClient := newFakePersistentReadClient(responses...)
+	ctx, cancel := context.WithCancel(context.Background())
+	subscription := esdb.NewPersistentSubscription(readClient, "sub", func() {
+		cancel()
+		readClient.close()
+	})
+
+	var lock sync.Mutex
+	handled := make(map[string][]uuid.UUID)
+	failedOnce := false
+	var done sync.WaitGroup
+	done.Add(len(deliveries))
+
+	handler := func(ctx context.Context, event *esdb.ResolvedEvent) error {
+		lock.Lock()
+		defer lock.Unlock()
+
+		// The first event of stream-0 fails once, the other events of stream-0 must wait for it.
+		if event.Event.EventID == deliveries[0].id && !failedOnce {
+			failedOnce = true
+			return errors.New("transient failure")
+		}
+
+		handled[event.Event.StreamID] = append(handled[event.Event.StreamID], event.Event.EventID)
+		done.Done()
+		return nil
+	}
+
+	result := make(chan error)
+	go func() {
+		result <- subscription.ConsumePartitioned(ctx, esdb.PartitionedConsumerOptions{
+			PartitionKey:   esdb.PartitionByStream,
+			QueueSize:      2,
+			MaxConcurrency: 2,
+			RetryDelay:     time.Millisecond,
+		}, handler)
+	}()
+
+	require.False(t, waitWithTimeout(&done, 5*time.Second), "timed out waiting for the events to be handled")
+
+	for _, d := range deliveries {
+		assert.Contains(t, handled[d.stream], d.id)
+	}
+	for stream, ids := range handled {
+		var expected []uuid.UUID
+		for _, d := range deliveries {
+			if d.stream == stream {
+				expected = append(expected, d.id)
+			}
+		}
+		assert.Equal(t, expected, ids, "events of %s were handled out of order", stream)
+	}
+
+	require.Eventually(t, func() bool { return len(readClient.ackedIds()) == len(deliveries) }, time.Second, time.Millisecond)
+
+	cancel()
+	assert.True(t, errors.Is(<-result, context.Canceled))
+}
+
+func TestConsumePartitionedParksAfterRetries(t *testing.T) {
+	id := uuid.Must(uuid.NewV4())
+	readClient := newFakePersistentReadClient(persistentEventResp(id, "stream", 0, ""))
+	subscription := esdb.NewPersistentSubscription(readClient, "sub", readClient.close)
+
+	go func() {
+		assert.Eventually(t, func() bool {
+			readClient.lock.Lock()
+			defer readClient.lock.Unlock()
+			return len(readClient.nacked) == 1
+		}, time.Second, time.Millisecond)
+		subscription.Close()
+	}()
+
+	maxRetryCount := 2
+	err := subscription.ConsumePartitioned(context.Background(), esdb.PartitionedConsumerOptions{
+		MaxRetryCount: &maxRetryCount,
+		RetryDelay:    time.Millisecond,
+		FailureAction: esdb.Nack_Park,
+	}, func(ctx context.Context, event *esdb.ResolvedEvent) error {
+		return errors.New("permanent failure")
+	})
+
+	assert.True(t, errors.Is(err, esdb.ErrSubscriptionClosed))
+	assert.Equal(t, []string{id.String()}, readClient.nacked)
+	assert.Empty(t, readClient.acked)
+}
+
+func TestConsumePartitionedStopsOnFailedPartitionByDefault(t *testing.T) {
+	failed := uuid.Must(uuid.NewV4())
+	next := uuid.Must(uuid.NewV4())
+	readClient := newFakePersistentReadClient(
+		persistentEventResp(failed, "stream", 0, ""),
+		persistentEventResp(next, "stream", 1, ""),
+	)
+	subscription := esdb.NewPersistentSubscription(readClient, "sub", readClient.close)
+
+	var lock sync.Mutex
+	var handled []uuid.UUID
+	permanent := errors.New("permanent failure")
+
+	maxRetryCount := 2
+	err := subscription.ConsumePartitioned(context.Background(), esdb.PartitionedConsumerOptions{
+		MaxRetryCount: &maxRetryCount,
+		RetryDelay:    time.Millisecond,
+	}, func(ctx context.Context, event *esdb.ResolvedEvent) error {
+		lock.Lock()
+		defer lock.Unlock()
+		handled = append(handled, event.OriginalEvent().EventID)
+
+		if event.OriginalEvent().EventID == failed {
+			return permanent
+		}
+
+		return nil
+	})
+
+	var partitionErr *esdb.PartitionFailedError
+	require.True(t, errors.As(err, &partitionErr), err)
+	assert.Equal(t, "stream", partitionErr.PartitionKey)
+	assert.Equal(t, failed, partitionErr.EventID)
+	assert.True(t, errors.Is(err, permanent))
+
+	// The failed event is left to the server and the next one of its partition isn't handled.
+	assert.Equal(t, []uuid.UUID{failed, failed, failed}, handled)
+	assert.Empty(t, readClient.acked)
+	assert.Empty(t, readClient.nacked)
+}
+
+func TestConsumePartitionedWithoutRetries(t *testing.T) {
+	id := uuid.Must(uuid.NewV4())
+	readClient := newFakePersistentReadClient(persistentEventResp(id, "stream", 0, ""))
+	subscription := esdb.NewPersistentSubscription(readClient, "sub", readClient.close)
+
+	go func() {
+		assert.Eventually(t, func() bool {
+			readClient.lock.Lock()
+			defer readClient.lock.Unlock()
+			return len(readClient.nacked) == 1
+		}, time.Second, time.Millisecond)
+		subscription.Close()
+	}()
+
+	var lock sync.Mutex
+	handled := 0
+	maxRetryCount := 0
+	err := subscription.ConsumePartitioned(context.Background(), esdb.PartitionedConsumerOptions{
+		MaxRetryCount: &maxRetryCount,
+		FailureAction: esdb.Nack_Park,
+	}, func(ctx context.Context, event *esdb.ResolvedEvent) error {
+		lock.Lock()
+		defer lock.Unlock()
+		handled++
+		return errors.New("permanent failure")
+	})
+
+	assert.True(t, errors.Is(err, esdb.ErrSubscriptionClosed))
+	assert.Equal(t, 1, handled)
+	assert.Equal(t, []string{id.String()}, readClient.nacked)
+}
+
+func TestConsumePartitionedRejectsInvalidOptions(t *testing.T) {
+	readClient := newFakePersistentReadClient(persistentEventResp(uuid.Must(uuid.NewV4()), "stream", 0, ""))
+	subscription := esdb.NewPersistentSubscription(readClient, "sub", readClient.close)
+	defer subscription.Close()
+
+	maxRetryCount := -1
+	err := subscription.ConsumePartitioned(context.Background(), esdb.PartitionedConsumerOptions{
+		MaxRetryCount: &maxRetryCount,
+	}, func(ctx context.Context, event *esdb.ResolvedEvent) error {
+		t.Error("the handler must not be called")
+		return nil
+	})
+
+	var validationErr *esdb.ValidationError
+	require.True(t, errors.As(err, &validationErr), err)
+	assert.Empty(t, readClient.ackedIds())
+}
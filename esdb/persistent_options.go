@@ -1,9 +1,12 @@
 package esdb
 
+import "time"
+
 type PersistentStreamSubscriptionOptions struct {
 	Settings      *SubscriptionSettings
 	From          StreamPosition
 	Authenticated *Credentials
+	Deadline      time.Duration // Defaults to Configuration.DefaultDeadline, -1 disables it.
 }
 
 func (o *PersistentStreamSubscriptionOptions) setDefaults() {
@@ -12,6 +15,17 @@ func (o *PersistentStreamSubscriptionOptions) setDefaults() {
 	}
 }
 
+// Validate checks the settings, if any. It returns a *ValidationError listing every invalid field.
+func (o PersistentStreamSubscriptionOptions) Validate() error {
+	err := &ValidationError{}
+
+	if o.Settings != nil {
+		err.merge("Settings", o.Settings.Validate())
+	}
+
+	return err.orNil()
+}
+
 type PersistentAllSubscriptionOptions struct {
 	Settings           *SubscriptionSettings
 	From               AllPosition
@@ -19,6 +33,7 @@ type PersistentAllSubscriptionOptions struct {
 	CheckpointInterval int
 	Filter             *SubscriptionFilter
 	Authenticated      *Credentials
+	Deadline           time.Duration // Defaults to Configuration.DefaultDeadline, -1 disables it.
 }
 
 func (o *PersistentAllSubscriptionOptions) setDefaults() {
@@ -37,9 +52,33 @@ func (o *PersistentAllSubscriptionOptions) setDefaults() {
 	}
 }
 
+// Validate checks the settings, the starting position and the filter, if any. It returns a
+// *ValidationError listing every invalid field.
+func (o PersistentAllSubscriptionOptions) Validate() error {
+	err := &ValidationError{}
+
+	if o.Settings != nil {
+		err.merge("Settings", o.Settings.Validate())
+	}
+
+	validateAllPosition(err, o.From)
+	validateFilterOptions(err, o.Filter, o.MaxSearchWindow, o.CheckpointInterval)
+
+	return err.orNil()
+}
+
 type ConnectToPersistentSubscriptionOptions struct {
 	BatchSize     uint32
 	Authenticated *Credentials
+	// Enables sampling the head of $all to compute the lag of the subscription.
+	Stats *SubscriptionStatsOptions
+	// When no message is received for that long, the connection is probed by reading the last
+	// event of the stream and the subscription is dropped if the probe fails. Use 0 to disable.
+	IdleTimeout time.Duration
+	// Number of events buffered ahead of the consumer, see Events. Defaults to 0, in which case
+	// a single event waits for the consumer.
+	EventBufferSize int
+	Deadline        time.Duration // Defaults to Configuration.DefaultDeadline, -1 disables it.
 }
 
 func (o *ConnectToPersistentSubscriptionOptions) setDefaults() {
@@ -50,4 +89,5 @@ func (o *ConnectToPersistentSubscriptionOptions) setDefaults() {
 
 type DeletePersistentSubscriptionOptions struct {
 	Authenticated *Credentials
+	Deadline      time.Duration // Defaults to Configuration.DefaultDeadline, -1 disables it.
 }
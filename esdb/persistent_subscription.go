@@ -4,7 +4,6 @@ import (
 	"context"
 	"fmt"
 
-	"log"
 	"sync"
 
 	"github.com/EventStore/EventStore-Client-Go/protos/persistent"
@@ -27,28 +26,47 @@ const (
 type PersistentSubscription struct {
 	client         persistent.PersistentSubscriptions_ReadClient
 	subscriptionId string
-	channel        chan persistentRequest
+	channel        *subscriptionChannel
 	cancel         context.CancelFunc
-	once           *sync.Once
+	// Acks and nacks may be sent from several goroutines but the gRPC stream doesn't support
+	// concurrent sends.
+	sendLock *sync.Mutex
+	tracker  *subscriptionTracker
+	// nil for subscriptions created with NewPersistentSubscription.
+	conf *Configuration
 }
 
+// Recv blocks until the next event. Once the subscription is dropped or closed, it keeps returning
+// the SubscriptionDropped event.
 func (connection *PersistentSubscription) Recv() *SubscriptionEvent {
-	channel := make(chan *SubscriptionEvent)
-	req := persistentRequest{
-		channel: channel,
-	}
+	event, _ := connection.channel.recv(context.Background())
+	return event
+}
 
-	connection.channel <- req
-	resp := <-channel
+// RecvContext is like Recv but gives up when ctx is done, in which case it returns ctx.Err() and
+// the subscription keeps running.
+func (connection *PersistentSubscription) RecvContext(ctx context.Context) (*SubscriptionEvent, error) {
+	return connection.channel.recv(ctx)
+}
 
-	return resp
+// Events returns the channel events are delivered to, see
+// ConnectToPersistentSubscriptionOptions.EventBufferSize. The last event is a SubscriptionDropped,
+// unless the subscription is closed, and the channel is closed once the subscription is over.
+func (connection *PersistentSubscription) Events() <-chan *SubscriptionEvent {
+	return connection.channel.events
 }
 
 func (connection *PersistentSubscription) Close() error {
-	connection.once.Do(connection.cancel)
+	connection.channel.close(connection.cancel)
+	connection.tracker.stop()
 	return nil
 }
 
+// Stats returns the throughput of the subscription and, when enabled, its lag behind the head of $all.
+func (connection *PersistentSubscription) Stats() SubscriptionStats {
+	return connection.tracker.stats()
+}
+
 func (connection *PersistentSubscription) Ack(messages ...*ResolvedEvent) error {
 	if len(messages) == 0 {
 		return nil
@@ -63,6 +81,9 @@ func (connection *PersistentSubscription) Ack(messages ...*ResolvedEvent) error
 		ids = append(ids, event.OriginalEvent().EventID)
 	}
 
+	connection.sendLock.Lock()
+	defer connection.sendLock.Unlock()
+
 	err := connection.client.Send(&persistent.ReadReq{
 		Content: &persistent.ReadReq_Ack_{
 			Ack: &persistent.ReadReq_Ack{
@@ -88,6 +109,9 @@ func (connection *PersistentSubscription) Nack(reason string, action Nack_Action
 		ids = append(ids, event.OriginalEvent().EventID)
 	}
 
+	connection.sendLock.Lock()
+	defer connection.sendLock.Unlock()
+
 	err := connection.client.Send(&persistent.ReadReq{
 		Content: &persistent.ReadReq_Nack_{
 			Nack: &persistent.ReadReq_Nack{
@@ -115,65 +139,69 @@ func messageIdSliceToProto(messageIds ...uuid.UUID) []*shared.UUID {
 	return result
 }
 
-type persistentRequest struct {
-	channel chan *SubscriptionEvent
+func NewPersistentSubscription(
+	client persistent.PersistentSubscriptions_ReadClient,
+	subscriptionId string,
+	cancel context.CancelFunc,
+) *PersistentSubscription {
+	return newPersistentSubscription(client, subscriptionId, cancel, nil, 0, nil, nil)
 }
 
-func NewPersistentSubscription(
+func newPersistentSubscription(
 	client persistent.PersistentSubscriptions_ReadClient,
 	subscriptionId string,
 	cancel context.CancelFunc,
+	idle *idleWatchdog,
+	bufferSize int,
+	conf *Configuration,
+	operations *operationTracker,
 ) *PersistentSubscription {
-	channel := make(chan persistentRequest)
-	once := new(sync.Once)
+	channel := newSubscriptionChannel(bufferSize)
+	tracker := newSubscriptionTracker()
+	metrics := metricsOf(conf)
+	metrics.SubscriptionStarted(subscriptionId)
+	untrack := operations.track(func() { channel.shutdown(cancel) })
 
 	// It is not safe to consume a stream in different goroutines. This is why we only consume
-	// the stream in a dedicated goroutine.
-	//
-	// Current implementation doesn't terminate the goroutine. When a subscription is dropped,
-	// we keep user requests coming but will always send back a subscription dropped event.
-	// This implementation is simple to maintain while letting the user sharing their subscription
-	// among as many goroutines as they want.
+	// the stream in a dedicated goroutine, which hands events over to the subscription channel.
+	// The goroutine exits once the stream ends, either because the subscription was closed or
+	// because it dropped.
 	go func() {
-		closed := false
+		defer untrack()
 
 		for {
-			req := <-channel
-
-			if closed {
-				req.channel <- &SubscriptionEvent{
-					SubscriptionDropped: &SubscriptionDropped{
-						Error: fmt.Errorf("subscription has been dropped"),
-					},
-				}
-
-				continue
-			}
-
+			idle.arm()
 			result, err := client.Recv()
+			timedOut := idle.disarm()
 			if err != nil {
-				log.Printf("[error] subscription has dropped. Reason: %v", err)
-
-				dropped := SubscriptionDropped{
-					Error: err,
-				}
-
-				req.channel <- &SubscriptionEvent{
-					SubscriptionDropped: &dropped,
+				if timedOut {
+					err = fmt.Errorf("%w: no message received for %v", ErrSubscriptionIdleTimeout, idle.timeout)
+				} else if channel.isClientClosed() {
+					err = ErrClientClosed
 				}
 
-				closed = true
+				dropped := newSubscriptionDropped(err, client.Trailer(), channel.isClosing())
+				conf.logf("[error] subscription has dropped. Reason: %v (%v)", dropped.Reason, err)
+				tracker.stop()
+				metrics.SubscriptionStopped(subscriptionId, dropped.Reason)
+				channel.drop(dropped)
 
-				continue
+				return
 			}
 
 			switch result.Content.(type) {
 			case *persistent.ReadResp_Event:
 				{
 					resolvedEvent := fromPersistentProtoResponse(result)
-					req.channel <- &SubscriptionEvent{
+					tracker.eventDelivered(resolvedEvent)
+					metrics.BytesRead(eventSize(resolvedEvent))
+					metrics.EventDelivered(subscriptionId)
+					ctx, span := traceDelivery(conf, "PersistentSubscription.Deliver", subscriptionId, resolvedEvent)
+					channel.deliver(&SubscriptionEvent{
 						EventAppeared: resolvedEvent,
-					}
+						ctx:           ctx,
+					})
+					span.end(nil)
 				}
 			}
 		}
@@ -183,7 +211,9 @@ func NewPersistentSubscription(
 		client:         client,
 		subscriptionId: subscriptionId,
 		channel:        channel,
-		once:           once,
 		cancel:         cancel,
+		sendLock:       new(sync.Mutex),
+		tracker:        tracker,
+		conf:           conf,
 	}
 }
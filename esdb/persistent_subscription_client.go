@@ -2,6 +2,7 @@ package esdb
 
 import (
 	"context"
+	"time"
 
 	"github.com/EventStore/EventStore-Client-Go/protos/persistent"
 	"google.golang.org/grpc"
@@ -16,45 +17,55 @@ type persistentClient struct {
 func (client *persistentClient) ConnectToPersistentSubscription(
 	ctx context.Context,
 	handle connectionHandle,
+	handshake *handshakeDeadline,
 	bufferSize int32,
 	streamName string,
 	groupName string,
 	auth *Credentials,
+	idleTimeout time.Duration,
+	idleProbe func(ctx context.Context) error,
+	eventBufferSize int,
 ) (*PersistentSubscription, error) {
 	var headers, trailers metadata.MD
 	callOptions := []grpc.CallOption{grpc.Header(&headers), grpc.Trailer(&trailers)}
-	if auth != nil {
-		callOptions = append(callOptions, grpc.PerRPCCredentials(basicAuth{
-			username: auth.Login,
-			password: auth.Password,
-		}))
-	}
+	ctx = withCallCredentials(ctx, auth)
 	ctx, cancel := context.WithCancel(ctx)
+	handshake.watch(cancel)
 	readClient, err := client.persistentSubscriptionClient.Read(ctx, callOptions...)
 	if err != nil {
 		defer cancel()
 		err = client.inner.handleError(handle, headers, trailers, err)
-		return nil, PersistentSubscriptionFailedToInitClientError(err)
+		return nil, handshake.done(PersistentSubscriptionFailedToInitClientError(err))
 	}
 
 	err = readClient.Send(toPersistentReadRequest(bufferSize, groupName, []byte(streamName)))
 	if err != nil {
 		defer cancel()
-		return nil, PersistentSubscriptionFailedSendStreamInitError(err)
+		return nil, handshake.done(PersistentSubscriptionFailedSendStreamInitError(err))
 	}
 
 	readResult, err := readClient.Recv()
 	if err != nil {
 		defer cancel()
-		return nil, PersistentSubscriptionFailedReceiveStreamInitError(err)
+		return nil, handshake.done(PersistentSubscriptionFailedReceiveStreamInitError(err))
 	}
+	handshake.done(nil)
 	switch readResult.Content.(type) {
 	case *persistent.ReadResp_SubscriptionConfirmation_:
 		{
-			asyncConnection := NewPersistentSubscription(
+			var idle *idleWatchdog
+			if idleTimeout > 0 {
+				idle = newIdleWatchdog(&client.inner.config, idleTimeout, idleProbe, cancel)
+			}
+
+			asyncConnection := newPersistentSubscription(
 				readClient,
 				readResult.GetSubscriptionConfirmation().SubscriptionId,
-				cancel)
+				cancel,
+				idle,
+				eventBufferSize,
+				&client.inner.config,
+				client.inner.operations)
 
 			return asyncConnection, nil
 		}
@@ -76,12 +87,7 @@ func (client *persistentClient) CreateStreamSubscription(
 	createSubscriptionConfig := createPersistentRequestProto(streamName, groupName, position, settings)
 	var headers, trailers metadata.MD
 	callOptions := []grpc.CallOption{grpc.Header(&headers), grpc.Trailer(&trailers)}
-	if auth != nil {
-		callOptions = append(callOptions, grpc.PerRPCCredentials(basicAuth{
-			username: auth.Login,
-			password: auth.Password,
-		}))
-	}
+	ctx = withCallCredentials(ctx, auth)
 	_, err := client.persistentSubscriptionClient.Create(ctx, createSubscriptionConfig, callOptions...)
 	if err != nil {
 		err = client.inner.handleError(handle, headers, trailers, err)
@@ -107,12 +113,7 @@ func (client *persistentClient) CreateAllSubscription(
 
 	var headers, trailers metadata.MD
 	callOptions := []grpc.CallOption{grpc.Header(&headers), grpc.Trailer(&trailers)}
-	if auth != nil {
-		callOptions = append(callOptions, grpc.PerRPCCredentials(basicAuth{
-			username: auth.Login,
-			password: auth.Password,
-		}))
-	}
+	ctx = withCallCredentials(ctx, auth)
 	_, err = client.persistentSubscriptionClient.Create(ctx, protoConfig, callOptions...)
 	if err != nil {
 		err = client.inner.handleError(handle, headers, trailers, err)
@@ -134,12 +135,7 @@ func (client *persistentClient) UpdateStreamSubscription(
 	updateSubscriptionConfig := updatePersistentRequestStreamProto(streamName, groupName, position, settings)
 	var headers, trailers metadata.MD
 	callOptions := []grpc.CallOption{grpc.Header(&headers), grpc.Trailer(&trailers)}
-	if auth != nil {
-		callOptions = append(callOptions, grpc.PerRPCCredentials(basicAuth{
-			username: auth.Login,
-			password: auth.Password,
-		}))
-	}
+	ctx = withCallCredentials(ctx, auth)
 	_, err := client.persistentSubscriptionClient.Update(ctx, updateSubscriptionConfig, callOptions...)
 	if err != nil {
 		err = client.inner.handleError(handle, headers, trailers, err)
@@ -161,12 +157,7 @@ func (client *persistentClient) UpdateAllSubscription(
 
 	var headers, trailers metadata.MD
 	callOptions := []grpc.CallOption{grpc.Header(&headers), grpc.Trailer(&trailers)}
-	if auth != nil {
-		callOptions = append(callOptions, grpc.PerRPCCredentials(basicAuth{
-			username: auth.Login,
-			password: auth.Password,
-		}))
-	}
+	ctx = withCallCredentials(ctx, auth)
 	_, err := client.persistentSubscriptionClient.Update(ctx, updateSubscriptionConfig, callOptions...)
 	if err != nil {
 		err = client.inner.handleError(handle, headers, trailers, err)
@@ -186,12 +177,7 @@ func (client *persistentClient) DeleteStreamSubscription(
 	deleteSubscriptionOptions := deletePersistentRequestStreamProto(streamName, groupName)
 	var headers, trailers metadata.MD
 	callOptions := []grpc.CallOption{grpc.Header(&headers), grpc.Trailer(&trailers)}
-	if auth != nil {
-		callOptions = append(callOptions, grpc.PerRPCCredentials(basicAuth{
-			username: auth.Login,
-			password: auth.Password,
-		}))
-	}
+	ctx = withCallCredentials(ctx, auth)
 	_, err := client.persistentSubscriptionClient.Delete(ctx, deleteSubscriptionOptions, callOptions...)
 	if err != nil {
 		err = client.inner.handleError(handle, headers, trailers, err)
@@ -205,12 +191,7 @@ func (client *persistentClient) DeleteAllSubscription(ctx context.Context, handl
 	deleteSubscriptionOptions := deletePersistentRequestAllOptionsProto(groupName)
 	var headers, trailers metadata.MD
 	callOptions := []grpc.CallOption{grpc.Header(&headers), grpc.Trailer(&trailers)}
-	if auth != nil {
-		callOptions = append(callOptions, grpc.PerRPCCredentials(basicAuth{
-			username: auth.Login,
-			password: auth.Password,
-		}))
-	}
+	ctx = withCallCredentials(ctx, auth)
 	_, err := client.persistentSubscriptionClient.Delete(ctx, deleteSubscriptionOptions, callOptions...)
 	if err != nil {
 		err = client.inner.handleError(handle, headers, trailers, err)
@@ -0,0 +1,166 @@
+package esdb
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+)
+
+// persistentSubscriptionConfig is the configuration of a group, as returned by the
+// /subscriptions/{stream}/{group}/info endpoint of the HTTP API. The gRPC API of the server doesn't
+// expose the settings of a group.
+type persistentSubscriptionConfig struct {
+	ResolveLinktos              bool   `json:"resolveLinktos"`
+	ExtraStatistics             bool   `json:"extraStatistics"`
+	MaxRetryCount               int32  `json:"maxRetryCount"`
+	MinCheckPointCount          int32  `json:"minCheckPointCount"`
+	MaxCheckPointCount          int32  `json:"maxCheckPointCount"`
+	MaxSubscriberCount          int32  `json:"maxSubscriberCount"`
+	LiveBufferSize              int32  `json:"liveBufferSize"`
+	ReadBatchSize               int32  `json:"readBatchSize"`
+	BufferSize                  int32  `json:"bufferSize"`
+	NamedConsumerStrategy       string `json:"namedConsumerStrategy"`
+	MessageTimeoutMilliseconds  int32  `json:"messageTimeoutMilliseconds"`
+	CheckPointAfterMilliseconds int32  `json:"checkPointAfterMilliseconds"`
+}
+
+func (config persistentSubscriptionConfig) toSubscriptionSettings() (SubscriptionSettings, error) {
+	strategy, err := parseConsumerStrategy(config.NamedConsumerStrategy)
+	if err != nil {
+		return SubscriptionSettings{}, err
+	}
+
+	return SubscriptionSettings{
+		ResolveLinkTos:        config.ResolveLinktos,
+		ExtraStatistics:       config.ExtraStatistics,
+		MaxRetryCount:         config.MaxRetryCount,
+		MinCheckpointCount:    config.MinCheckPointCount,
+		MaxCheckpointCount:    config.MaxCheckPointCount,
+		MaxSubscriberCount:    config.MaxSubscriberCount,
+		LiveBufferSize:        config.LiveBufferSize,
+		ReadBatchSize:         config.ReadBatchSize,
+		HistoryBufferSize:     config.BufferSize,
+		NamedConsumerStrategy: strategy,
+		MessageTimeoutInMs:    config.MessageTimeoutMilliseconds,
+		CheckpointAfterInMs:   config.CheckPointAfterMilliseconds,
+	}, nil
+}
+
+// getPersistentSubscriptionSettings reads the settings of a group from the leader, returns nil if
+// the group doesn't exist.
+func (client *Client) getPersistentSubscriptionSettings(
+	ctx context.Context,
+	streamName string,
+	groupName string,
+	auth *Credentials,
+) (_ *SubscriptionSettings, err error) {
+	ctx, span := client.grpcClient.startSpan(ctx, "GetPersistentSubscriptionInfo", streamName)
+	defer func() { span.end(err) }()
+
+	end, err := client.grpcClient.operations.begin()
+	if err != nil {
+		return nil, err
+	}
+	defer end()
+
+	ctx, cancel := client.grpcClient.withDeadline(ctx, "GetPersistentSubscriptionInfo", 0)
+	defer cancel()
+	defer func() { err = deadlineError(ctx, err) }()
+
+	// Only the address of the leader is needed, a client certificate is used by the HTTP client.
+	handle, err := client.grpcClient.getLeaderConnectionHandle(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("can't get a connection handle: %w", err)
+	}
+
+	return readPersistentSubscriptionSettings(ctx, &client.grpcClient.config, handle.Connection().Target(), streamName, groupName, auth)
+}
+
+func readPersistentSubscriptionSettings(
+	ctx context.Context,
+	conf *Configuration,
+	target string,
+	streamName string,
+	groupName string,
+	auth *Credentials,
+) (*SubscriptionSettings, error) {
+	scheme := "https"
+	if conf.DisableTLS {
+		scheme = "http"
+	}
+
+	var certificate *tls.Certificate
+	if auth != nil {
+		certificate = auth.Certificate
+	}
+
+	transport := &http.Transport{
+		TLSClientConfig:   conf.tlsConfig(certificate),
+		ForceAttemptHTTP2: true,
+		DialContext:       (&net.Dialer{}).DialContext,
+	}
+	defer transport.CloseIdleConnections()
+
+	if conf.Dialer != nil {
+		transport.DialContext = func(ctx context.Context, _ string, address string) (net.Conn, error) {
+			return conf.Dialer(ctx, address)
+		}
+	}
+
+	address := fmt.Sprintf("%s://%s/subscriptions/%s/%s/info", scheme, target, url.PathEscape(streamName), url.PathEscape(groupName))
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, address, nil)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Accept", "application/json")
+
+	headers, err := callCredentials{provider: conf.credentialsProvider()}.GetRequestMetadata(withCallCredentials(ctx, auth))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(headers) > 0 && conf.DisableTLS && conf.CredentialsRequireTLS {
+		return nil, fmt.Errorf("the credentials require transport level security")
+	}
+
+	for key, value := range headers {
+		request.Header.Set(key, value)
+	}
+
+	response, err := (&http.Client{Transport: transport}).Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the info of group '%s' on '%s': %w", groupName, streamName, err)
+	}
+	defer response.Body.Close()
+
+	switch response.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, nil
+	case http.StatusUnauthorized:
+		return nil, ErrUnauthenticated
+	case http.StatusForbidden:
+		return nil, ErrPermissionDenied
+	default:
+		return nil, fmt.Errorf("failed to read the info of group '%s' on '%s': %s", groupName, streamName, response.Status)
+	}
+
+	var info struct {
+		Config persistentSubscriptionConfig `json:"config"`
+	}
+
+	if err := json.NewDecoder(response.Body).Decode(&info); err != nil {
+		return nil, fmt.Errorf("invalid info of group '%s' on '%s': %w", groupName, streamName, err)
+	}
+
+	settings, err := info.Config.toSubscriptionSettings()
+	if err != nil {
+		return nil, fmt.Errorf("invalid info of group '%s' on '%s': %w", groupName, streamName, err)
+	}
+
+	return &settings, nil
+}
@@ -0,0 +1,452 @@
+package esdb
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const manifestAllStreamName = "$all"
+
+// PersistentSubscriptionManifest declares a set of persistent subscription groups. Applying a
+// manifest creates the groups that don't exist yet and updates the ones whose settings differ from
+// the declared ones, in the order they are declared.
+type PersistentSubscriptionManifest struct {
+	Groups []PersistentSubscriptionDeclaration
+}
+
+// PersistentSubscriptionDeclaration declares a persistent subscription group on a stream, or on
+// $all when StreamName is $all.
+type PersistentSubscriptionDeclaration struct {
+	StreamName string
+	GroupName  string
+	// Used when StreamName isn't $all.
+	StreamOptions PersistentStreamSubscriptionOptions
+	// Used when StreamName is $all.
+	AllOptions PersistentAllSubscriptionOptions
+}
+
+// IsAll tells whether the group is on $all.
+func (declaration *PersistentSubscriptionDeclaration) IsAll() bool {
+	return declaration.StreamName == manifestAllStreamName
+}
+
+func (declaration *PersistentSubscriptionDeclaration) settings() *SubscriptionSettings {
+	if declaration.IsAll() {
+		return declaration.AllOptions.Settings
+	}
+
+	return declaration.StreamOptions.Settings
+}
+
+type ManifestAction int
+
+const (
+	// The group doesn't exist and is created.
+	ManifestAction_Create ManifestAction = iota
+	// The group exists with other settings and is updated.
+	ManifestAction_Update
+	// The group exists with the declared settings and is left as is.
+	ManifestAction_Unchanged
+)
+
+func (action ManifestAction) String() string {
+	switch action {
+	case ManifestAction_Create:
+		return "Create"
+	case ManifestAction_Update:
+		return "Update"
+	case ManifestAction_Unchanged:
+		return "Unchanged"
+	default:
+		return fmt.Sprintf("ManifestAction(%d)", int(action))
+	}
+}
+
+// PersistentSubscriptionManifestChange reports what happened, or what would happen in dry-run mode,
+// to a single group of a manifest.
+type PersistentSubscriptionManifestChange struct {
+	StreamName string
+	GroupName  string
+	Action     ManifestAction
+}
+
+type ApplyManifestOptions struct {
+	// Reads the groups of the manifest and reports what applying it would do, without changing
+	// anything on the server.
+	DryRun        bool
+	Authenticated *Credentials
+}
+
+type manifestDocument struct {
+	Groups []manifestGroup `yaml:"groups"`
+}
+
+type manifestGroup struct {
+	Stream             string           `yaml:"stream"`
+	Group              string           `yaml:"group"`
+	From               string           `yaml:"from"`
+	Settings           manifestSettings `yaml:"settings"`
+	Filter             *manifestFilter  `yaml:"filter"`
+	MaxSearchWindow    int              `yaml:"maxSearchWindow"`
+	CheckpointInterval int              `yaml:"checkpointInterval"`
+}
+
+type manifestSettings struct {
+	ResolveLinkTos      *bool   `yaml:"resolveLinkTos"`
+	ExtraStatistics     *bool   `yaml:"extraStatistics"`
+	MaxRetryCount       *int32  `yaml:"maxRetryCount"`
+	MinCheckpointCount  *int32  `yaml:"minCheckpointCount"`
+	MaxCheckpointCount  *int32  `yaml:"maxCheckpointCount"`
+	MaxSubscriberCount  *int32  `yaml:"maxSubscriberCount"`
+	LiveBufferSize      *int32  `yaml:"liveBufferSize"`
+	ReadBatchSize       *int32  `yaml:"readBatchSize"`
+	HistoryBufferSize   *int32  `yaml:"historyBufferSize"`
+	ConsumerStrategy    *string `yaml:"consumerStrategy"`
+	MessageTimeoutInMs  *int32  `yaml:"messageTimeoutInMs"`
+	CheckpointAfterInMs *int32  `yaml:"checkpointAfterInMs"`
+}
+
+type manifestFilter struct {
+	Type     string   `yaml:"type"`
+	Prefixes []string `yaml:"prefixes"`
+	Regex    string   `yaml:"regex"`
+}
+
+// LoadPersistentSubscriptionManifest reads a YAML or JSON manifest file.
+func LoadPersistentSubscriptionManifest(path string) (*PersistentSubscriptionManifest, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read persistent subscription manifest: %w", err)
+	}
+
+	return ParsePersistentSubscriptionManifest(data)
+}
+
+// ParsePersistentSubscriptionManifest parses a YAML or JSON manifest. Example:
+//
+//	groups:
+//	  - stream: orders
+//	    group: billing
+//	    from: start
+//	    settings:
+//	      maxRetryCount: 5
+//	      consumerStrategy: Pinned
+//	  - stream: $all
+//	    group: audit
+//	    filter:
+//	      type: streamName
+//	      prefixes: [orders-]
+//
+// Settings that are omitted take their value from SubscriptionSettingsDefault.
+func ParsePersistentSubscriptionManifest(data []byte) (*PersistentSubscriptionManifest, error) {
+	var document manifestDocument
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&document); err != nil {
+		return nil, fmt.Errorf("failed to parse persistent subscription manifest: %w", err)
+	}
+
+	manifest := &PersistentSubscriptionManifest{}
+	seen := make(map[string]bool)
+
+	for i, group := range document.Groups {
+		if group.Stream == "" {
+			return nil, fmt.Errorf("manifest group #%d: no stream specified", i+1)
+		}
+
+		if group.Group == "" {
+			return nil, fmt.Errorf("manifest group #%d: no group name specified", i+1)
+		}
+
+		key := group.Stream + "::" + group.Group
+		if seen[key] {
+			return nil, fmt.Errorf("manifest group #%d: group '%s' on '%s' is declared more than once", i+1, group.Group, group.Stream)
+		}
+		seen[key] = true
+
+		settings, err := group.Settings.toSubscriptionSettings()
+		if err != nil {
+			return nil, fmt.Errorf("manifest group '%s' on '%s': %w", group.Group, group.Stream, err)
+		}
+
+		if group.Stream == manifestAllStreamName {
+			declaration, err := group.toAllDeclaration(settings)
+			if err != nil {
+				return nil, fmt.Errorf("manifest group '%s' on '%s': %w", group.Group, group.Stream, err)
+			}
+
+			if err := declaration.AllOptions.Validate(); err != nil {
+				return nil, fmt.Errorf("manifest group '%s' on '%s': %w", group.Group, group.Stream, err)
+			}
+
+			manifest.Groups = append(manifest.Groups, declaration)
+			continue
+		}
+
+		declaration, err := group.toStreamDeclaration(settings)
+		if err != nil {
+			return nil, fmt.Errorf("manifest group '%s' on '%s': %w", group.Group, group.Stream, err)
+		}
+
+		if err := declaration.StreamOptions.Validate(); err != nil {
+			return nil, fmt.Errorf("manifest group '%s' on '%s': %w", group.Group, group.Stream, err)
+		}
+
+		manifest.Groups = append(manifest.Groups, declaration)
+	}
+
+	return manifest, nil
+}
+
+func (group manifestGroup) toStreamDeclaration(settings SubscriptionSettings) (PersistentSubscriptionDeclaration, error) {
+	declaration := PersistentSubscriptionDeclaration{
+		StreamName: group.Stream,
+		GroupName:  group.Group,
+		StreamOptions: PersistentStreamSubscriptionOptions{
+			Settings: &settings,
+		},
+	}
+
+	if group.Filter != nil || group.MaxSearchWindow != 0 || group.CheckpointInterval != 0 {
+		return declaration, fmt.Errorf("filters are only supported on $all")
+	}
+
+	switch strings.ToLower(group.From) {
+	case "":
+	case "start":
+		declaration.StreamOptions.From = Start{}
+	case "end":
+		declaration.StreamOptions.From = End{}
+	default:
+		revision, err := strconv.ParseUint(group.From, 10, 64)
+		if err != nil {
+			return declaration, fmt.Errorf("invalid from '%s', expecting start, end or a stream revision", group.From)
+		}
+
+		declaration.StreamOptions.From = Revision(revision)
+	}
+
+	return declaration, nil
+}
+
+func (group manifestGroup) toAllDeclaration(settings SubscriptionSettings) (PersistentSubscriptionDeclaration, error) {
+	declaration := PersistentSubscriptionDeclaration{
+		StreamName: group.Stream,
+		GroupName:  group.Group,
+		AllOptions: PersistentAllSubscriptionOptions{
+			Settings:           &settings,
+			MaxSearchWindow:    group.MaxSearchWindow,
+			CheckpointInterval: group.CheckpointInterval,
+		},
+	}
+
+	switch strings.ToLower(group.From) {
+	case "":
+	case "start":
+		declaration.AllOptions.From = Start{}
+	case "end":
+		declaration.AllOptions.From = End{}
+	default:
+		position, err := parseManifestPosition(group.From)
+		if err != nil {
+			return declaration, err
+		}
+
+		declaration.AllOptions.From = position
+	}
+
+	if group.Filter != nil {
+		filter := &SubscriptionFilter{
+			Prefixes: group.Filter.Prefixes,
+			Regex:    group.Filter.Regex,
+		}
+
+		switch strings.ToLower(group.Filter.Type) {
+		case "eventtype":
+			filter.Type = EventFilterType
+		case "streamname":
+			filter.Type = StreamFilterType
+		default:
+			return declaration, fmt.Errorf("invalid filter type '%s', expecting eventType or streamName", group.Filter.Type)
+		}
+
+		declaration.AllOptions.Filter = filter
+	}
+
+	return declaration, nil
+}
+
+// parseManifestPosition parses a position written as C:{commit}/P:{prepare}.
+func parseManifestPosition(s string) (Position, error) {
+	invalid := fmt.Errorf("invalid from '%s', expecting start, end or a position in C:{commit}/P:{prepare} format", s)
+
+	tokens := strings.Split(s, "/")
+	if len(tokens) != 2 || !strings.HasPrefix(tokens[0], "C:") || !strings.HasPrefix(tokens[1], "P:") {
+		return Position{}, invalid
+	}
+
+	commit, err := strconv.ParseUint(strings.TrimPrefix(tokens[0], "C:"), 10, 64)
+	if err != nil {
+		return Position{}, invalid
+	}
+
+	prepare, err := strconv.ParseUint(strings.TrimPrefix(tokens[1], "P:"), 10, 64)
+	if err != nil {
+		return Position{}, invalid
+	}
+
+	return Position{Commit: commit, Prepare: prepare}, nil
+}
+
+func (raw manifestSettings) toSubscriptionSettings() (SubscriptionSettings, error) {
+	settings := SubscriptionSettingsDefault()
+
+	if raw.ResolveLinkTos != nil {
+		settings.ResolveLinkTos = *raw.ResolveLinkTos
+	}
+	if raw.ExtraStatistics != nil {
+		settings.ExtraStatistics = *raw.ExtraStatistics
+	}
+	if raw.MaxRetryCount != nil {
+		settings.MaxRetryCount = *raw.MaxRetryCount
+	}
+	if raw.MinCheckpointCount != nil {
+		settings.MinCheckpointCount = *raw.MinCheckpointCount
+	}
+	if raw.MaxCheckpointCount != nil {
+		settings.MaxCheckpointCount = *raw.MaxCheckpointCount
+	}
+	if raw.MaxSubscriberCount != nil {
+		settings.MaxSubscriberCount = *raw.MaxSubscriberCount
+	}
+	if raw.LiveBufferSize != nil {
+		settings.LiveBufferSize = *raw.LiveBufferSize
+	}
+	if raw.ReadBatchSize != nil {
+		settings.ReadBatchSize = *raw.ReadBatchSize
+	}
+	if raw.HistoryBufferSize != nil {
+		settings.HistoryBufferSize = *raw.HistoryBufferSize
+	}
+	if raw.MessageTimeoutInMs != nil {
+		settings.MessageTimeoutInMs = *raw.MessageTimeoutInMs
+	}
+	if raw.CheckpointAfterInMs != nil {
+		settings.CheckpointAfterInMs = *raw.CheckpointAfterInMs
+	}
+	if raw.ConsumerStrategy != nil {
+		strategy, err := parseConsumerStrategy(*raw.ConsumerStrategy)
+		if err != nil {
+			return settings, err
+		}
+
+		settings.NamedConsumerStrategy = strategy
+	}
+
+	return settings, nil
+}
+
+func parseConsumerStrategy(s string) (ConsumerStrategy, error) {
+	switch strings.ToLower(s) {
+	case "roundrobin":
+		return ConsumerStrategy_RoundRobin, nil
+	case "dispatchtosingle":
+		return ConsumerStrategy_DispatchToSingle, nil
+	case "pinned":
+		return ConsumerStrategy_Pinned, nil
+	case "pinnedbycorrelation":
+		return ConsumerStrategy_PinnedByCorrelation, nil
+	default:
+		return 0, fmt.Errorf("invalid consumer strategy '%s'", s)
+	}
+}
+
+// ApplyPersistentSubscriptionManifest ensures every group declared in the manifest exists with the
+// declared settings, in the order of the manifest, see EnsurePersistentSubscription. It stops at the
+// first error and returns the changes applied so far.
+func (client *Client) ApplyPersistentSubscriptionManifest(
+	ctx context.Context,
+	manifest *PersistentSubscriptionManifest,
+	opts ApplyManifestOptions,
+) ([]PersistentSubscriptionManifestChange, error) {
+	var changes []PersistentSubscriptionManifestChange
+
+	for _, declaration := range manifest.Groups {
+		streamOptions := declaration.StreamOptions
+		allOptions := declaration.AllOptions
+		if opts.Authenticated != nil {
+			streamOptions.Authenticated = opts.Authenticated
+			allOptions.Authenticated = opts.Authenticated
+		}
+
+		change := PersistentSubscriptionManifestChange{
+			StreamName: declaration.StreamName,
+			GroupName:  declaration.GroupName,
+		}
+
+		var err error
+		if opts.DryRun {
+			authenticated := streamOptions.Authenticated
+			if declaration.IsAll() {
+				authenticated = allOptions.Authenticated
+			}
+
+			change.Action, err = client.persistentSubscriptionAction(ctx, declaration.StreamName, declaration.GroupName, declaration.settings(), authenticated)
+		} else {
+			var result *EnsurePersistentSubscriptionResult
+			if declaration.IsAll() {
+				result, err = client.EnsurePersistentSubscriptionAll(ctx, declaration.GroupName, allOptions)
+			} else {
+				result, err = client.EnsurePersistentSubscription(ctx, declaration.StreamName, declaration.GroupName, streamOptions)
+			}
+
+			if err == nil {
+				change.Action = result.Action()
+			}
+		}
+
+		if err != nil {
+			return changes, fmt.Errorf("failed to ensure group '%s' on '%s': %w", declaration.GroupName, declaration.StreamName, err)
+		}
+
+		changes = append(changes, change)
+	}
+
+	return changes, nil
+}
+
+// persistentSubscriptionAction tells what ensuring a group with the given settings would do.
+func (client *Client) persistentSubscriptionAction(
+	ctx context.Context,
+	streamName string,
+	groupName string,
+	settings *SubscriptionSettings,
+	authenticated *Credentials,
+) (ManifestAction, error) {
+	existing, err := client.getPersistentSubscriptionSettings(ctx, streamName, groupName, authenticated)
+	if err != nil {
+		return 0, err
+	}
+
+	if existing == nil {
+		return ManifestAction_Create, nil
+	}
+
+	declared := SubscriptionSettingsDefault()
+	if settings != nil {
+		declared = *settings
+	}
+
+	if *existing == declared {
+		return ManifestAction_Unchanged, nil
+	}
+
+	return ManifestAction_Update, nil
+}
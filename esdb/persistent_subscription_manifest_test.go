@@ -0,0 +1,228 @@
+package esdb_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/EventStore/EventStore-Client-Go/esdb"
+	"github.com/EventStore/EventStore-Client-Go/protos/persistent"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+func TestParsePersistentSubscriptionManifestYaml(t *testing.T) {
+	manifest, err := esdb.ParsePersistentSubscriptionManifest([]byte(`
+groups:
+  - stream: orders
+    group: billing
+    from: start
+    settings:
+      maxRetryCount: 5
+      consumerStrategy: Pinned
+  - stream: invoices
+    group: billing
+    from: "42"
+  - stream: $all
+    group: audit
+    from: C:10/P:8
+    maxSearchWindow: 64
+    filter:
+      type: streamName
+      prefixes: [orders-]
+`))
+
+	require.NoError(t, err)
+	require.Len(t, manifest.Groups, 3)
+
+	orders := manifest.Groups[0]
+	assert.Equal(t, "orders", orders.StreamName)
+	assert.Equal(t, "billing", orders.GroupName)
+	assert.False(t, orders.IsAll())
+	assert.Equal(t, esdb.Start{}, orders.StreamOptions.From)
+	assert.Equal(t, int32(5), orders.StreamOptions.Settings.MaxRetryCount)
+	assert.Equal(t, esdb.ConsumerStrategy_Pinned, orders.StreamOptions.Settings.NamedConsumerStrategy)
+	assert.Equal(t, esdb.SubscriptionSettingsDefault().ReadBatchSize, orders.StreamOptions.Settings.ReadBatchSize)
+
+	assert.Equal(t, esdb.Revision(42), manifest.Groups[1].StreamOptions.From)
+
+	audit := manifest.Groups[2]
+	assert.Equal(t, "$all", audit.StreamName)
+	assert.Equal(t, "audit", audit.GroupName)
+	assert.True(t, audit.IsAll())
+	assert.Equal(t, esdb.Position{Commit: 10, Prepare: 8}, audit.AllOptions.From)
+	assert.Equal(t, 64, audit.AllOptions.MaxSearchWindow)
+	require.NotNil(t, audit.AllOptions.Filter)
+	assert.Equal(t, esdb.StreamFilterType, audit.AllOptions.Filter.Type)
+	assert.Equal(t, []string{"orders-"}, audit.AllOptions.Filter.Prefixes)
+}
+
+func TestParsePersistentSubscriptionManifestJson(t *testing.T) {
+	manifest, err := esdb.ParsePersistentSubscriptionManifest([]byte(`{
+		"groups": [
+			{"stream": "orders", "group": "billing", "from": "end", "settings": {"resolveLinkTos": true}}
+		]
+	}`))
+
+	require.NoError(t, err)
+	require.Len(t, manifest.Groups, 1)
+	assert.Equal(t, esdb.End{}, manifest.Groups[0].StreamOptions.From)
+	assert.True(t, manifest.Groups[0].StreamOptions.Settings.ResolveLinkTos)
+}
+
+func TestParsePersistentSubscriptionManifestErrors(t *testing.T) {
+	cases := map[string]string{
+		"unknown field":      "groups:\n  - stream: a\n    group: b\n    settings:\n      maxRetries: 1\n",
+		"no stream":          "groups:\n  - group: b\n",
+		"no group":           "groups:\n  - stream: a\n",
+		"duplicate":          "groups:\n  - stream: a\n    group: b\n  - stream: a\n    group: b\n",
+		"invalid revision":   "groups:\n  - stream: a\n    group: b\n    from: later\n",
+		"invalid position":   "groups:\n  - stream: $all\n    group: b\n    from: 12\n",
+		"invalid strategy":   "groups:\n  - stream: a\n    group: b\n    settings:\n      consumerStrategy: Fastest\n",
+		"filter on stream":   "groups:\n  - stream: a\n    group: b\n    filter:\n      type: eventType\n      regex: ^foo\n",
+		"invalid filtertype": "groups:\n  - stream: $all\n    group: b\n    filter:\n      type: category\n      regex: ^foo\n",
+		"invalid settings":   "groups:\n  - stream: a\n    group: b\n    settings:\n      maxRetryCount: -1\n",
+	}
+
+	for name, document := range cases {
+		t.Run(name, func(t *testing.T) {
+			manifest, err := esdb.ParsePersistentSubscriptionManifest([]byte(document))
+			assert.Error(t, err)
+			assert.Nil(t, manifest)
+		})
+	}
+}
+
+// fakePersistentGroups serves the info of the groups it holds over HTTP, and records the groups
+// created and updated over gRPC.
+type fakePersistentGroups struct {
+	persistent.UnimplementedPersistentSubscriptionsServer
+	lock    sync.Mutex
+	info    map[string]string
+	created []string
+	updated []string
+}
+
+// groupInfo is the body of /subscriptions/{stream}/{group}/info for a group with the given
+// settings, strategy being the name of their consumer strategy.
+func groupInfo(settings esdb.SubscriptionSettings, strategy string) string {
+	return fmt.Sprintf(`{"eventStreamId": "orders", "groupName": "billing", "status": "Live", "config": {
+		"resolveLinktos": %t, "startFrom": 0, "messageTimeoutMilliseconds": %d, "extraStatistics": %t,
+		"maxRetryCount": %d, "liveBufferSize": %d, "bufferSize": %d, "readBatchSize": %d,
+		"preferRoundRobin": true, "checkPointAfterMilliseconds": %d, "minCheckPointCount": %d,
+		"maxCheckPointCount": %d, "maxSubscriberCount": %d, "namedConsumerStrategy": %q}}`,
+		settings.ResolveLinkTos, settings.MessageTimeoutInMs, settings.ExtraStatistics,
+		settings.MaxRetryCount, settings.LiveBufferSize, settings.HistoryBufferSize, settings.ReadBatchSize,
+		settings.CheckpointAfterInMs, settings.MinCheckpointCount,
+		settings.MaxCheckpointCount, settings.MaxSubscriberCount, strategy)
+}
+
+func (groups *fakePersistentGroups) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	groups.lock.Lock()
+	defer groups.lock.Unlock()
+
+	info, exists := groups.info[r.URL.Path]
+	if !exists {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(info))
+}
+
+func (groups *fakePersistentGroups) Create(ctx context.Context, req *persistent.CreateReq) (*persistent.CreateResp, error) {
+	groups.lock.Lock()
+	defer groups.lock.Unlock()
+
+	groups.created = append(groups.created, req.GetOptions().GetGroupName())
+	return &persistent.CreateResp{}, nil
+}
+
+func (groups *fakePersistentGroups) Update(ctx context.Context, req *persistent.UpdateReq) (*persistent.UpdateResp, error) {
+	groups.lock.Lock()
+	defer groups.lock.Unlock()
+
+	groups.updated = append(groups.updated, req.GetOptions().GetGroupName())
+	return &persistent.UpdateResp{}, nil
+}
+
+// startFakeNode serves the gRPC services and the HTTP API of a node over TLS on a random local
+// port, and returns its address.
+func startFakeNode(t *testing.T, register func(server *grpc.Server), api http.Handler) string {
+	grpcServer := grpc.NewServer()
+	register(grpcServer)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor == 2 && strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
+			grpcServer.ServeHTTP(w, r)
+			return
+		}
+
+		api.ServeHTTP(w, r)
+	}))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	t.Cleanup(server.Close)
+	t.Cleanup(grpcServer.Stop)
+
+	return server.Listener.Addr().String()
+}
+
+func TestApplyPersistentSubscriptionManifestDiffsSettings(t *testing.T) {
+	manifest, err := esdb.ParsePersistentSubscriptionManifest([]byte(`
+groups:
+  - stream: $all
+    group: audit
+    filter:
+      type: eventType
+      regex: ^order
+  - stream: orders
+    group: billing
+    settings:
+      maxRetryCount: 5
+  - stream: invoices
+    group: billing
+`))
+	require.NoError(t, err)
+
+	defaults := esdb.SubscriptionSettingsDefault()
+	groups := &fakePersistentGroups{
+		info: map[string]string{
+			"/subscriptions/$all/audit/info":     groupInfo(defaults, "RoundRobin"),
+			"/subscriptions/orders/billing/info": groupInfo(defaults, "RoundRobin"),
+		},
+	}
+	address := startFakeNode(t, func(server *grpc.Server) {
+		persistent.RegisterPersistentSubscriptionsServer(server, groups)
+	}, groups)
+
+	client := CreateClient("esdb://"+address+"?tlsVerifyCert=false", t)
+	defer client.Close()
+
+	expected := []esdb.PersistentSubscriptionManifestChange{
+		{StreamName: "$all", GroupName: "audit", Action: esdb.ManifestAction_Unchanged},
+		{StreamName: "orders", GroupName: "billing", Action: esdb.ManifestAction_Update},
+		{StreamName: "invoices", GroupName: "billing", Action: esdb.ManifestAction_Create},
+	}
+
+	// A dry run reports the changes in manifest order without applying them.
+	changes, err := client.ApplyPersistentSubscriptionManifest(context.Background(), manifest, esdb.ApplyManifestOptions{
+		DryRun: true,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, expected, changes)
+	assert.Empty(t, groups.created)
+	assert.Empty(t, groups.updated)
+
+	changes, err = client.ApplyPersistentSubscriptionManifest(context.Background(), manifest, esdb.ApplyManifestOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, expected, changes)
+	assert.Equal(t, []string{"billing"}, groups.created)
+	assert.Equal(t, []string{"billing"}, groups.updated)
+}
@@ -327,3 +327,54 @@ func TestPersistentSubscriptionClosing(t *testing.T) {
 	timedOut = waitWithTimeout(&droppedEvent, time.Duration(5)*time.Second)
 	require.False(t, timedOut, "Timed out waiting for dropped event")
 }
+
+func Test_EnsurePersistentStreamSubscription(t *testing.T) {
+	containerInstance, clientInstance := initializeContainerAndClient(t)
+	defer func() {
+		err := clientInstance.Close()
+		require.NoError(t, err)
+	}()
+	defer containerInstance.Close()
+
+	streamID := "someStream"
+	pushEventToStream(t, clientInstance, streamID)
+
+	result, err := clientInstance.EnsurePersistentSubscription(
+		context.Background(),
+		streamID,
+		"Group 1",
+		esdb.PersistentStreamSubscriptionOptions{},
+	)
+
+	require.NoError(t, err)
+	require.True(t, result.Created)
+
+	settings := esdb.SubscriptionSettingsDefault()
+	settings.MaxRetryCount = 3
+
+	result, err = clientInstance.EnsurePersistentSubscription(
+		context.Background(),
+		streamID,
+		"Group 1",
+		esdb.PersistentStreamSubscriptionOptions{
+			Settings: &settings,
+		},
+	)
+
+	require.NoError(t, err)
+	require.False(t, result.Created)
+	require.True(t, result.Updated)
+
+	// The group already has the declared settings.
+	result, err = clientInstance.EnsurePersistentSubscription(
+		context.Background(),
+		streamID,
+		"Group 1",
+		esdb.PersistentStreamSubscriptionOptions{
+			Settings: &settings,
+		},
+	)
+
+	require.NoError(t, err)
+	require.Equal(t, esdb.ManifestAction_Unchanged, result.Action())
+}
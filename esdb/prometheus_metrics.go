@@ -0,0 +1,255 @@
+package esdb
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultLatencyBuckets are the upper bounds, in seconds, of the latency histograms of
+// PrometheusMetrics.
+var DefaultLatencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// PrometheusMetrics is a Metrics keeping its measurements in memory and exposing them in the
+// Prometheus text format, either by serving them over HTTP or with WriteTo. Every series has a
+// connection label, the ConnectionName of the client. It exposes:
+//   - esdb_operation_duration_seconds: histogram of the operations by operation.
+//   - esdb_operation_errors_total: failed operations by operation and ErrorType.
+//   - esdb_appended_bytes_total and esdb_read_bytes_total.
+//   - esdb_discovery_attempts_total and esdb_discovery_duration_seconds, by result.
+//   - esdb_reconnects_total: reconnections triggered by failed operations, by reason.
+//   - esdb_active_subscriptions.
+//   - esdb_subscription_events_delivered_total: by subscription, removed once it stops.
+type PrometheusMetrics struct {
+	lock    sync.Mutex
+	buckets []float64
+	// Series are keyed by their rendered labels.
+	operations          map[string]*histogram
+	errors              map[string]uint64
+	appendedBytes       map[string]uint64
+	readBytes           map[string]uint64
+	discoveryAttempts   map[string]uint64
+	discoveryDurations  map[string]*histogram
+	reconnects          map[string]uint64
+	activeSubscriptions map[string]uint64
+	delivered           map[string]uint64
+}
+
+// NewPrometheusMetrics creates a PrometheusMetrics whose histograms use the given buckets, in
+// seconds, DefaultLatencyBuckets when none is given.
+func NewPrometheusMetrics(buckets ...float64) *PrometheusMetrics {
+	if len(buckets) == 0 {
+		buckets = DefaultLatencyBuckets
+	}
+
+	sorted := append([]float64(nil), buckets...)
+	sort.Float64s(sorted)
+
+	return &PrometheusMetrics{
+		buckets:             sorted,
+		operations:          make(map[string]*histogram),
+		errors:              make(map[string]uint64),
+		appendedBytes:       make(map[string]uint64),
+		readBytes:           make(map[string]uint64),
+		discoveryAttempts:   make(map[string]uint64),
+		discoveryDurations:  make(map[string]*histogram),
+		reconnects:          make(map[string]uint64),
+		activeSubscriptions: make(map[string]uint64),
+		delivered:           make(map[string]uint64),
+	}
+}
+
+type histogram struct {
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+func (metrics *PrometheusMetrics) observe(histograms map[string]*histogram, labels string, duration time.Duration) {
+	observed, exists := histograms[labels]
+	if !exists {
+		observed = &histogram{counts: make([]uint64, len(metrics.buckets))}
+		histograms[labels] = observed
+	}
+
+	seconds := duration.Seconds()
+	for i, bound := range metrics.buckets {
+		if seconds <= bound {
+			observed.counts[i]++
+		}
+	}
+
+	observed.count++
+	observed.sum += seconds
+}
+
+func (metrics *PrometheusMetrics) OperationCompleted(connectionName string, operation string, duration time.Duration, err error) {
+	metrics.lock.Lock()
+	defer metrics.lock.Unlock()
+
+	metrics.observe(metrics.operations, labels("connection", connectionName, "operation", operation), duration)
+	if err != nil {
+		metrics.errors[labels("connection", connectionName, "operation", operation, "error", ErrorType(err))]++
+	}
+}
+
+func (metrics *PrometheusMetrics) BytesAppended(connectionName string, bytes int) {
+	metrics.lock.Lock()
+	defer metrics.lock.Unlock()
+	metrics.appendedBytes[labels("connection", connectionName)] += uint64(bytes)
+}
+
+func (metrics *PrometheusMetrics) BytesRead(connectionName string, bytes int) {
+	metrics.lock.Lock()
+	defer metrics.lock.Unlock()
+	metrics.readBytes[labels("connection", connectionName)] += uint64(bytes)
+}
+
+func (metrics *PrometheusMetrics) DiscoveryCompleted(connectionName string, attempts int, duration time.Duration, err error) {
+	metrics.lock.Lock()
+	defer metrics.lock.Unlock()
+
+	result := "success"
+	if err != nil {
+		result = "failure"
+	}
+
+	series := labels("connection", connectionName, "result", result)
+	metrics.discoveryAttempts[series] += uint64(attempts)
+	metrics.observe(metrics.discoveryDurations, series, duration)
+}
+
+func (metrics *PrometheusMetrics) Reconnected(connectionName string, reason string) {
+	metrics.lock.Lock()
+	defer metrics.lock.Unlock()
+	metrics.reconnects[labels("connection", connectionName, "reason", reason)]++
+}
+
+func (metrics *PrometheusMetrics) SubscriptionStarted(connectionName string, subscriptionId string) {
+	metrics.lock.Lock()
+	defer metrics.lock.Unlock()
+
+	metrics.activeSubscriptions[labels("connection", connectionName)]++
+	metrics.delivered[labels("connection", connectionName, "subscription", subscriptionId)] += 0
+}
+
+func (metrics *PrometheusMetrics) SubscriptionStopped(connectionName string, subscriptionId string, reason SubscriptionDropReason) {
+	metrics.lock.Lock()
+	defer metrics.lock.Unlock()
+
+	metrics.activeSubscriptions[labels("connection", connectionName)]--
+	delete(metrics.delivered, labels("connection", connectionName, "subscription", subscriptionId))
+}
+
+func (metrics *PrometheusMetrics) EventDelivered(connectionName string, subscriptionId string) {
+	metrics.lock.Lock()
+	defer metrics.lock.Unlock()
+	metrics.delivered[labels("connection", connectionName, "subscription", subscriptionId)]++
+}
+
+// ServeHTTP serves the metrics in the Prometheus text format.
+func (metrics *PrometheusMetrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	metrics.WriteTo(w)
+}
+
+// WriteTo writes the metrics in the Prometheus text format.
+func (metrics *PrometheusMetrics) WriteTo(w io.Writer) (int64, error) {
+	metrics.lock.Lock()
+	defer metrics.lock.Unlock()
+
+	out := &countingWriter{writer: bufio.NewWriter(w)}
+
+	metrics.writeHistograms(out, "esdb_operation_duration_seconds", "Duration of the client operations.", metrics.operations)
+	writeSeries(out, "esdb_operation_errors_total", "Failed client operations.", "counter", metrics.errors)
+	writeSeries(out, "esdb_appended_bytes_total", "Size of the data and metadata of the appended events.", "counter", metrics.appendedBytes)
+	writeSeries(out, "esdb_read_bytes_total", "Size of the data and metadata of the read and delivered events.", "counter", metrics.readBytes)
+	writeSeries(out, "esdb_discovery_attempts_total", "Discovery attempts, by result of the discovery.", "counter", metrics.discoveryAttempts)
+	metrics.writeHistograms(out, "esdb_discovery_duration_seconds", "Duration of the discoveries.", metrics.discoveryDurations)
+	writeSeries(out, "esdb_reconnects_total", "Reconnections triggered by failed operations.", "counter", metrics.reconnects)
+	writeSeries(out, "esdb_active_subscriptions", "Subscriptions currently running.", "gauge", metrics.activeSubscriptions)
+	writeSeries(out, "esdb_subscription_events_delivered_total", "Events delivered by the running subscriptions.", "counter", metrics.delivered)
+
+	if out.err == nil {
+		out.err = out.writer.Flush()
+	}
+
+	return out.written, out.err
+}
+
+func writeSeries(out *countingWriter, name string, help string, kind string, series map[string]uint64) {
+	out.header(name, help, kind)
+
+	keys := make([]string, 0, len(series))
+	for key := range series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		out.printf("%s{%s} %d\n", name, key, series[key])
+	}
+}
+
+func (metrics *PrometheusMetrics) writeHistograms(out *countingWriter, name string, help string, histograms map[string]*histogram) {
+	out.header(name, help, "histogram")
+
+	keys := make([]string, 0, len(histograms))
+	for key := range histograms {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		observed := histograms[key]
+		for i, bound := range metrics.buckets {
+			out.printf("%s_bucket{%s,le=\"%s\"} %d\n", name, key, strconv.FormatFloat(bound, 'g', -1, 64), observed.counts[i])
+		}
+		out.printf("%s_bucket{%s,le=\"+Inf\"} %d\n", name, key, observed.count)
+		out.printf("%s_sum{%s} %s\n", name, key, strconv.FormatFloat(observed.sum, 'g', -1, 64))
+		out.printf("%s_count{%s} %d\n", name, key, observed.count)
+	}
+}
+
+// labels renders label names and values, given in pairs.
+func labels(pairs ...string) string {
+	rendered := make([]string, 0, len(pairs)/2)
+	for i := 0; i+1 < len(pairs); i += 2 {
+		rendered = append(rendered, pairs[i]+"="+quoteLabel(pairs[i+1]))
+	}
+
+	return strings.Join(rendered, ",")
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func quoteLabel(value string) string {
+	return `"` + labelEscaper.Replace(value) + `"`
+}
+
+// countingWriter keeps the first error and the number of bytes written.
+type countingWriter struct {
+	writer  *bufio.Writer
+	written int64
+	err     error
+}
+
+func (out *countingWriter) printf(format string, args ...interface{}) {
+	if out.err != nil {
+		return
+	}
+
+	n, err := fmt.Fprintf(out.writer, format, args...)
+	out.written += int64(n)
+	out.err = err
+}
+
+func (out *countingWriter) header(name string, help string, kind string) {
+	out.printf("# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
@@ -1,10 +1,16 @@
 package esdb
 
+import "time"
+
 type ReadStreamOptions struct {
 	Direction      Direction
 	From           StreamPosition
 	ResolveLinkTos bool
 	Authenticated  *Credentials
+	// Routes the read to a node matching the preference. Defaults to the NodePreference of the
+	// configuration.
+	NodePreference NodePreference
+	Deadline       time.Duration // Defaults to Configuration.DefaultDeadline, -1 disables it.
 }
 
 func (o *ReadStreamOptions) setDefaults() {
@@ -18,6 +24,10 @@ type ReadAllOptions struct {
 	From           AllPosition
 	ResolveLinkTos bool
 	Authenticated  *Credentials
+	// Routes the read to a node matching the preference. Defaults to the NodePreference of the
+	// configuration.
+	NodePreference NodePreference
+	Deadline       time.Duration // Defaults to Configuration.DefaultDeadline, -1 disables it.
 }
 
 func (o *ReadAllOptions) setDefaults() {
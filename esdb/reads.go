@@ -3,24 +3,25 @@ package esdb
 import (
 	"context"
 	"errors"
-	"fmt"
 	"io"
 	"sync"
+	"sync/atomic"
 
 	api "github.com/EventStore/EventStore-Client-Go/protos/streams"
 	"google.golang.org/grpc/metadata"
 )
 
-type readResp struct {
-	event *ResolvedEvent
-	err   *error
-}
-
 type ReadStream struct {
 	client  *grpcClient
-	channel chan (chan readResp)
+	channel chan *ResolvedEvent
+	closing chan struct{}
+	done    chan struct{}
 	cancel  context.CancelFunc
 	once    *sync.Once
+	// Set to 1 once the client shuts the stream down.
+	clientClosed int32
+	// Only written by the read goroutine before done is closed.
+	err error
 }
 
 type readStreamParams struct {
@@ -33,89 +34,95 @@ type readStreamParams struct {
 }
 
 func (stream *ReadStream) Close() {
-	stream.once.Do(stream.cancel)
+	stream.once.Do(func() {
+		close(stream.closing)
+		stream.cancel()
+	})
 }
 
+// Recv returns the next event. Once the stream is over, it keeps returning io.EOF, or the error
+// that ended the stream.
 func (stream *ReadStream) Recv() (*ResolvedEvent, error) {
-	promise := make(chan readResp)
+	select {
+	case event, ok := <-stream.channel:
+		if ok {
+			return event, nil
+		}
+	case <-stream.closing:
+	}
 
-	stream.channel <- promise
+	<-stream.done
+	return nil, stream.err
+}
 
-	resp, isOk := <-promise
+// shutdown closes the stream because the client is shutting down, Recv then fails with
+// ErrClientClosed.
+func (stream *ReadStream) shutdown() {
+	atomic.StoreInt32(&stream.clientClosed, 1)
+	stream.Close()
+}
 
-	if !isOk {
-		return nil, fmt.Errorf("read stream has been termimated")
+func (stream *ReadStream) isClosing() bool {
+	select {
+	case <-stream.closing:
+		return true
+	default:
+		return false
 	}
+}
 
-	if resp.err != nil {
-		return nil, *resp.err
+// deliver blocks until a consumer takes the event or the stream is closed.
+func (stream *ReadStream) deliver(event *ResolvedEvent) {
+	select {
+	case stream.channel <- event:
+	case <-stream.closing:
 	}
-
-	return resp.event, nil
 }
 
 func newReadStream(params readStreamParams, firstEvt ResolvedEvent) *ReadStream {
-	channel := make(chan (chan readResp))
-	once := new(sync.Once)
+	stream := &ReadStream{
+		client:  params.client,
+		channel: make(chan *ResolvedEvent),
+		closing: make(chan struct{}),
+		done:    make(chan struct{}),
+		cancel:  params.cancel,
+		once:    new(sync.Once),
+	}
 
 	// It is not safe to consume a stream in different goroutines. This is why we only consume
-	// the stream in a dedicated goroutine.
-	//
-	// Current implementation doesn't terminate the goroutine. When a stream is terminated (without or with an error),
-	// we keep user requests coming but will always send back the last errror messages we got.
-	// This implementation is simple to maintain while letting the user sharing their subscription
-	// among as many goroutines as they want.
-	go func() {
-		var lastError *error
-		cachedEvent := &firstEvt
-		for {
-			resp := <-channel
-
-			if cachedEvent != nil {
-				resp <- readResp{
-					event: cachedEvent,
-				}
+	// the stream in a dedicated goroutine. The goroutine exits once the stream ends, either
+	// because it was fully read, failed or was closed.
+	untrack := params.client.operations.track(stream.shutdown)
 
-				cachedEvent = nil
-				continue
-			}
+	go func() {
+		defer untrack()
+		defer close(stream.done)
+		defer close(stream.channel)
 
-			if lastError != nil {
-				resp <- readResp{
-					err: lastError,
-				}
-
-				continue
-			}
+		metrics := metricsOf(&params.client.config)
+		metrics.BytesRead(eventSize(&firstEvt))
+		stream.deliver(&firstEvt)
 
+		for {
 			result, err := params.inner.Recv()
 
 			if err != nil {
-				if !errors.Is(err, io.EOF) {
+				// Closing cancels the call, that's not a connection issue.
+				if atomic.LoadInt32(&stream.clientClosed) == 1 {
+					err = ErrClientClosed
+				} else if !errors.Is(err, io.EOF) && !stream.isClosing() {
 					err = params.client.handleError(params.handle, params.headers, params.trailers, err)
 				}
 
-				lastError = &err
-
-				resp <- readResp{
-					err: &err,
-				}
-
-				continue
+				stream.err = err
+				return
 			}
 
 			resolvedEvent := getResolvedEventFromProto(result.GetEvent())
-			resp <- readResp{
-				event: &resolvedEvent,
-			}
+			metrics.BytesRead(eventSize(&resolvedEvent))
+			stream.deliver(&resolvedEvent)
 		}
-
 	}()
 
-	return &ReadStream{
-		client:  params.client,
-		channel: channel,
-		once:    once,
-		cancel:  params.cancel,
-	}
+	return stream
 }
@@ -0,0 +1,116 @@
+package esdb_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/EventStore/EventStore-Client-Go/esdb"
+	gossipApi "github.com/EventStore/EventStore-Client-Go/protos/gossip"
+	api "github.com/EventStore/EventStore-Client-Go/protos/streams"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// routedCall records which node served a call and whether the call required the leader.
+type routedCall struct {
+	node           string
+	requiresLeader bool
+}
+
+type callRecorder struct {
+	lock  sync.Mutex
+	calls []routedCall
+}
+
+func (recorder *callRecorder) record(ctx context.Context, node string) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get("requires-leader")
+
+	recorder.lock.Lock()
+	defer recorder.lock.Unlock()
+	recorder.calls = append(recorder.calls, routedCall{
+		node:           node,
+		requiresLeader: len(values) > 0 && values[0] == "true",
+	})
+}
+
+func (recorder *callRecorder) last() routedCall {
+	recorder.lock.Lock()
+	defer recorder.lock.Unlock()
+	return recorder.calls[len(recorder.calls)-1]
+}
+
+// recordingNode serves gossip from a shared view, and reads and deletes recorded as served by the
+// node.
+func recordingNode(t *testing.T, name string, gossip *mutableGossip, recorder *callRecorder) string {
+	streams := &fakeStreamsServer{
+		read: func(req *api.ReadReq, server api.Streams_ReadServer) error {
+			recorder.record(server.Context(), name)
+			return server.Send(fakeReadEvent(name, 0))
+		},
+		delete: func(ctx context.Context, req *api.DeleteReq) (*api.DeleteResp, error) {
+			recorder.record(ctx, name)
+			return &api.DeleteResp{
+				PositionOption: &api.DeleteResp_Position_{Position: &api.DeleteResp_Position{}},
+			}, nil
+		},
+	}
+
+	return startFakeServer(t, func(server *grpc.Server) {
+		gossipApi.RegisterGossipServer(server, gossip.server())
+		api.RegisterStreamsServer(server, streams)
+	})
+}
+
+func TestOperationsAreRoutedByNodePreference(t *testing.T) {
+	gossip := &mutableGossip{}
+	recorder := &callRecorder{}
+	leader := recordingNode(t, "leader", gossip, recorder)
+	follower := recordingNode(t, "follower", gossip, recorder)
+	gossip.set(
+		fakeMember(leader, gossipApi.MemberInfo_Leader),
+		fakeMember(follower, gossipApi.MemberInfo_Follower),
+	)
+
+	client := CreateClient("esdb+discover://"+leader+"?tls=false&nodepreference=follower", t)
+	defer client.Close()
+
+	read := func(preference esdb.NodePreference) {
+		stream, err := client.ReadStream(context.Background(), "orders", esdb.ReadStreamOptions{NodePreference: preference}, 1)
+		require.NoError(t, err)
+		stream.Close()
+	}
+
+	read("")
+	assert.Equal(t, routedCall{node: "follower"}, recorder.last())
+
+	read(esdb.NodePreference_Leader)
+	assert.Equal(t, routedCall{node: "leader", requiresLeader: true}, recorder.last())
+
+	read(esdb.NodePreference_Follower)
+	assert.Equal(t, routedCall{node: "follower"}, recorder.last())
+
+	_, err := client.DeleteStream(context.Background(), "orders", esdb.DeleteStreamOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, routedCall{node: "leader", requiresLeader: true}, recorder.last())
+}
+
+func TestSingleNodeSharesItsConnectionAcrossPreferences(t *testing.T) {
+	recorder := &callRecorder{}
+	address := recordingNode(t, "single", &mutableGossip{}, recorder)
+
+	client := CreateClient("esdb://"+address+"?tls=false&nodepreference=follower", t)
+	defer client.Close()
+
+	stream, err := client.ReadStream(context.Background(), "orders", esdb.ReadStreamOptions{}, 1)
+	require.NoError(t, err)
+	stream.Close()
+	assert.Equal(t, routedCall{node: "single"}, recorder.last())
+
+	_, err = client.DeleteStream(context.Background(), "orders", esdb.DeleteStreamOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, routedCall{node: "single", requiresLeader: true}, recorder.last())
+}
@@ -0,0 +1,133 @@
+package esdb
+
+import (
+	"context"
+	"sync"
+)
+
+// operationTracker counts the operations in flight and keeps the subscriptions and read streams
+// open, so that Client.Shutdown can wait for the former and close the latter. Its methods do
+// nothing on a nil tracker.
+type operationTracker struct {
+	lock     sync.Mutex
+	closing  bool
+	inFlight int
+	// Closed once the tracker is closing and no operation is in flight.
+	idle    chan struct{}
+	streams map[int]func()
+	nextId  int
+}
+
+func newOperationTracker() *operationTracker {
+	return &operationTracker{
+		idle:    make(chan struct{}),
+		streams: make(map[int]func()),
+	}
+}
+
+// begin registers an operation, end must be called once it completes. Operations are refused
+// with ErrClientClosed once the client is shutting down.
+func (tracker *operationTracker) begin() (end func(), err error) {
+	if tracker == nil {
+		return func() {}, nil
+	}
+
+	tracker.lock.Lock()
+	defer tracker.lock.Unlock()
+
+	if tracker.closing {
+		return nil, ErrClientClosed
+	}
+
+	tracker.inFlight++
+	var once sync.Once
+
+	return func() {
+		once.Do(func() {
+			tracker.lock.Lock()
+			defer tracker.lock.Unlock()
+
+			tracker.inFlight--
+			if tracker.closing && tracker.inFlight == 0 {
+				close(tracker.idle)
+			}
+		})
+	}, nil
+}
+
+// track registers an open subscription or read stream, shutdown ending it because the client is
+// shutting down. untrack must be called once it is over. A stream opened while the client is
+// shutting down is ended right away.
+func (tracker *operationTracker) track(shutdown func()) (untrack func()) {
+	if tracker == nil {
+		return func() {}
+	}
+
+	tracker.lock.Lock()
+	if tracker.closing {
+		tracker.lock.Unlock()
+		shutdown()
+		return func() {}
+	}
+
+	id := tracker.nextId
+	tracker.nextId++
+	tracker.streams[id] = shutdown
+	tracker.lock.Unlock()
+
+	return func() {
+		tracker.lock.Lock()
+		defer tracker.lock.Unlock()
+		delete(tracker.streams, id)
+	}
+}
+
+// shutdown refuses new operations, ends the open streams and returns a channel closed once no
+// operation is in flight.
+func (tracker *operationTracker) shutdown() <-chan struct{} {
+	if tracker == nil {
+		idle := make(chan struct{})
+		close(idle)
+		return idle
+	}
+
+	tracker.lock.Lock()
+	if tracker.closing {
+		tracker.lock.Unlock()
+		return tracker.idle
+	}
+
+	tracker.closing = true
+	if tracker.inFlight == 0 {
+		close(tracker.idle)
+	}
+
+	streams := tracker.streams
+	tracker.streams = make(map[int]func())
+	tracker.lock.Unlock()
+
+	for _, shutdown := range streams {
+		shutdown()
+	}
+
+	return tracker.idle
+}
+
+// Shutdown closes the client gracefully. Operations started afterwards fail with ErrClientClosed,
+// open subscriptions are dropped with SubscriptionDropReason_ClientClosed and open read streams
+// fail with ErrClientClosed. Shutdown then waits for the operations in flight, like appends or
+// deletes, to complete. If ctx is done first, the client is closed anyway, which cancels them, and
+// ctx.Err() is returned.
+func (client *Client) Shutdown(ctx context.Context) error {
+	idle := client.grpcClient.operations.shutdown()
+
+	var err error
+	select {
+	case <-idle:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	client.grpcClient.close()
+	return err
+}
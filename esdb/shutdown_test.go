@@ -0,0 +1,136 @@
+package esdb_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/EventStore/EventStore-Client-Go/esdb"
+	api "github.com/EventStore/EventStore-Client-Go/protos/streams"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// holdingStreamsServer holds deletes until released, and keeps reads and subscriptions open.
+func holdingStreamsServer(started chan<- struct{}, release <-chan struct{}) *fakeStreamsServer {
+	return &fakeStreamsServer{
+		read: func(req *api.ReadReq, server api.Streams_ReadServer) error {
+			if req.GetOptions().GetSubscription() != nil {
+				if err := server.Send(fakeSubscriptionConfirmation()); err != nil {
+					return err
+				}
+			} else if err := server.Send(fakeReadEvent("orders", 0)); err != nil {
+				return err
+			}
+
+			<-server.Context().Done()
+			return server.Context().Err()
+		},
+		delete: func(ctx context.Context, req *api.DeleteReq) (*api.DeleteResp, error) {
+			started <- struct{}{}
+
+			select {
+			case <-release:
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+
+			return &api.DeleteResp{
+				PositionOption: &api.DeleteResp_Position_{Position: &api.DeleteResp_Position{}},
+			}, nil
+		},
+	}
+}
+
+func TestShutdownWaitsForOperationsInFlight(t *testing.T) {
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	client := createFakeServerClient(t, holdingStreamsServer(started, release))
+
+	subscription, err := client.SubscribeToStream(context.Background(), "orders", esdb.SubscribeToStreamOptions{})
+	require.NoError(t, err)
+
+	stream, err := client.ReadStream(context.Background(), "orders", esdb.ReadStreamOptions{}, 10)
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	require.NoError(t, err)
+
+	deleted := make(chan error, 1)
+	go func() {
+		_, err := client.DeleteStream(context.Background(), "orders", esdb.DeleteStreamOptions{})
+		deleted <- err
+	}()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	shutdown := make(chan error, 1)
+	go func() { shutdown <- client.Shutdown(ctx) }()
+
+	// Open streams are closed right away.
+	event := subscription.Recv()
+	require.NotNil(t, event.SubscriptionDropped)
+	assert.Equal(t, esdb.SubscriptionDropReason_ClientClosed, event.SubscriptionDropped.Reason)
+	assert.True(t, errors.Is(event.SubscriptionDropped.Error, esdb.ErrClientClosed))
+
+	_, err = stream.Recv()
+	assert.True(t, errors.Is(err, esdb.ErrClientClosed), err)
+
+	// New operations are refused while the delete in flight is waited for.
+	_, err = client.DeleteStream(context.Background(), "orders", esdb.DeleteStreamOptions{})
+	assert.True(t, errors.Is(err, esdb.ErrClientClosed), err)
+
+	_, err = client.SubscribeToStream(context.Background(), "orders", esdb.SubscribeToStreamOptions{})
+	assert.True(t, errors.Is(err, esdb.ErrClientClosed), err)
+
+	select {
+	case err := <-shutdown:
+		t.Fatalf("shutdown returned before the delete completed: %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(release)
+	assert.NoError(t, <-deleted)
+	assert.NoError(t, <-shutdown)
+}
+
+func TestShutdownForceClosesOnceContextIsDone(t *testing.T) {
+	started := make(chan struct{}, 1)
+	client := createFakeServerClient(t, holdingStreamsServer(started, nil))
+
+	deleted := make(chan error, 1)
+	go func() {
+		_, err := client.DeleteStream(context.Background(), "orders", esdb.DeleteStreamOptions{})
+		deleted <- err
+	}()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	err := client.Shutdown(ctx)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded), err)
+
+	select {
+	case err := <-deleted:
+		assert.Error(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the delete in flight wasn't cancelled")
+	}
+}
+
+func TestCloseDropsSubscriptionsAsClientClosed(t *testing.T) {
+	client := createFakeServerClient(t, holdingStreamsServer(nil, nil))
+
+	subscription, err := client.SubscribeToStream(context.Background(), "orders", esdb.SubscribeToStreamOptions{})
+	require.NoError(t, err)
+
+	client.Close()
+
+	event := subscription.Recv()
+	require.NotNil(t, event.SubscriptionDropped)
+	assert.Equal(t, esdb.SubscriptionDropReason_ClientClosed, event.SubscriptionDropped.Reason)
+	assert.Equal(t, "ClientClosed", event.SubscriptionDropped.Reason.String())
+}
@@ -1,9 +1,23 @@
 package esdb
 
+import "time"
+
 type SubscribeToStreamOptions struct {
 	From           StreamPosition
 	ResolveLinkTos bool
 	Authenticated  *Credentials
+	// Enables sampling the head of $all to compute the lag of the subscription.
+	Stats *SubscriptionStatsOptions
+	// When no message is received for that long, the connection is probed by reading the last
+	// event of the stream and the subscription is dropped if the probe fails. Use 0 to disable.
+	IdleTimeout time.Duration
+	// Number of events buffered ahead of the consumer, see Events. Defaults to 0, in which case
+	// a single event waits for the consumer.
+	EventBufferSize int
+	// Routes the subscription to a node matching the preference. Defaults to the NodePreference of
+	// the configuration.
+	NodePreference NodePreference
+	Deadline       time.Duration // Defaults to Configuration.DefaultDeadline, -1 disables it.
 }
 
 func (o *SubscribeToStreamOptions) setDefaults() {
@@ -19,6 +33,21 @@ type SubscribeToAllOptions struct {
 	CheckpointInterval int
 	Filter             *SubscriptionFilter
 	Authenticated      *Credentials
+	// Enables sampling the head of $all to compute the lag of the subscription.
+	Stats *SubscriptionStatsOptions
+	// When no message is received for that long, a filtered subscription is dropped right away,
+	// as CheckpointInterval guarantees regular checkpoints, so the timeout must be longer than the
+	// time needed to go through CheckpointInterval search windows. Unfiltered subscriptions probe
+	// the connection by reading the last event of $all and are only dropped if the probe fails.
+	// Use 0 to disable.
+	IdleTimeout time.Duration
+	// Number of events buffered ahead of the consumer, see Events. Defaults to 0, in which case
+	// a single event waits for the consumer.
+	EventBufferSize int
+	// Routes the subscription to a node matching the preference. Defaults to the NodePreference of
+	// the configuration.
+	NodePreference NodePreference
+	Deadline       time.Duration // Defaults to Configuration.DefaultDeadline, -1 disables it.
 }
 
 func (o *SubscribeToAllOptions) setDefaults() {
@@ -36,3 +65,14 @@ func (o *SubscribeToAllOptions) setDefaults() {
 		}
 	}
 }
+
+// Validate checks the starting position and the filter, if any. It returns a *ValidationError
+// listing every invalid field.
+func (o SubscribeToAllOptions) Validate() error {
+	err := &ValidationError{}
+
+	validateAllPosition(err, o.From)
+	validateFilterOptions(err, o.Filter, o.MaxSearchWindow, o.CheckpointInterval)
+
+	return err.orNil()
+}
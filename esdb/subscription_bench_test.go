@@ -0,0 +1,112 @@
+package esdb_test
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/EventStore/EventStore-Client-Go/esdb"
+	api "github.com/EventStore/EventStore-Client-Go/protos/streams"
+	"github.com/gofrs/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// endlessReadClient returns the same event until the stream is cancelled, so benchmarks only
+// measure the cost of handing events over to the consumer.
+type endlessReadClient struct {
+	grpc.ClientStream
+	event  *api.ReadResp
+	closed chan struct{}
+}
+
+func newEndlessReadClient() *endlessReadClient {
+	return &endlessReadClient{
+		event:  fakeReadEvent("bench", 0),
+		closed: make(chan struct{}),
+	}
+}
+
+func (client *endlessReadClient) Recv() (*api.ReadResp, error) {
+	select {
+	case <-client.closed:
+		return nil, io.EOF
+	default:
+		return client.event, nil
+	}
+}
+
+func (client *endlessReadClient) Trailer() metadata.MD {
+	return nil
+}
+
+func (client *endlessReadClient) close() {
+	close(client.closed)
+}
+
+func BenchmarkSubscriptionRecv(b *testing.B) {
+	readClient := newEndlessReadClient()
+	subscription := esdb.NewSubscription(nil, readClient.close, readClient, "bench")
+	defer subscription.Close()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if event := subscription.Recv(); event.EventAppeared == nil {
+			b.Fatalf("unexpected event: %+v", event)
+		}
+	}
+}
+
+func BenchmarkSubscriptionEvents(b *testing.B) {
+	readClient := newEndlessReadClient()
+	subscription := esdb.NewSubscription(nil, readClient.close, readClient, "bench")
+	defer subscription.Close()
+
+	events := subscription.Events()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if event := <-events; event.EventAppeared == nil {
+			b.Fatalf("unexpected event: %+v", event)
+		}
+	}
+}
+
+func BenchmarkSubscriptionRecvContext(b *testing.B) {
+	readClient := newEndlessReadClient()
+	subscription := esdb.NewSubscription(nil, readClient.close, readClient, "bench")
+	defer subscription.Close()
+
+	ctx := context.Background()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		event, err := subscription.RecvContext(ctx)
+		if err != nil || event.EventAppeared == nil {
+			b.Fatalf("unexpected event: %+v, %v", event, err)
+		}
+	}
+}
+
+func BenchmarkPersistentSubscriptionRecv(b *testing.B) {
+	event := persistentEventResp(uuid.Must(uuid.NewV4()), "bench", 0, "")
+	readClient := newFakePersistentReadClient(event)
+	subscription := esdb.NewPersistentSubscription(readClient, "bench", readClient.close)
+	defer subscription.Close()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if received := subscription.Recv(); received.EventAppeared == nil {
+			b.Fatalf("unexpected event: %+v", received)
+		}
+		readClient.responses <- event
+	}
+}
@@ -0,0 +1,99 @@
+package esdb
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// subscriptionChannel hands the events read by the goroutine of a subscription to its consumers.
+// The goroutine exits once the subscription is dropped, after which events is closed and dropped
+// tells why.
+type subscriptionChannel struct {
+	events  chan *SubscriptionEvent
+	closing chan struct{}
+	done    chan struct{}
+	once    sync.Once
+	// Set to 1 once the client shuts the subscription down.
+	clientClosed int32
+	// Only written by the subscription goroutine before done is closed.
+	dropped *SubscriptionDropped
+}
+
+func newSubscriptionChannel(bufferSize int) *subscriptionChannel {
+	if bufferSize < 0 {
+		bufferSize = 0
+	}
+
+	return &subscriptionChannel{
+		events:  make(chan *SubscriptionEvent, bufferSize),
+		closing: make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+}
+
+// close tells the goroutine to stop delivering events, cancel is expected to end the gRPC stream.
+func (channel *subscriptionChannel) close(cancel context.CancelFunc) {
+	channel.once.Do(func() {
+		close(channel.closing)
+		cancel()
+	})
+}
+
+// shutdown ends the subscription because the client is shutting down. Unlike close, the consumers
+// still receive the SubscriptionDropped event.
+func (channel *subscriptionChannel) shutdown(cancel context.CancelFunc) {
+	atomic.StoreInt32(&channel.clientClosed, 1)
+	cancel()
+}
+
+func (channel *subscriptionChannel) isClientClosed() bool {
+	return atomic.LoadInt32(&channel.clientClosed) == 1
+}
+
+func (channel *subscriptionChannel) isClosing() bool {
+	select {
+	case <-channel.closing:
+		return true
+	default:
+		return false
+	}
+}
+
+// deliver blocks until a consumer takes the event or the subscription is closed.
+func (channel *subscriptionChannel) deliver(event *SubscriptionEvent) {
+	select {
+	case channel.events <- event:
+	case <-channel.closing:
+	}
+}
+
+// drop delivers the drop event, unless the subscription was closed, then releases the consumers.
+func (channel *subscriptionChannel) drop(dropped *SubscriptionDropped) {
+	channel.dropped = dropped
+	channel.deliver(&SubscriptionEvent{SubscriptionDropped: dropped})
+	close(channel.events)
+	close(channel.done)
+}
+
+// recv returns the next event, or the drop event once the subscription is dropped or closed.
+func (channel *subscriptionChannel) recv(ctx context.Context) (*SubscriptionEvent, error) {
+	if !channel.isClosing() {
+		select {
+		case event, ok := <-channel.events:
+			if ok {
+				return event, nil
+			}
+		case <-channel.closing:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	select {
+	case <-channel.done:
+		return &SubscriptionEvent{SubscriptionDropped: channel.dropped}, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
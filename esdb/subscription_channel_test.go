@@ -0,0 +1,127 @@
+package esdb_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/EventStore/EventStore-Client-Go/esdb"
+	api "github.com/EventStore/EventStore-Client-Go/protos/streams"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubscriptionEventsChannelIsClosedAfterDrop(t *testing.T) {
+	client := createFakeServerClient(t, &fakeStreamsServer{
+		read: func(req *api.ReadReq, server api.Streams_ReadServer) error {
+			if err := server.Send(fakeSubscriptionConfirmation()); err != nil {
+				return err
+			}
+
+			for revision := uint64(0); revision < 3; revision++ {
+				if err := server.Send(fakeReadEvent("orders", revision)); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		},
+	})
+
+	subscription, err := client.SubscribeToStream(context.Background(), "orders", esdb.SubscribeToStreamOptions{
+		EventBufferSize: 2,
+	})
+	require.NoError(t, err)
+	defer subscription.Close()
+
+	var revisions []uint64
+	var dropped *esdb.SubscriptionDropped
+	timeout := time.After(5 * time.Second)
+
+	for done := false; !done; {
+		select {
+		case event, ok := <-subscription.Events():
+			if !ok {
+				done = true
+				break
+			}
+
+			if event.EventAppeared != nil {
+				revisions = append(revisions, event.EventAppeared.OriginalEvent().EventNumber)
+			}
+
+			if event.SubscriptionDropped != nil {
+				dropped = event.SubscriptionDropped
+			}
+		case <-timeout:
+			t.Fatalf("timed out waiting for the events channel to be closed")
+		}
+	}
+
+	assert.Equal(t, []uint64{0, 1, 2}, revisions)
+	require.NotNil(t, dropped)
+	assert.Equal(t, esdb.SubscriptionDropReason_ServerShutdown, dropped.Reason)
+
+	// Recv keeps reporting the drop once the channel is closed.
+	event := subscription.Recv()
+	require.NotNil(t, event.SubscriptionDropped)
+	assert.Equal(t, esdb.SubscriptionDropReason_ServerShutdown, event.SubscriptionDropped.Reason)
+}
+
+func TestSubscriptionRecvContextRespectsCancellation(t *testing.T) {
+	client := createFakeServerClient(t, stallingServer(false, new(int32)))
+
+	subscription, err := client.SubscribeToStream(context.Background(), "orders", esdb.SubscribeToStreamOptions{})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	event, err := subscription.RecvContext(ctx)
+	assert.Nil(t, event)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+
+	// The subscription is still running until it is closed.
+	subscription.Close()
+
+	event, err = subscription.RecvContext(context.Background())
+	require.NoError(t, err)
+	require.NotNil(t, event.SubscriptionDropped)
+	assert.Equal(t, esdb.SubscriptionDropReason_Closed, event.SubscriptionDropped.Reason)
+
+	select {
+	case _, ok := <-subscription.Events():
+		assert.False(t, ok)
+	case <-time.After(5 * time.Second):
+		t.Fatalf("the events channel wasn't closed")
+	}
+}
+
+func TestReadStreamGoroutineStopsOnClose(t *testing.T) {
+	client := createFakeServerClient(t, &fakeStreamsServer{
+		read: func(req *api.ReadReq, server api.Streams_ReadServer) error {
+			for revision := uint64(0); ; revision++ {
+				if err := server.Send(fakeReadEvent("orders", revision)); err != nil {
+					return err
+				}
+			}
+		},
+	})
+
+	stream, err := client.ReadStream(context.Background(), "orders", esdb.ReadStreamOptions{}, 1000)
+	require.NoError(t, err)
+
+	event, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(0), event.OriginalEvent().EventNumber)
+
+	stream.Close()
+
+	_, err = stream.Recv()
+	assert.Error(t, err)
+
+	// Later calls keep returning the same error instead of blocking.
+	_, again := stream.Recv()
+	assert.Equal(t, err, again)
+}
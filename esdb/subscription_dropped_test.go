@@ -0,0 +1,154 @@
+package esdb_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/EventStore/EventStore-Client-Go/esdb"
+	api "github.com/EventStore/EventStore-Client-Go/protos/streams"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// droppingServer confirms subscriptions then ends them with the given error and trailers.
+func droppingServer(err error, trailer metadata.MD) *fakeStreamsServer {
+	return &fakeStreamsServer{
+		read: func(req *api.ReadReq, server api.Streams_ReadServer) error {
+			if err := server.Send(fakeSubscriptionConfirmation()); err != nil {
+				return err
+			}
+
+			server.SetTrailer(trailer)
+			return err
+		},
+	}
+}
+
+func TestSubscriptionDroppedReasonFromServer(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		trailer metadata.MD
+		reason  esdb.SubscriptionDropReason
+		check   func(t *testing.T, err error)
+	}{
+		{
+			name:   "server shutdown",
+			reason: esdb.SubscriptionDropReason_ServerShutdown,
+			check: func(t *testing.T, err error) {
+				assert.True(t, errors.Is(err, esdb.ErrServerShutdown))
+			},
+		},
+		{
+			name:    "stream deleted",
+			err:     status.Error(codes.FailedPrecondition, "stream deleted"),
+			trailer: metadata.Pairs("exception", "stream-deleted", "stream-name", "orders"),
+			reason:  esdb.SubscriptionDropReason_StreamDeleted,
+			check: func(t *testing.T, err error) {
+				var streamDeleted *esdb.StreamDeletedError
+				require.True(t, errors.As(err, &streamDeleted))
+				assert.Equal(t, "orders", streamDeleted.StreamName)
+			},
+		},
+		{
+			name:    "access denied",
+			err:     status.Error(codes.PermissionDenied, "access denied"),
+			trailer: metadata.Pairs("exception", "access-denied"),
+			reason:  esdb.SubscriptionDropReason_AccessDenied,
+			check: func(t *testing.T, err error) {
+				assert.True(t, errors.Is(err, esdb.ErrPermissionDenied))
+			},
+		},
+		{
+			name:   "network failure",
+			err:    status.Error(codes.Unavailable, "transport is closing"),
+			reason: esdb.SubscriptionDropReason_NetworkFailure,
+			check: func(t *testing.T, err error) {
+				assert.Equal(t, codes.Unavailable, status.Code(err))
+			},
+		},
+		{
+			name:   "unknown",
+			err:    status.Error(codes.Internal, "boom"),
+			reason: esdb.SubscriptionDropReason_Unknown,
+			check: func(t *testing.T, err error) {
+				assert.Equal(t, codes.Internal, status.Code(err))
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client := createFakeServerClient(t, droppingServer(test.err, test.trailer))
+
+			subscription, err := client.SubscribeToStream(context.Background(), "orders", esdb.SubscribeToStreamOptions{})
+			require.NoError(t, err)
+			defer subscription.Close()
+
+			event := recvWithTimeout(t, subscription, 5*time.Second)
+			require.NotNil(t, event.SubscriptionDropped)
+			assert.Equal(t, test.reason, event.SubscriptionDropped.Reason)
+			test.check(t, event.SubscriptionDropped.Error)
+
+			// Later receives keep reporting the same drop.
+			again := recvWithTimeout(t, subscription, 5*time.Second)
+			require.NotNil(t, again.SubscriptionDropped)
+			assert.Equal(t, test.reason, again.SubscriptionDropped.Reason)
+		})
+	}
+}
+
+func TestSubscriptionDroppedReasonOnClose(t *testing.T) {
+	client := createFakeServerClient(t, stallingServer(false, new(int32)))
+
+	subscription, err := client.SubscribeToStream(context.Background(), "orders", esdb.SubscribeToStreamOptions{})
+	require.NoError(t, err)
+
+	subscription.Close()
+
+	event := recvWithTimeout(t, subscription, 5*time.Second)
+	require.NotNil(t, event.SubscriptionDropped)
+	assert.Equal(t, esdb.SubscriptionDropReason_Closed, event.SubscriptionDropped.Reason)
+	assert.True(t, errors.Is(event.SubscriptionDropped.Error, esdb.ErrSubscriptionClosed))
+}
+
+func TestSubscriptionDroppedReasonOnContextCancellation(t *testing.T) {
+	client := createFakeServerClient(t, stallingServer(false, new(int32)))
+	ctx, cancel := context.WithCancel(context.Background())
+
+	subscription, err := client.SubscribeToStream(ctx, "orders", esdb.SubscribeToStreamOptions{})
+	require.NoError(t, err)
+	defer subscription.Close()
+
+	cancel()
+
+	event := recvWithTimeout(t, subscription, 5*time.Second)
+	require.NotNil(t, event.SubscriptionDropped)
+	assert.Equal(t, esdb.SubscriptionDropReason_ContextCancelled, event.SubscriptionDropped.Reason)
+	assert.True(t, errors.Is(event.SubscriptionDropped.Error, context.Canceled))
+}
+
+func TestPersistentSubscriptionDroppedReasonWhenGroupDeleted(t *testing.T) {
+	readClient := newFakePersistentReadClient()
+	subscription := esdb.NewPersistentSubscription(readClient, "subscription-id", func() {})
+	defer subscription.Close()
+
+	readClient.fail(
+		status.Error(codes.Canceled, "subscription group was dropped"),
+		metadata.Pairs("exception", "persistent-subscription-dropped", "stream-name", "orders", "group-name", "billing"),
+	)
+
+	event := subscription.Recv()
+	require.NotNil(t, event.SubscriptionDropped)
+	assert.Equal(t, esdb.SubscriptionDropReason_PersistentSubscriptionDeleted, event.SubscriptionDropped.Reason)
+
+	var groupDeleted *esdb.PersistentSubscriptionDeletedError
+	require.True(t, errors.As(event.SubscriptionDropped.Error, &groupDeleted))
+	assert.Equal(t, "orders", groupDeleted.StreamName)
+	assert.Equal(t, "billing", groupDeleted.GroupName)
+}
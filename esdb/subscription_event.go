@@ -1,11 +1,193 @@
 package esdb
 
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
 type SubscriptionEvent struct {
 	EventAppeared       *ResolvedEvent
 	SubscriptionDropped *SubscriptionDropped
 	CheckPointReached   *Position
+	// Holds the delivery span of EventAppeared when a Tracer is configured.
+	ctx context.Context
+}
+
+// Context returns the context holding the delivery span of the event when a Tracer is configured,
+// so that handling the event can be traced as its child. The span, named Subscription.Deliver or
+// PersistentSubscription.Deliver, only covers handing the event to the subscription buffer: it has
+// ended by the time the event is received, and the spans started for handling it outlive it.
+// Events appended with the context link to the span.
+func (event *SubscriptionEvent) Context() context.Context {
+	if event.ctx == nil {
+		return context.Background()
+	}
+
+	return event.ctx
 }
 
+// SubscriptionDropReason tells why a subscription stopped delivering events.
+type SubscriptionDropReason int32
+
+const (
+	// The drop couldn't be classified, see SubscriptionDropped.Error.
+	SubscriptionDropReason_Unknown SubscriptionDropReason = 0
+	// Close was called on the subscription.
+	SubscriptionDropReason_Closed SubscriptionDropReason = 1
+	// The context the subscription was started with was cancelled or reached its deadline.
+	SubscriptionDropReason_ContextCancelled SubscriptionDropReason = 2
+	// The server ended the subscription, usually because it is shutting down.
+	SubscriptionDropReason_ServerShutdown SubscriptionDropReason = 3
+	// The credentials of the subscription aren't allowed to read the stream or group.
+	SubscriptionDropReason_AccessDenied SubscriptionDropReason = 4
+	// The subscribed stream was deleted.
+	SubscriptionDropReason_StreamDeleted SubscriptionDropReason = 5
+	// The persistent subscription group was deleted or doesn't exist.
+	SubscriptionDropReason_PersistentSubscriptionDeleted SubscriptionDropReason = 6
+	// The connection to the server failed.
+	SubscriptionDropReason_NetworkFailure SubscriptionDropReason = 7
+	// No message was received within the idle timeout of the subscription.
+	SubscriptionDropReason_IdleTimeout SubscriptionDropReason = 8
+	// The client was closed or shut down, see Client.Shutdown.
+	SubscriptionDropReason_ClientClosed SubscriptionDropReason = 9
+)
+
+func (reason SubscriptionDropReason) String() string {
+	switch reason {
+	case SubscriptionDropReason_Unknown:
+		return "Unknown"
+	case SubscriptionDropReason_Closed:
+		return "Closed"
+	case SubscriptionDropReason_ContextCancelled:
+		return "ContextCancelled"
+	case SubscriptionDropReason_ServerShutdown:
+		return "ServerShutdown"
+	case SubscriptionDropReason_AccessDenied:
+		return "AccessDenied"
+	case SubscriptionDropReason_StreamDeleted:
+		return "StreamDeleted"
+	case SubscriptionDropReason_PersistentSubscriptionDeleted:
+		return "PersistentSubscriptionDeleted"
+	case SubscriptionDropReason_NetworkFailure:
+		return "NetworkFailure"
+	case SubscriptionDropReason_IdleTimeout:
+		return "IdleTimeout"
+	case SubscriptionDropReason_ClientClosed:
+		return "ClientClosed"
+	default:
+		return fmt.Sprintf("SubscriptionDropReason(%d)", int32(reason))
+	}
+}
+
+// SubscriptionDropped is the last event of a subscription. Error can be matched with errors.Is or
+// errors.As depending on Reason:
+//   - Closed: ErrSubscriptionClosed
+//   - ContextCancelled: context.Canceled or context.DeadlineExceeded
+//   - ServerShutdown: ErrServerShutdown
+//   - AccessDenied: ErrPermissionDenied
+//   - StreamDeleted: *StreamDeletedError
+//   - PersistentSubscriptionDeleted: *PersistentSubscriptionDeletedError
+//   - IdleTimeout: ErrSubscriptionIdleTimeout
+//   - ClientClosed: ErrClientClosed
+//   - NetworkFailure and Unknown: the gRPC error as received
 type SubscriptionDropped struct {
-	Error error
+	Reason SubscriptionDropReason
+	Error  error
+}
+
+// newSubscriptionDropped classifies the error that ended a subscription stream, using the gRPC
+// status and the exception trailers sent by the server. closed tells if the subscription was
+// closed by the user.
+func newSubscriptionDropped(err error, trailers metadata.MD, closed bool) *SubscriptionDropped {
+	if closed {
+		return &SubscriptionDropped{
+			Reason: SubscriptionDropReason_Closed,
+			Error:  ErrSubscriptionClosed,
+		}
+	}
+
+	if errors.Is(err, ErrSubscriptionIdleTimeout) {
+		return &SubscriptionDropped{
+			Reason: SubscriptionDropReason_IdleTimeout,
+			Error:  err,
+		}
+	}
+
+	if errors.Is(err, ErrClientClosed) {
+		return &SubscriptionDropped{
+			Reason: SubscriptionDropReason_ClientClosed,
+			Error:  ErrClientClosed,
+		}
+	}
+
+	if errors.Is(err, io.EOF) {
+		return &SubscriptionDropped{
+			Reason: SubscriptionDropReason_ServerShutdown,
+			Error:  ErrServerShutdown,
+		}
+	}
+
+	switch trailerValue(trailers, "exception") {
+	case "stream-deleted":
+		return &SubscriptionDropped{
+			Reason: SubscriptionDropReason_StreamDeleted,
+			Error:  &StreamDeletedError{StreamName: trailerValue(trailers, "stream-name")},
+		}
+	case "access-denied":
+		return &SubscriptionDropped{
+			Reason: SubscriptionDropReason_AccessDenied,
+			Error:  fmt.Errorf("%w, reason: %s", ErrPermissionDenied, err.Error()),
+		}
+	case "persistent-subscription-dropped", "persistent-subscription-does-not-exist":
+		return &SubscriptionDropped{
+			Reason: SubscriptionDropReason_PersistentSubscriptionDeleted,
+			Error: &PersistentSubscriptionDeletedError{
+				StreamName: trailerValue(trailers, "stream-name"),
+				GroupName:  trailerValue(trailers, "group-name"),
+			},
+		}
+	}
+
+	switch status.Code(err) {
+	case codes.Canceled:
+		return &SubscriptionDropped{
+			Reason: SubscriptionDropReason_ContextCancelled,
+			Error:  fmt.Errorf("%w: %v", context.Canceled, err),
+		}
+	case codes.DeadlineExceeded:
+		return &SubscriptionDropped{
+			Reason: SubscriptionDropReason_ContextCancelled,
+			Error:  fmt.Errorf("%w: %v", context.DeadlineExceeded, err),
+		}
+	case codes.PermissionDenied, codes.Unauthenticated:
+		return &SubscriptionDropped{
+			Reason: SubscriptionDropReason_AccessDenied,
+			Error:  fmt.Errorf("%w, reason: %s", ErrPermissionDenied, err.Error()),
+		}
+	case codes.Unavailable:
+		return &SubscriptionDropped{
+			Reason: SubscriptionDropReason_NetworkFailure,
+			Error:  err,
+		}
+	}
+
+	return &SubscriptionDropped{
+		Reason: SubscriptionDropReason_Unknown,
+		Error:  err,
+	}
+}
+
+func trailerValue(trailers metadata.MD, key string) string {
+	values := trailers.Get(key)
+	if len(values) == 0 {
+		return ""
+	}
+
+	return values[0]
 }
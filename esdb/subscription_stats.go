@@ -0,0 +1,243 @@
+package esdb
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"time"
+)
+
+// SubscriptionStats describes the throughput of a subscription and how far behind the head of $all
+// it is. Head and lag fields are only set once the head has been sampled, see SubscriptionStatsOptions.
+type SubscriptionStats struct {
+	// Number of events delivered since the subscription started.
+	EventsDelivered uint64
+	// Delivery rate over the last sampling interval, or since the subscription started when the
+	// head isn't sampled.
+	EventsPerSecond float64
+	// Position of the last delivered event or checkpoint, nil if nothing was delivered yet.
+	LastPosition *Position
+	// Creation date of the last delivered event.
+	LastCreated time.Time
+	// Position of the last event of $all when it was last sampled.
+	HeadPosition *Position
+	// Creation date of the last event of $all when it was last sampled.
+	HeadCreated time.Time
+	// When the head was last sampled.
+	SampledAt time.Time
+	// Size of the transaction log between the last delivered position and the head.
+	LagBytes uint64
+	// Estimated number of events between the last delivered position and the head. As $all can't
+	// be counted cheaply, LagBytes is divided by the average log size of the events delivered so
+	// far, so it is not an exact count. It is 1 while too few events were delivered to tell their
+	// average size, as the subscription is then known to be behind without knowing by how much.
+	EstimatedLagEvents uint64
+	// Time between the creation of the last delivered event and the creation of the head.
+	Lag time.Duration
+}
+
+// SubscriptionMetrics receives the stats of a subscription every time the head of $all is sampled.
+type SubscriptionMetrics interface {
+	SubscriptionStatsSampled(subscriptionId string, stats SubscriptionStats)
+}
+
+type SubscriptionStatsOptions struct {
+	// Interval between two samples of the head of $all.
+	SampleInterval time.Duration // Defaults to 5 seconds.
+	// Optional, called after every sample.
+	Metrics SubscriptionMetrics
+	// Credentials used to read the head of $all, which usually requires an admin user.
+	// Defaults to the credentials of the subscription.
+	Authenticated *Credentials
+}
+
+func (o *SubscriptionStatsOptions) setDefaults() {
+	if o.SampleInterval <= 0 {
+		o.SampleInterval = 5 * time.Second
+	}
+}
+
+type subscriptionTracker struct {
+	lock          sync.Mutex
+	started       time.Time
+	delivered     uint64
+	firstPosition *Position
+	firstCount    uint64
+	lastPosition  *Position
+	lastCreated   time.Time
+	headPosition  *Position
+	headCreated   time.Time
+	sampledAt     time.Time
+	sampledCount  uint64
+	rate          float64
+	sampling      bool
+	done          chan struct{}
+	once          sync.Once
+}
+
+func newSubscriptionTracker() *subscriptionTracker {
+	return &subscriptionTracker{
+		started: time.Now(),
+		done:    make(chan struct{}),
+	}
+}
+
+func (tracker *subscriptionTracker) eventDelivered(event *ResolvedEvent) {
+	recorded := event.OriginalEvent()
+	if recorded == nil {
+		return
+	}
+
+	tracker.lock.Lock()
+	defer tracker.lock.Unlock()
+
+	tracker.delivered++
+	tracker.lastCreated = recorded.CreatedDate
+
+	position := recorded.Position
+	tracker.lastPosition = &position
+	if tracker.firstPosition == nil {
+		tracker.firstPosition = &position
+		tracker.firstCount = tracker.delivered
+	}
+}
+
+func (tracker *subscriptionTracker) checkpointReached(position Position) {
+	tracker.lock.Lock()
+	defer tracker.lock.Unlock()
+
+	tracker.lastPosition = &position
+}
+
+func (tracker *subscriptionTracker) stop() {
+	tracker.once.Do(func() {
+		close(tracker.done)
+	})
+}
+
+func (tracker *subscriptionTracker) stats() SubscriptionStats {
+	tracker.lock.Lock()
+	defer tracker.lock.Unlock()
+
+	stats := SubscriptionStats{
+		EventsDelivered: tracker.delivered,
+		EventsPerSecond: tracker.rate,
+		LastPosition:    tracker.lastPosition,
+		LastCreated:     tracker.lastCreated,
+		HeadPosition:    tracker.headPosition,
+		HeadCreated:     tracker.headCreated,
+		SampledAt:       tracker.sampledAt,
+	}
+
+	if !tracker.sampling {
+		if elapsed := time.Since(tracker.started).Seconds(); elapsed > 0 {
+			stats.EventsPerSecond = float64(tracker.delivered) / elapsed
+		}
+	}
+
+	if tracker.headPosition == nil || tracker.lastPosition == nil {
+		return stats
+	}
+
+	if tracker.headPosition.Commit > tracker.lastPosition.Commit {
+		stats.LagBytes = tracker.headPosition.Commit - tracker.lastPosition.Commit
+
+		// Average log size of the delivered events, used to turn the log size lag into events.
+		spannedEvents := tracker.delivered - tracker.firstCount
+		spannedBytes := tracker.lastPosition.Commit - tracker.firstPosition.Commit
+		if spannedEvents > 0 && spannedBytes > 0 {
+			stats.EstimatedLagEvents = stats.LagBytes * spannedEvents / spannedBytes
+		} else {
+			stats.EstimatedLagEvents = 1
+		}
+	}
+
+	if !tracker.lastCreated.IsZero() && tracker.headCreated.After(tracker.lastCreated) {
+		stats.Lag = tracker.headCreated.Sub(tracker.lastCreated)
+	}
+
+	return stats
+}
+
+func (tracker *subscriptionTracker) headSampled(head *ResolvedEvent) {
+	now := time.Now()
+
+	tracker.lock.Lock()
+	defer tracker.lock.Unlock()
+
+	if head != nil {
+		recorded := head.OriginalEvent()
+		position := recorded.Position
+		tracker.headPosition = &position
+		tracker.headCreated = recorded.CreatedDate
+	}
+
+	previous := tracker.sampledAt
+	if previous.IsZero() {
+		previous = tracker.started
+	}
+
+	if elapsed := now.Sub(previous).Seconds(); elapsed > 0 {
+		tracker.rate = float64(tracker.delivered-tracker.sampledCount) / elapsed
+	}
+
+	tracker.sampledAt = now
+	tracker.sampledCount = tracker.delivered
+}
+
+// startSampling periodically reads the last event of $all until the tracker is stopped.
+func (tracker *subscriptionTracker) startSampling(client *Client, subscriptionId string, options SubscriptionStatsOptions) {
+	options.setDefaults()
+
+	tracker.lock.Lock()
+	tracker.sampling = true
+	tracker.lock.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(options.SampleInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-tracker.done:
+				return
+			case <-ticker.C:
+			}
+
+			head, err := readHeadOfAll(client, options)
+			if err != nil {
+				client.grpcClient.config.logf("[warn] failed to sample the head of $all for subscription %s: %v", subscriptionId, err)
+				continue
+			}
+
+			tracker.headSampled(head)
+
+			if options.Metrics != nil {
+				options.Metrics.SubscriptionStatsSampled(subscriptionId, tracker.stats())
+			}
+		}
+	}()
+}
+
+func readHeadOfAll(client *Client, options SubscriptionStatsOptions) (*ResolvedEvent, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), options.SampleInterval)
+	defer cancel()
+
+	stream, err := client.ReadAll(ctx, ReadAllOptions{
+		Direction:     Backwards,
+		From:          End{},
+		Authenticated: options.Authenticated,
+	}, 1)
+	if err != nil {
+		return nil, err
+	}
+	defer stream.Close()
+
+	event, err := stream.Recv()
+	if errors.Is(err, io.EOF) {
+		return nil, nil
+	}
+
+	return event, err
+}
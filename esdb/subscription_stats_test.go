@@ -0,0 +1,85 @@
+package esdb_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/EventStore/EventStore-Client-Go/esdb"
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPersistentSubscriptionStatsTrackDeliveredEvents(t *testing.T) {
+	first := persistentEventResp(uuid.Must(uuid.NewV4()), "stream", 0, "")
+	first.GetEvent().GetEvent().CommitPosition = 100
+	first.GetEvent().GetEvent().PreparePosition = 100
+	second := persistentEventResp(uuid.Must(uuid.NewV4()), "stream", 1, "")
+	second.GetEvent().GetEvent().CommitPosition = 250
+	second.GetEvent().GetEvent().PreparePosition = 250
+
+	readClient := newFakePersistentReadClient(first, second)
+	subscription := esdb.NewPersistentSubscription(readClient, "sub", readClient.close)
+	defer subscription.Close()
+
+	assert.Nil(t, subscription.Stats().LastPosition)
+
+	subscription.Recv()
+	subscription.Recv()
+
+	stats := subscription.Stats()
+	assert.Equal(t, uint64(2), stats.EventsDelivered)
+	require.NotNil(t, stats.LastPosition)
+	assert.Equal(t, esdb.Position{Commit: 250, Prepare: 250}, *stats.LastPosition)
+	assert.Greater(t, stats.EventsPerSecond, 0.0)
+	assert.Nil(t, stats.HeadPosition)
+	assert.Zero(t, stats.Lag)
+}
+
+type recordingSubscriptionMetrics struct {
+	lock    sync.Mutex
+	samples []esdb.SubscriptionStats
+}
+
+func (metrics *recordingSubscriptionMetrics) SubscriptionStatsSampled(subscriptionId string, stats esdb.SubscriptionStats) {
+	metrics.lock.Lock()
+	defer metrics.lock.Unlock()
+	metrics.samples = append(metrics.samples, stats)
+}
+
+func (metrics *recordingSubscriptionMetrics) count() int {
+	metrics.lock.Lock()
+	defer metrics.lock.Unlock()
+	return len(metrics.samples)
+}
+
+func TestSubscriptionStatsSampleHeadOfAll(t *testing.T) {
+	container := GetPrePopulatedDatabase()
+	defer container.Close()
+	db := CreateTestClient(container, t)
+	defer db.Close()
+
+	metrics := &recordingSubscriptionMetrics{}
+	subscription, err := db.SubscribeToAll(context.Background(), esdb.SubscribeToAllOptions{
+		From: esdb.Start{},
+		Stats: &esdb.SubscriptionStatsOptions{
+			SampleInterval: 100 * time.Millisecond,
+			Metrics:        metrics,
+		},
+	})
+	require.NoError(t, err)
+	defer subscription.Close()
+
+	for i := 0; i < 10; i++ {
+		require.NotNil(t, subscription.Recv().EventAppeared)
+	}
+
+	require.Eventually(t, func() bool { return metrics.count() > 0 }, 5*time.Second, 10*time.Millisecond)
+
+	stats := subscription.Stats()
+	require.NotNil(t, stats.HeadPosition)
+	assert.Greater(t, stats.LagBytes, uint64(0))
+	assert.Greater(t, stats.EstimatedLagEvents, uint64(0))
+}
@@ -3,88 +3,98 @@ package esdb
 import (
 	"context"
 	"fmt"
-	"log"
-	"sync"
 
 	api "github.com/EventStore/EventStore-Client-Go/protos/streams"
 )
 
-type request struct {
-	channel chan *SubscriptionEvent
-}
-
 type Subscription struct {
 	client  *Client
 	id      string
 	inner   api.Streams_ReadClient
-	channel chan request
+	channel *subscriptionChannel
 	cancel  context.CancelFunc
-	once    *sync.Once
+	tracker *subscriptionTracker
 }
 
 func NewSubscription(client *Client, cancel context.CancelFunc, inner api.Streams_ReadClient, id string) *Subscription {
-	channel := make(chan request)
-	once := new(sync.Once)
+	return newSubscription(client, cancel, inner, id, nil, 0)
+}
+
+func newSubscription(
+	client *Client,
+	cancel context.CancelFunc,
+	inner api.Streams_ReadClient,
+	id string,
+	idle *idleWatchdog,
+	bufferSize int,
+) *Subscription {
+	channel := newSubscriptionChannel(bufferSize)
+	tracker := newSubscriptionTracker()
+
+	var conf *Configuration
+	var operations *operationTracker
+	if client != nil {
+		conf = &client.grpcClient.config
+		operations = client.grpcClient.operations
+	}
+
+	metrics := metricsOf(conf)
+	metrics.SubscriptionStarted(id)
+	untrack := operations.track(func() { channel.shutdown(cancel) })
 
 	// It is not safe to consume a stream in different goroutines. This is why we only consume
-	// the stream in a dedicated goroutine.
-	//
-	// Current implementation doesn't terminate the goroutine. When a subscription is dropped,
-	// we keep user requests coming but will always send back a subscription dropped event.
-	// This implementation is simple to maintain while letting the user sharing their subscription
-	// among as many goroutines as they want.
+	// the stream in a dedicated goroutine, which hands events over to the subscription channel.
+	// The goroutine exits once the stream ends, either because the subscription was closed or
+	// because it dropped.
 	go func() {
-		closed := false
+		defer untrack()
 
 		for {
-			req := <-channel
-
-			if closed {
-				req.channel <- &SubscriptionEvent{
-					SubscriptionDropped: &SubscriptionDropped{
-						Error: fmt.Errorf("subscription has been dropped"),
-					},
-				}
-
-				continue
-			}
-
+			idle.arm()
 			result, err := inner.Recv()
+			timedOut := idle.disarm()
 			if err != nil {
-				log.Printf("[error] subscription has dropped. Reason: %v", err)
-
-				dropped := SubscriptionDropped{
-					Error: err,
-				}
-
-				req.channel <- &SubscriptionEvent{
-					SubscriptionDropped: &dropped,
+				if timedOut {
+					err = fmt.Errorf("%w: no message received for %v", ErrSubscriptionIdleTimeout, idle.timeout)
+				} else if channel.isClientClosed() {
+					err = ErrClientClosed
 				}
 
-				closed = true
+				dropped := newSubscriptionDropped(err, inner.Trailer(), channel.isClosing())
+				conf.logf("[error] subscription has dropped. Reason: %v (%v)", dropped.Reason, err)
+				tracker.stop()
+				metrics.SubscriptionStopped(id, dropped.Reason)
+				channel.drop(dropped)
 
-				continue
+				return
 			}
 
 			switch result.Content.(type) {
 			case *api.ReadResp_Checkpoint_:
 				{
 					checkpoint := result.GetCheckpoint()
-					position := Position{
+					position := &Position{
 						Commit:  checkpoint.CommitPosition,
 						Prepare: checkpoint.PreparePosition,
 					}
+					tracker.checkpointReached(*position)
 
-					req.channel <- &SubscriptionEvent{
-						CheckPointReached: &position,
-					}
+					channel.deliver(&SubscriptionEvent{
+						CheckPointReached: position,
+					})
 				}
 			case *api.ReadResp_Event:
 				{
 					resolvedEvent := getResolvedEventFromProto(result.GetEvent())
-					req.channel <- &SubscriptionEvent{
+					tracker.eventDelivered(&resolvedEvent)
+					metrics.BytesRead(eventSize(&resolvedEvent))
+					metrics.EventDelivered(id)
+					ctx, span := traceDelivery(conf, "Subscription.Deliver", id, &resolvedEvent)
+					channel.deliver(&SubscriptionEvent{
 						EventAppeared: &resolvedEvent,
-					}
+						ctx:           ctx,
+					})
+					span.end(nil)
 				}
 			}
 		}
@@ -95,8 +105,8 @@ func NewSubscription(client *Client, cancel context.CancelFunc, inner api.Stream
 		id:      id,
 		inner:   inner,
 		channel: channel,
-		once:    once,
 		cancel:  cancel,
+		tracker: tracker,
 	}
 }
 
@@ -105,18 +115,33 @@ func (sub *Subscription) Id() string {
 }
 
 func (sub *Subscription) Close() error {
-	sub.once.Do(sub.cancel)
+	sub.channel.close(sub.cancel)
+	sub.tracker.stop()
 	return nil
 }
 
+// Stats returns the throughput of the subscription and, when enabled, its lag behind the head of $all.
+func (sub *Subscription) Stats() SubscriptionStats {
+	return sub.tracker.stats()
+}
+
+// Recv blocks until the next event. Once the subscription is dropped or closed, it keeps returning
+// the SubscriptionDropped event.
 func (sub *Subscription) Recv() *SubscriptionEvent {
-	channel := make(chan *SubscriptionEvent)
-	req := request{
-		channel: channel,
-	}
+	event, _ := sub.channel.recv(context.Background())
+	return event
+}
 
-	sub.channel <- req
-	resp := <-channel
+// RecvContext is like Recv but gives up when ctx is done, in which case it returns ctx.Err() and
+// the subscription keeps running.
+func (sub *Subscription) RecvContext(ctx context.Context) (*SubscriptionEvent, error) {
+	return sub.channel.recv(ctx)
+}
 
-	return resp
+// Events returns the channel events are delivered to, see SubscribeToStreamOptions.EventBufferSize.
+// The last event is a SubscriptionDropped, unless the subscription is closed, and the channel is
+// closed once the subscription is over. Events and Recv can be used together, each event is
+// delivered once.
+func (sub *Subscription) Events() <-chan *SubscriptionEvent {
+	return sub.channel.events
 }
@@ -0,0 +1,258 @@
+package esdb
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SpanKind tells the role of the client in a span, as defined by OpenTelemetry.
+type SpanKind int32
+
+const (
+	// A request to the server.
+	SpanKind_Client SpanKind = 0
+	// An append, whose trace context is stored in the metadata of the events.
+	SpanKind_Producer SpanKind = 1
+	// The delivery of an event by a subscription.
+	SpanKind_Consumer SpanKind = 2
+)
+
+// Attributes set on the spans started by the client.
+const (
+	SpanAttribute_System         = "db.system"
+	SpanAttribute_Operation      = "db.operation"
+	SpanAttribute_Stream         = "db.eventstoredb.stream"
+	SpanAttribute_EventCount     = "db.eventstoredb.event_count"
+	SpanAttribute_Revision       = "db.eventstoredb.revision"
+	SpanAttribute_SubscriptionId = "db.eventstoredb.subscription_id"
+)
+
+// TraceParentMetadataKey is the key of the W3C traceparent in the JSON metadata of the events.
+const TraceParentMetadataKey = "traceparent"
+
+// SpanAttribute is a key-value pair describing a span. Values are strings, int64 or uint64.
+type SpanAttribute struct {
+	Key   string
+	Value interface{}
+}
+
+// SpanOptions describes a span to start.
+type SpanOptions struct {
+	Kind       SpanKind
+	Attributes []SpanAttribute
+	// W3C traceparents of the spans the new span is causally related to, like the producer span of
+	// a delivered event.
+	Links []string
+}
+
+// Tracer starts a span around every Client operation and every event delivered by a subscription.
+// It mirrors the part of the OpenTelemetry API the client needs, so any tracing library can be
+// plugged in with a small adapter.
+type Tracer interface {
+	// Start starts a span, child of the span in ctx if any, and returns the context holding it.
+	Start(ctx context.Context, name string, options SpanOptions) (context.Context, Span)
+}
+
+// Span is a span started by a Tracer.
+type Span interface {
+	SetAttributes(attributes ...SpanAttribute)
+	// TraceParent returns the W3C traceparent identifying the span, or "" when the span isn't
+	// recorded.
+	TraceParent() string
+	// End ends the span, err is nil when the operation succeeded.
+	End(err error)
+}
+
+type traceParentKey struct{}
+
+// ContextWithTraceParent returns a context whose W3C traceparent is stored in the metadata of the
+// events appended with it. The client sets it to the span of the operation when a Tracer is
+// configured.
+func ContextWithTraceParent(ctx context.Context, traceParent string) context.Context {
+	return context.WithValue(ctx, traceParentKey{}, traceParent)
+}
+
+// TraceParentFromContext returns the W3C traceparent stored by ContextWithTraceParent.
+func TraceParentFromContext(ctx context.Context) string {
+	traceParent, _ := ctx.Value(traceParentKey{}).(string)
+	return traceParent
+}
+
+// TraceParent returns the W3C traceparent stored in the JSON metadata of the event when it was
+// appended, or "" when there is none.
+func (event *RecordedEvent) TraceParent() string {
+	var metadata struct {
+		TraceParent string `json:"traceparent"`
+	}
+
+	if err := json.Unmarshal(event.UserMetadata, &metadata); err != nil || !isValidTraceParent(metadata.TraceParent) {
+		return ""
+	}
+
+	return metadata.TraceParent
+}
+
+// isValidTraceParent checks the format of a W3C traceparent, version-traceid-parentid-flags.
+func isValidTraceParent(traceParent string) bool {
+	parts := strings.Split(traceParent, "-")
+	if len(parts) != 4 {
+		return false
+	}
+
+	for i, size := range []int{2, 32, 16, 2} {
+		if len(parts[i]) != size || strings.ToLower(parts[i]) != parts[i] {
+			return false
+		}
+
+		if _, err := hex.DecodeString(parts[i]); err != nil {
+			return false
+		}
+	}
+
+	return parts[0] != "ff" && strings.Trim(parts[1], "0") != "" && strings.Trim(parts[2], "0") != ""
+}
+
+// withTraceParent adds the traceparent to the metadata of a JSON event. Metadata that isn't a JSON
+// object, or that already holds a traceparent, is left untouched.
+func withTraceParent(event EventData, traceParent string) EventData {
+	if event.ContentType != JsonContentType || traceParent == "" {
+		return event
+	}
+
+	entry := strconv.Quote(TraceParentMetadataKey) + ":" + strconv.Quote(traceParent)
+	metadata := bytes.TrimSpace(event.Metadata)
+	if len(metadata) == 0 {
+		event.Metadata = []byte("{" + entry + "}")
+		return event
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(metadata, &fields); err != nil || fields == nil {
+		return event
+	}
+
+	if _, exists := fields[TraceParentMetadataKey]; exists {
+		return event
+	}
+
+	// The entry is inserted rather than the object re-encoded, to keep the metadata as written.
+	if len(fields) > 0 {
+		entry += ","
+	}
+
+	injected := make([]byte, 0, len(metadata)+len(entry))
+	injected = append(injected, '{')
+	injected = append(injected, entry...)
+	injected = append(injected, metadata[1:]...)
+	event.Metadata = injected
+
+	return event
+}
+
+// operationSpan wraps the span of an operation and reports its outcome to Metrics. It does nothing
+// when neither a Tracer nor Metrics are configured.
+type operationSpan struct {
+	// nil when no Tracer is configured.
+	span      Span
+	metrics   clientMetrics
+	operation string
+	started   time.Time
+}
+
+// startSpan starts the span of a Client operation. The traceparent of the span replaces the one of
+// ctx, so that appends made with the returned context link to it.
+func startSpan(ctx context.Context, tracer Tracer, operation string, options SpanOptions) (context.Context, *operationSpan) {
+	if tracer == nil {
+		return ctx, nil
+	}
+
+	options.Attributes = append([]SpanAttribute{
+		{Key: SpanAttribute_System, Value: "eventstoredb"},
+		{Key: SpanAttribute_Operation, Value: operation},
+	}, options.Attributes...)
+
+	ctx, span := tracer.Start(ctx, operation, options)
+	if traceParent := span.TraceParent(); traceParent != "" {
+		ctx = ContextWithTraceParent(ctx, traceParent)
+	}
+
+	return ctx, &operationSpan{span: span}
+}
+
+func (client *grpcClient) startSpan(ctx context.Context, operation string, stream string, attributes ...SpanAttribute) (context.Context, *operationSpan) {
+	if stream != "" {
+		attributes = append([]SpanAttribute{{Key: SpanAttribute_Stream, Value: stream}}, attributes...)
+	}
+
+	return client.startOperation(ctx, operation, SpanOptions{Kind: SpanKind_Client, Attributes: attributes})
+}
+
+// startOperation starts the span of a Client operation, its latency and error being reported to
+// the configured Metrics when it ends.
+func (client *grpcClient) startOperation(ctx context.Context, operation string, options SpanOptions) (context.Context, *operationSpan) {
+	ctx, span := startSpan(ctx, client.config.Tracer, operation, options)
+	if client.config.Metrics == nil {
+		return ctx, span
+	}
+
+	if span == nil {
+		span = &operationSpan{}
+	}
+
+	span.metrics = metricsOf(&client.config)
+	span.operation = operation
+	span.started = time.Now()
+
+	return ctx, span
+}
+
+func (span *operationSpan) setAttributes(attributes ...SpanAttribute) {
+	if span != nil && span.span != nil {
+		span.span.SetAttributes(attributes...)
+	}
+}
+
+func (span *operationSpan) end(err error) {
+	if span == nil {
+		return
+	}
+
+	span.metrics.OperationCompleted(span.operation, time.Since(span.started), err)
+
+	if span.span != nil {
+		span.span.End(err)
+	}
+}
+
+// traceDelivery starts the span of an event delivered by a subscription, linked to the span the
+// event was appended in, and returns the context holding it. The span is ended once the event is
+// handed to the subscription buffer.
+func traceDelivery(conf *Configuration, operation string, subscriptionId string, event *ResolvedEvent) (context.Context, *operationSpan) {
+	if conf == nil || conf.Tracer == nil {
+		return nil, nil
+	}
+
+	recorded := event.OriginalEvent()
+	options := SpanOptions{
+		Kind: SpanKind_Consumer,
+		Attributes: []SpanAttribute{
+			{Key: SpanAttribute_Stream, Value: recorded.StreamID},
+			{Key: SpanAttribute_Revision, Value: recorded.EventNumber},
+			{Key: SpanAttribute_SubscriptionId, Value: subscriptionId},
+		},
+	}
+
+	// Links to deleted events are delivered without the event, the link itself has no trace.
+	if event.Event != nil {
+		if traceParent := event.Event.TraceParent(); traceParent != "" {
+			options.Links = []string{traceParent}
+		}
+	}
+
+	return startSpan(context.Background(), conf.Tracer, operation, options)
+}
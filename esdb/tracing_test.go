@@ -0,0 +1,298 @@
+package esdb_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/EventStore/EventStore-Client-Go/esdb"
+	api "github.com/EventStore/EventStore-Client-Go/protos/streams"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+// recordedSpan is a span started by a recordingTracer.
+type recordedSpan struct {
+	name        string
+	options     esdb.SpanOptions
+	traceParent string
+	parent      string
+	attributes  map[string]interface{}
+	ended       bool
+	err         error
+}
+
+// recordingTracer keeps the spans it starts, each span getting a distinct traceparent.
+type recordingTracer struct {
+	lock  sync.Mutex
+	spans []*recordedSpan
+}
+
+type recordingSpan struct {
+	tracer *recordingTracer
+	span   *recordedSpan
+}
+
+func (tracer *recordingTracer) Start(ctx context.Context, name string, options esdb.SpanOptions) (context.Context, esdb.Span) {
+	tracer.lock.Lock()
+	defer tracer.lock.Unlock()
+
+	span := &recordedSpan{
+		name:        name,
+		options:     options,
+		traceParent: fmt.Sprintf("00-0af7651916cd43dd8448eb211c80319c-%016x-01", len(tracer.spans)+1),
+		parent:      esdb.TraceParentFromContext(ctx),
+		attributes:  map[string]interface{}{},
+	}
+
+	for _, attribute := range options.Attributes {
+		span.attributes[attribute.Key] = attribute.Value
+	}
+
+	tracer.spans = append(tracer.spans, span)
+	return ctx, &recordingSpan{tracer: tracer, span: span}
+}
+
+func (span *recordingSpan) SetAttributes(attributes ...esdb.SpanAttribute) {
+	span.tracer.lock.Lock()
+	defer span.tracer.lock.Unlock()
+
+	for _, attribute := range attributes {
+		span.span.attributes[attribute.Key] = attribute.Value
+	}
+}
+
+func (span *recordingSpan) TraceParent() string {
+	return span.span.traceParent
+}
+
+func (span *recordingSpan) End(err error) {
+	span.tracer.lock.Lock()
+	defer span.tracer.lock.Unlock()
+
+	span.span.ended = true
+	span.span.err = err
+}
+
+func (tracer *recordingTracer) named(name string) []recordedSpan {
+	tracer.lock.Lock()
+	defer tracer.lock.Unlock()
+
+	var spans []recordedSpan
+	for _, span := range tracer.spans {
+		if span.name == name {
+			spans = append(spans, *span)
+		}
+	}
+
+	return spans
+}
+
+// appendedMetadata serves appends and subscriptions, subscriptions replaying the custom metadata of
+// the appended events.
+type appendedMetadata struct {
+	lock     sync.Mutex
+	metadata [][]byte
+}
+
+func (appended *appendedMetadata) server() *fakeStreamsServer {
+	return &fakeStreamsServer{
+		append: func(server api.Streams_AppendServer) error {
+			for {
+				req, err := server.Recv()
+				if err == io.EOF {
+					break
+				}
+
+				if err != nil {
+					return err
+				}
+
+				if message := req.GetProposedMessage(); message != nil {
+					appended.lock.Lock()
+					appended.metadata = append(appended.metadata, message.CustomMetadata)
+					appended.lock.Unlock()
+				}
+			}
+
+			return server.SendAndClose(&api.AppendResp{
+				Result: &api.AppendResp_Success_{
+					Success: &api.AppendResp_Success{
+						CurrentRevisionOption: &api.AppendResp_Success_CurrentRevision{CurrentRevision: 41},
+					},
+				},
+			})
+		},
+		read: func(req *api.ReadReq, server api.Streams_ReadServer) error {
+			if err := server.Send(fakeSubscriptionConfirmation()); err != nil {
+				return err
+			}
+
+			for revision, metadata := range appended.all() {
+				event := fakeReadEvent("orders", uint64(revision))
+				event.GetEvent().GetEvent().CustomMetadata = metadata
+				if err := server.Send(event); err != nil {
+					return err
+				}
+			}
+
+			<-server.Context().Done()
+			return nil
+		},
+	}
+}
+
+func (appended *appendedMetadata) all() [][]byte {
+	appended.lock.Lock()
+	defer appended.lock.Unlock()
+	return append([][]byte(nil), appended.metadata...)
+}
+
+func createTracedClient(t *testing.T, appended *appendedMetadata, tracer esdb.Tracer) *esdb.Client {
+	address := startFakeServer(t, func(server *grpc.Server) {
+		api.RegisterStreamsServer(server, appended.server())
+	})
+
+	config, err := esdb.ParseConnectionString("esdb://" + address + "?tls=false")
+	require.NoError(t, err)
+	config.Tracer = tracer
+
+	client, err := esdb.NewClient(config)
+	require.NoError(t, err)
+	t.Cleanup(func() { client.Close() })
+
+	return client
+}
+
+func TestAppendStoresTraceParentInMetadata(t *testing.T) {
+	tracer := &recordingTracer{}
+	appended := &appendedMetadata{}
+	client := createTracedClient(t, appended, tracer)
+
+	ctx := esdb.ContextWithTraceParent(context.Background(), "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+	_, err := client.AppendToStream(ctx, "orders", esdb.AppendToStreamOptions{},
+		esdb.EventData{EventType: "OrderPlaced", ContentType: esdb.JsonContentType, Data: []byte(`{}`)},
+		esdb.EventData{EventType: "OrderPlaced", ContentType: esdb.JsonContentType, Data: []byte(`{}`), Metadata: []byte(` {"user": "bob"}`)},
+		esdb.EventData{EventType: "OrderPlaced", ContentType: esdb.JsonContentType, Data: []byte(`{}`), Metadata: []byte(`{"traceparent":"kept"}`)},
+		esdb.EventData{EventType: "OrderPlaced", ContentType: esdb.BinaryContentType, Data: []byte{1}, Metadata: []byte{2}},
+	)
+	require.NoError(t, err)
+
+	spans := tracer.named("AppendToStream")
+	require.Len(t, spans, 1)
+	span := spans[0]
+	assert.True(t, span.ended)
+	assert.NoError(t, span.err)
+	assert.Equal(t, esdb.SpanKind_Producer, span.options.Kind)
+	assert.Equal(t, "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01", span.parent)
+	assert.Equal(t, "orders", span.attributes[esdb.SpanAttribute_Stream])
+	assert.Equal(t, int64(4), span.attributes[esdb.SpanAttribute_EventCount])
+	assert.Equal(t, uint64(41), span.attributes[esdb.SpanAttribute_Revision])
+
+	assert.Equal(t, []string{
+		`{"traceparent":"` + span.traceParent + `"}`,
+		`{"traceparent":"` + span.traceParent + `","user": "bob"}`,
+		`{"traceparent":"kept"}`,
+		"\x02",
+	}, toStrings(appended.all()))
+}
+
+func TestAppendWithoutTracerStoresTraceParentOfContext(t *testing.T) {
+	appended := &appendedMetadata{}
+	client := createTracedClient(t, appended, nil)
+
+	_, err := client.AppendToStream(context.Background(), "orders", esdb.AppendToStreamOptions{},
+		esdb.EventData{EventType: "OrderPlaced", ContentType: esdb.JsonContentType, Data: []byte(`{}`)})
+	require.NoError(t, err)
+
+	ctx := esdb.ContextWithTraceParent(context.Background(), "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+	_, err = client.AppendToStream(ctx, "orders", esdb.AppendToStreamOptions{},
+		esdb.EventData{EventType: "OrderPlaced", ContentType: esdb.JsonContentType, Data: []byte(`{}`)})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{
+		"",
+		`{"traceparent":"00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"}`,
+	}, toStrings(appended.all()))
+}
+
+func TestSubscriptionDeliveryLinksToProducerSpan(t *testing.T) {
+	tracer := &recordingTracer{}
+	appended := &appendedMetadata{}
+	client := createTracedClient(t, appended, tracer)
+
+	_, err := client.AppendToStream(context.Background(), "orders", esdb.AppendToStreamOptions{},
+		esdb.EventData{EventType: "OrderPlaced", ContentType: esdb.JsonContentType, Data: []byte(`{}`)})
+	require.NoError(t, err)
+	producer := tracer.named("AppendToStream")[0]
+
+	subscription, err := client.SubscribeToStream(context.Background(), "orders", esdb.SubscribeToStreamOptions{})
+	require.NoError(t, err)
+	defer subscription.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	event, err := subscription.RecvContext(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, event.EventAppeared)
+	assert.Equal(t, producer.traceParent, event.EventAppeared.Event.TraceParent())
+
+	assert.Eventually(t, func() bool {
+		deliveries := tracer.named("Subscription.Deliver")
+		return len(deliveries) == 1 && deliveries[0].ended
+	}, 5*time.Second, 10*time.Millisecond)
+
+	delivery := tracer.named("Subscription.Deliver")[0]
+	assert.Equal(t, esdb.SpanKind_Consumer, delivery.options.Kind)
+	assert.Equal(t, []string{producer.traceParent}, delivery.options.Links)
+	assert.Equal(t, "orders", delivery.attributes[esdb.SpanAttribute_Stream])
+	assert.Equal(t, uint64(0), delivery.attributes[esdb.SpanAttribute_Revision])
+
+	// Handling the event is traced as a child of the delivery.
+	assert.Equal(t, delivery.traceParent, esdb.TraceParentFromContext(event.Context()))
+
+	_, err = client.AppendToStream(event.Context(), "orders", esdb.AppendToStreamOptions{},
+		esdb.EventData{EventType: "OrderShipped", ContentType: esdb.JsonContentType, Data: []byte(`{}`)})
+	require.NoError(t, err)
+	assert.Equal(t, delivery.traceParent, tracer.named("AppendToStream")[1].parent)
+
+	subscribe := tracer.named("SubscribeToStream")
+	require.Len(t, subscribe, 1)
+	assert.Equal(t, esdb.SpanKind_Client, subscribe[0].options.Kind)
+	assert.True(t, subscribe[0].ended)
+}
+
+func TestRecordedEventTraceParent(t *testing.T) {
+	tests := []struct {
+		metadata string
+		expected string
+	}{
+		{`{"traceparent":"00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"}`, "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"},
+		{`{"traceparent":"00-0AF7651916CD43DD8448EB211C80319C-b7ad6b7169203331-01"}`, ""},
+		{`{"traceparent":"00-00000000000000000000000000000000-b7ad6b7169203331-01"}`, ""},
+		{`{"traceparent":"00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331"}`, ""},
+		{`{"traceparent":42}`, ""},
+		{`{}`, ""},
+		{`not json`, ""},
+		{``, ""},
+	}
+
+	for _, test := range tests {
+		event := esdb.RecordedEvent{UserMetadata: []byte(test.metadata)}
+		assert.Equal(t, test.expected, event.TraceParent(), test.metadata)
+	}
+}
+
+func toStrings(values [][]byte) []string {
+	strings := make([]string, len(values))
+	for i, value := range values {
+		strings[i] = string(value)
+	}
+
+	return strings
+}
@@ -17,6 +17,21 @@ const (
 	ConsumerStrategy_PinnedByCorrelation ConsumerStrategy = 3
 )
 
+func (strategy ConsumerStrategy) String() string {
+	switch strategy {
+	case ConsumerStrategy_RoundRobin:
+		return "RoundRobin"
+	case ConsumerStrategy_DispatchToSingle:
+		return "DispatchToSingle"
+	case ConsumerStrategy_Pinned:
+		return "Pinned"
+	case ConsumerStrategy_PinnedByCorrelation:
+		return "PinnedByCorrelation"
+	default:
+		return fmt.Sprintf("ConsumerStrategy(%d)", int32(strategy))
+	}
+}
+
 type SubscriptionSettings struct {
 	ResolveLinkTos        bool
 	ExtraStatistics       bool
@@ -49,6 +64,50 @@ func SubscriptionSettingsDefault() SubscriptionSettings {
 	}
 }
 
+// Validate checks the settings for values the server would reject. It returns a *ValidationError
+// listing every invalid field.
+func (settings SubscriptionSettings) Validate() error {
+	err := &ValidationError{}
+
+	if settings.MaxRetryCount < 0 {
+		err.add("MaxRetryCount", "must not be negative, got %d", settings.MaxRetryCount)
+	}
+	if settings.MinCheckpointCount < 0 {
+		err.add("MinCheckpointCount", "must not be negative, got %d", settings.MinCheckpointCount)
+	}
+	if settings.MinCheckpointCount > settings.MaxCheckpointCount {
+		err.add("MinCheckpointCount", "must not be greater than MaxCheckpointCount (%d), got %d", settings.MaxCheckpointCount, settings.MinCheckpointCount)
+	}
+	if settings.MaxSubscriberCount < 0 {
+		err.add("MaxSubscriberCount", "must not be negative, got %d", settings.MaxSubscriberCount)
+	}
+	if settings.ReadBatchSize <= 0 {
+		err.add("ReadBatchSize", "must be positive, got %d", settings.ReadBatchSize)
+	}
+	if settings.LiveBufferSize < settings.ReadBatchSize {
+		err.add("LiveBufferSize", "must not be smaller than ReadBatchSize (%d), got %d", settings.ReadBatchSize, settings.LiveBufferSize)
+	}
+	if settings.HistoryBufferSize < settings.ReadBatchSize {
+		err.add("HistoryBufferSize", "must not be smaller than ReadBatchSize (%d), got %d", settings.ReadBatchSize, settings.HistoryBufferSize)
+	}
+	if settings.MessageTimeoutInMs < 0 {
+		err.add("MessageTimeoutInMs", "must not be negative, got %d", settings.MessageTimeoutInMs)
+	}
+	if settings.CheckpointAfterInMs < 0 {
+		err.add("CheckpointAfterInMs", "must not be negative, got %d", settings.CheckpointAfterInMs)
+	}
+
+	switch settings.NamedConsumerStrategy {
+	case ConsumerStrategy_RoundRobin, ConsumerStrategy_DispatchToSingle, ConsumerStrategy_Pinned:
+	case ConsumerStrategy_PinnedByCorrelation:
+		err.add("NamedConsumerStrategy", "%s is not supported by the server protocol", settings.NamedConsumerStrategy)
+	default:
+		err.add("NamedConsumerStrategy", "is unknown, got %d", int32(settings.NamedConsumerStrategy))
+	}
+
+	return err.orNil()
+}
+
 type PersistentSubscriptionError struct {
 	Code int
 	Err  error
@@ -581,3 +640,49 @@ func ExcludeSystemEventsFilter() *SubscriptionFilter {
 		Regex: "/^[^\\$].*/",
 	}
 }
+
+// Validate checks the filter has a known type and either a set of prefixes or a regex, but not
+// both. It returns a *ValidationError listing every invalid field.
+func (filter SubscriptionFilter) Validate() error {
+	err := &ValidationError{}
+
+	if filter.Type != EventFilterType && filter.Type != StreamFilterType {
+		err.add("Type", "is unknown, got %d", int(filter.Type))
+	}
+
+	if len(filter.Prefixes) == 0 && filter.Regex == "" {
+		err.add("Prefixes", "or Regex must be set")
+	}
+
+	if len(filter.Prefixes) > 0 && filter.Regex != "" {
+		err.add("Prefixes", "can't be set together with Regex")
+	}
+
+	for i, prefix := range filter.Prefixes {
+		if prefix == "" {
+			err.add(fmt.Sprintf("Prefixes[%d]", i), "must not be empty")
+		}
+	}
+
+	return err.orNil()
+}
+
+func validateFilterOptions(err *ValidationError, filter *SubscriptionFilter, maxSearchWindow int, checkpointInterval int) {
+	if filter != nil {
+		err.merge("Filter", filter.Validate())
+	}
+
+	if maxSearchWindow < NoMaxSearchWindow {
+		err.add("MaxSearchWindow", "must be positive or NoMaxSearchWindow, got %d", maxSearchWindow)
+	}
+
+	if checkpointInterval < 0 {
+		err.add("CheckpointInterval", "must not be negative, got %d", checkpointInterval)
+	}
+}
+
+func validateAllPosition(err *ValidationError, position AllPosition) {
+	if value, ok := position.(Position); ok && value.Commit < value.Prepare {
+		err.add("From", "commit position %d must not be lower than prepare position %d", value.Commit, value.Prepare)
+	}
+}
@@ -0,0 +1,129 @@
+package esdb_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/EventStore/EventStore-Client-Go/esdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func invalidFields(t *testing.T, err error) []string {
+	var validationErr *esdb.ValidationError
+	require.True(t, errors.As(err, &validationErr), "expected a validation error, got %v", err)
+
+	fields := make([]string, 0, len(validationErr.Fields))
+	for _, field := range validationErr.Fields {
+		fields = append(fields, field.Field)
+	}
+
+	return fields
+}
+
+func TestSubscriptionSettingsDefaultIsValid(t *testing.T) {
+	assert.NoError(t, esdb.SubscriptionSettingsDefault().Validate())
+}
+
+func TestSubscriptionSettingsValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(*esdb.SubscriptionSettings)
+		fields []string
+	}{
+		{"negative retry count", func(s *esdb.SubscriptionSettings) { s.MaxRetryCount = -1 }, []string{"MaxRetryCount"}},
+		{"min checkpoint above max", func(s *esdb.SubscriptionSettings) { s.MinCheckpointCount = s.MaxCheckpointCount + 1 }, []string{"MinCheckpointCount"}},
+		{"negative subscriber count", func(s *esdb.SubscriptionSettings) { s.MaxSubscriberCount = -1 }, []string{"MaxSubscriberCount"}},
+		{"live buffer below batch", func(s *esdb.SubscriptionSettings) { s.LiveBufferSize = s.ReadBatchSize - 1 }, []string{"LiveBufferSize"}},
+		{"history buffer below batch", func(s *esdb.SubscriptionSettings) { s.HistoryBufferSize = s.ReadBatchSize - 1 }, []string{"HistoryBufferSize"}},
+		{"zero batch", func(s *esdb.SubscriptionSettings) { s.ReadBatchSize = 0 }, []string{"ReadBatchSize"}},
+		{"negative message timeout", func(s *esdb.SubscriptionSettings) { s.MessageTimeoutInMs = -1 }, []string{"MessageTimeoutInMs"}},
+		{"negative checkpoint after", func(s *esdb.SubscriptionSettings) { s.CheckpointAfterInMs = -1 }, []string{"CheckpointAfterInMs"}},
+		{"unknown strategy", func(s *esdb.SubscriptionSettings) { s.NamedConsumerStrategy = 42 }, []string{"NamedConsumerStrategy"}},
+		{"unsupported strategy", func(s *esdb.SubscriptionSettings) {
+			s.NamedConsumerStrategy = esdb.ConsumerStrategy_PinnedByCorrelation
+		}, []string{"NamedConsumerStrategy"}},
+		{"several", func(s *esdb.SubscriptionSettings) { s.MaxRetryCount = -1; s.MessageTimeoutInMs = -1 }, []string{"MaxRetryCount", "MessageTimeoutInMs"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			settings := esdb.SubscriptionSettingsDefault()
+			test.modify(&settings)
+			assert.Equal(t, test.fields, invalidFields(t, settings.Validate()))
+		})
+	}
+}
+
+func TestSubscriptionFilterValidate(t *testing.T) {
+	assert.NoError(t, esdb.ExcludeSystemEventsFilter().Validate())
+
+	filter := esdb.SubscriptionFilter{Type: esdb.StreamFilterType, Prefixes: []string{"a", ""}, Regex: "^b"}
+	assert.Equal(t, []string{"Prefixes", "Prefixes[1]"}, invalidFields(t, filter.Validate()))
+
+	filter = esdb.SubscriptionFilter{Type: 7}
+	assert.Equal(t, []string{"Type", "Prefixes"}, invalidFields(t, filter.Validate()))
+}
+
+func TestPersistentAllSubscriptionOptionsValidate(t *testing.T) {
+	settings := esdb.SubscriptionSettingsDefault()
+	settings.MaxRetryCount = -1
+
+	options := esdb.PersistentAllSubscriptionOptions{
+		Settings:        &settings,
+		From:            esdb.Position{Commit: 1, Prepare: 2},
+		MaxSearchWindow: -2,
+		Filter:          &esdb.SubscriptionFilter{Prefixes: []string{"a"}, Regex: "b"},
+	}
+
+	assert.Equal(t,
+		[]string{"Settings.MaxRetryCount", "From", "Filter.Prefixes", "MaxSearchWindow"},
+		invalidFields(t, options.Validate()))
+}
+
+func TestPartitionedConsumerOptionsValidate(t *testing.T) {
+	assert.NoError(t, esdb.PartitionedConsumerOptions{}.Validate())
+
+	maxRetryCount := -1
+	options := esdb.PartitionedConsumerOptions{
+		QueueSize:      -1,
+		MaxConcurrency: -1,
+		MaxRetryCount:  &maxRetryCount,
+		RetryDelay:     -time.Second,
+	}
+
+	assert.Equal(t,
+		[]string{"QueueSize", "MaxConcurrency", "MaxRetryCount", "RetryDelay"},
+		invalidFields(t, options.Validate()))
+}
+
+func TestSubscribeToAllOptionsValidate(t *testing.T) {
+	assert.NoError(t, esdb.SubscribeToAllOptions{Filter: esdb.ExcludeSystemEventsFilter()}.Validate())
+
+	options := esdb.SubscribeToAllOptions{
+		Filter:             &esdb.SubscriptionFilter{},
+		CheckpointInterval: -1,
+	}
+
+	assert.Equal(t, []string{"Filter.Prefixes", "CheckpointInterval"}, invalidFields(t, options.Validate()))
+}
+
+func TestCreatePersistentSubscriptionValidatesBeforeConnecting(t *testing.T) {
+	// Nothing listens on that port, validation must fail before any connection attempt.
+	config, err := esdb.ParseConnectionString("esdb://localhost:1?maxDiscoverAttempts=1")
+	require.NoError(t, err)
+	client, err := esdb.NewClient(config)
+	require.NoError(t, err)
+	defer client.Close()
+
+	settings := esdb.SubscriptionSettingsDefault()
+	settings.LiveBufferSize = 1
+
+	err = client.CreatePersistentSubscription(context.Background(), "a", "b", esdb.PersistentStreamSubscriptionOptions{
+		Settings: &settings,
+	})
+
+	assert.Equal(t, []string{"Settings.LiveBufferSize"}, invalidFields(t, err))
+}